@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/x-motemen/ghq/cmdutil"
+	"github.com/x-motemen/ghq/logger"
+	"golang.org/x/sync/errgroup"
+)
+
+// The states of the upstream of a stale local repository.
+const (
+	staleUnreachable = "unreachable"  // 'git ls-remote' fails
+	staleRenamed     = "renamed"      // renamed, transferred or redirected
+	staleArchived    = "archived"     // archived on the forge
+	staleForcePushed = "force-pushed" // the default branch was rewritten
+	// the default branch upstream has commits not fetched, so whether it
+	// was rewritten is not known, which does not make it stale
+	staleUnknown = "unknown"
+)
+
+// auditConcurrency is the number of the repositories audited at once.
+const auditConcurrency = 6
+
+// A staleness is a state of the upstream of a local repository.
+type staleness struct {
+	state string
+	// detail is the error if unreachable, the new remote URL if renamed, or
+	// the default branch if force-pushed or unknown
+	detail string
+}
+
+// isStale reports whether any of the states makes the repository stale.
+func isStale(states []staleness) bool {
+	return slices.ContainsFunc(states, func(s staleness) bool { return s.state != staleUnknown })
+}
+
+func (s staleness) describe(relPath string) string {
+	switch s.state {
+	case staleUnreachable:
+		return fmt.Sprintf("%s: unreachable: %s", relPath, s.detail)
+	case staleRenamed:
+		return fmt.Sprintf("%s: renamed to %s; run 'ghq relocate %s %s'", relPath, s.detail, relPath, s.detail)
+	case staleForcePushed:
+		return fmt.Sprintf("%s: %s was force-pushed upstream", relPath, s.detail)
+	case staleUnknown:
+		return fmt.Sprintf("%s: %s upstream has commits not fetched; fetch them to check whether it was force-pushed", relPath, s.detail)
+	}
+	return fmt.Sprintf("%s: %s upstream", relPath, s.state)
+}
+
+// An audit is the result of auditing a local repository.
+type audit struct {
+	repo  *LocalRepository
+	stale []staleness
+}
+
+// auditRepositories audits the local Git repositories concurrently and
+// returns the results in the same order. The others are never stale.
+func auditRepositories(ctx context.Context, repos []*LocalRepository) ([]audit, error) {
+	audits := make([]audit, len(repos))
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(auditConcurrency)
+	for i, repo := range repos {
+		audits[i].repo = repo
+		if vcs, _ := repo.VCS(); vcs != GitBackend {
+			continue
+		}
+		eg.Go(func() error {
+			stale, err := auditRepository(ctx, repo)
+			audits[i].stale = stale
+			return err
+		})
+	}
+	return audits, eg.Wait()
+}
+
+// auditRepository checks the upstream of the local Git repository, and
+// returns its states if it is stale or unknown. The problems other than the upstream,
+// such as no remote, are logged and the repository is not reported stale.
+func auditRepository(ctx context.Context, repo *LocalRepository) ([]staleness, error) {
+	dir := repo.FullPath
+	remote, err := GitBackend.RemoteURL(dir)
+	if err != nil {
+		logger.Log("debug", fmt.Sprintf("%s: %s", repo.RelPath, err))
+		return nil, nil
+	}
+	head, err := lsRemoteHead(ctx, dir, remote)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return []staleness{{state: staleUnreachable, detail: err.Error()}}, nil
+	}
+
+	var stale []staleness
+	if u, ok := forgeRemoteURL(remote); ok {
+		up, err := lookupUpstream(ctx, u)
+		if err != nil {
+			logger.Log("warning", fmt.Sprintf("%s: %s", repo.RelPath, err))
+		}
+		if up.path != "" {
+			stale = append(stale, staleness{state: staleRenamed, detail: renamedRemote(remote, u, up.host, up.path)})
+		}
+		if up.archived {
+			stale = append(stale, staleness{state: staleArchived})
+		}
+	}
+
+	if head.branch != "" {
+		// nothing is fetched by auditing, so the remote commit can be
+		// compared only if it has been fetched already
+		if _, err := gitOutput(ctx, dir, "cat-file", "-e", head.commit+"^{commit}"); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return append(stale, staleness{state: staleUnknown, detail: head.branch}), nil
+		}
+		rewritten, err := isForcePushed(ctx, dir, head)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Log("warning", fmt.Sprintf("%s: %s", repo.RelPath, err))
+		} else if rewritten {
+			stale = append(stale, staleness{state: staleForcePushed, detail: head.branch})
+		}
+	}
+	return stale, nil
+}
+
+// forgeRemoteURL parses the remote URL if it is of a repository on a host,
+// rather than a local path.
+func forgeRemoteURL(remote string) (*url.URL, bool) {
+	if !hasSchemePattern.MatchString(remote) && !scpLikeURLPattern.MatchString(remote) {
+		return nil, false
+	}
+	u, err := newURL(remote, false, false)
+	if err != nil || !slices.Contains([]string{"https", "http", "ssh"}, u.Scheme) {
+		return nil, false
+	}
+	return u, true
+}
+
+// A remoteHead is the default branch of a remote repository.
+type remoteHead struct {
+	branch, commit string
+}
+
+// gitNoPrompt is the environment to run git never waiting for credentials.
+func gitNoPrompt() []string {
+	return append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+}
+
+// gitOutput runs git in the directory and returns its output, with the
+// first line of its stderr in the error.
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := cmdutil.Command(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = gitNoPrompt()
+	out, err := cmd.Output()
+	if err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			if msg, _, _ := strings.Cut(strings.TrimSpace(string(ee.Stderr)), "\n"); msg != "" {
+				return "", errors.New(strings.TrimPrefix(msg, "fatal: "))
+			}
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// lsRemoteHead asks the remote for its default branch, which fails if the
+// remote is unreachable.
+func lsRemoteHead(ctx context.Context, dir, remote string) (remoteHead, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout())
+	defer cancel()
+	out, err := gitOutput(ctx, dir, "ls-remote", "--symref", remote, "HEAD")
+	if err != nil {
+		return remoteHead{}, err
+	}
+	var head remoteHead
+	scr := bufio.NewScanner(strings.NewReader(out))
+	for scr.Scan() {
+		ref, name, _ := strings.Cut(scr.Text(), "\t")
+		if name != "HEAD" {
+			continue
+		}
+		if target, ok := strings.CutPrefix(ref, "ref: "); ok {
+			head.branch = strings.TrimPrefix(target, "refs/heads/")
+		} else {
+			head.commit = ref
+		}
+	}
+	if head.commit == "" {
+		// an empty repository
+		return remoteHead{}, nil
+	}
+	return head, nil
+}
+
+// isForcePushed reports whether the default branch of the remote, whose
+// commit should be found locally, no longer contains the local branch, that
+// is, it has been rewritten. The commits of the local branch not pushed yet
+// are told apart by the fork point from the upstream, which is found in its
+// reflog even after the rewritten branch is fetched.
+func isForcePushed(ctx context.Context, dir string, head remoteHead) (bool, error) {
+	local, err := gitOutput(ctx, dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+head.branch)
+	if err != nil {
+		// the default branch has never been checked out
+		base, err := gitOutput(ctx, dir, "rev-parse", "--verify", "--quiet", head.branch+"@{upstream}")
+		if err != nil {
+			return false, nil
+		}
+		return isNotAncestor(ctx, dir, base, head.commit)
+	}
+	rewritten, err := isNotAncestor(ctx, dir, local, head.commit)
+	if err != nil || !rewritten {
+		return false, err
+	}
+	fork, err := gitOutput(ctx, dir, "merge-base", "--fork-point", head.branch+"@{upstream}", local)
+	if err != nil {
+		// no upstream or no reflog of it to tell the local commits
+		return true, nil
+	}
+	return isNotAncestor(ctx, dir, fork, head.commit)
+}
+
+// isNotAncestor reports whether the commit is not an ancestor of the other.
+func isNotAncestor(ctx context.Context, dir, commit, of string) (bool, error) {
+	if commit == of {
+		return false, nil
+	}
+	cmd := cmdutil.Command(ctx, "git", "merge-base", "--is-ancestor", commit, of)
+	cmd.Dir = dir
+	err := cmd.Run()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) && ee.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, err
+}
+
+// An upstream is what the forge tells about a remote repository.
+type upstream struct {
+	// host and path are the new ones if it has been renamed, or empty
+	host, path string
+	archived   bool
+}
+
+// lookupUpstream asks the forge of the remote whether the repository has
+// been renamed or archived. On hosts not known as forges, only redirections
+// of the web page are followed.
+func lookupUpstream(ctx context.Context, u *url.URL) (upstream, error) {
+	p := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	f, err := newForge(u.Hostname())
+	if errors.Is(err, errUnknownForge) {
+		return lookupRedirect(ctx, u.Hostname(), p)
+	}
+	if err != nil {
+		return upstream{}, err
+	}
+	repo, err := f.repository(ctx, p)
+	if err != nil {
+		if isForgeNotFound(err) {
+			// private ones are not found without a token
+			logger.Log("debug", err.Error())
+			return upstream{}, nil
+		}
+		return upstream{}, err
+	}
+	up := upstream{archived: repo.Archived}
+	if repo.Name != "" && !strings.EqualFold(repo.Name, p) {
+		up.path = repo.Name
+	}
+	return up, nil
+}
+
+// lookupRedirect requests the web page of the repository, and returns the
+// new location if it is permanently redirected.
+func lookupRedirect(ctx context.Context, host, p string) (upstream, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+"/"+p, nil)
+	if err != nil {
+		return upstream{}, err
+	}
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Log("debug", err.Error())
+		return upstream{}, nil
+	}
+	resp.Body.Close()
+	if !slices.Contains([]int{http.StatusMovedPermanently, http.StatusPermanentRedirect}, resp.StatusCode) {
+		return upstream{}, nil
+	}
+	loc, err := resp.Location()
+	if err != nil {
+		return upstream{}, nil
+	}
+	newPath := strings.TrimSuffix(strings.Trim(loc.Path, "/"), ".git")
+	if newPath == "" || loc.Host == host && strings.EqualFold(newPath, p) {
+		return upstream{}, nil
+	}
+	return upstream{host: loc.Host, path: newPath}, nil
+}
+
+// renamedRemote returns the remote URL with the path, and the host if not
+// empty, replaced, keeping the style of the URL such as scp-like.
+func renamedRemote(remote string, u *url.URL, host, newPath string) string {
+	oldPath := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if i := strings.LastIndex(remote, oldPath); i >= 0 {
+		remote = remote[:i] + newPath + remote[i+len(oldPath):]
+	} else {
+		remote = strings.TrimSuffix(remote, strings.Trim(u.Path, "/")) + newPath
+	}
+	if host != "" && host != u.Host {
+		remote = strings.Replace(remote, u.Host, host, 1)
+	}
+	return remote
+}
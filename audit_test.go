@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// git runs git in the directory, failing the test on error.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	c := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@test.com"}, args...)...)
+	c.Dir = dir
+	out, err := c.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newUpstream creates a bare repository with a commit on main, and returns
+// its path and the directory of the working copy pushing to it.
+func newUpstream(t *testing.T, name string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	bare := filepath.Join(dir, name+".git")
+	git(t, dir, "init", "--bare", bare)
+	git(t, bare, "symbolic-ref", "HEAD", "refs/heads/main")
+	work := initGitRepo(t, filepath.Join(dir, name), bare)
+	git(t, work, "branch", "-M", "main")
+	git(t, work, "push", "-u", "origin", "main")
+	return bare, work
+}
+
+func TestDoAudit(t *testing.T) {
+	setSyncRoot(t)
+	root, err := primaryLocalRepositoryRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := func(upstream, relPath string) string {
+		t.Helper()
+		dir := filepath.Join(root, filepath.FromSlash(relPath))
+		git(t, root, "clone", "--quiet", upstream, dir)
+		return dir
+	}
+
+	forced, forcedWork := newUpstream(t, "forced")
+	behind, behindWork := newUpstream(t, "behind")
+	ahead, _ := newUpstream(t, "ahead")
+	gone, _ := newUpstream(t, "gone")
+
+	forcedDir := clone(forced, "example.org/x/forced")
+	git(t, forcedWork, "commit", "--amend", "--allow-empty", "-m", "rewritten")
+	git(t, forcedWork, "push", "--force", "origin", "main")
+	git(t, forcedDir, "fetch", "--quiet")
+	clone(forced, "example.org/x/fresh")
+	clone(behind, "example.org/x/behind")
+	git(t, behindWork, "commit", "--allow-empty", "-m", "not fetched")
+	git(t, behindWork, "push", "origin", "main")
+	// commits not pushed yet are not taken as rewritten
+	aheadDir := clone(ahead, "example.org/x/ahead")
+	git(t, aheadDir, "commit", "--allow-empty", "-m", "not pushed")
+	clone(gone, "example.org/x/gone")
+	if err := os.RemoveAll(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	var runErr error
+	out, _, _ := capture(func() {
+		runErr = newApp().Run(context.Background(), []string{"", "audit"})
+	})
+	if runErr == nil || !strings.Contains(runErr.Error(), "2 of 5 repositories are stale") {
+		t.Errorf("error should be returned, but: %v", runErr)
+	}
+	for _, s := range []string{
+		"example.org/x/forced: main was force-pushed upstream\n",
+		"example.org/x/behind: main upstream has commits not fetched; fetch them to check whether it was force-pushed\n",
+		"example.org/x/gone: unreachable: '" + gone + "' does not appear to be a git repository\n",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("out should contain %q, but: %q", s, out)
+		}
+	}
+	for _, name := range []string{"fresh", "ahead"} {
+		if strings.Contains(out, name) {
+			t.Errorf("the %s repository should not be reported, but: %q", name, out)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "example.org", "x", "behind", ".git", "FETCH_HEAD")); !os.IsNotExist(err) {
+		t.Errorf("nothing should be fetched, but: %v", err)
+	}
+
+	out, _, _ = capture(func() {
+		if err := newApp().Run(context.Background(), []string{"", "list", "--stale"}); err != nil {
+			t.Errorf("error should be nil, but: %s", err)
+		}
+	})
+	expect := "example.org/x/forced\nexample.org/x/gone\n"
+	if filepath.ToSlash(out) != expect {
+		t.Errorf("got: %q, expect: %q", out, expect)
+	}
+}
+
+func TestLookupUpstream(t *testing.T) {
+	newFakeForge(t, "github", map[string]string{
+		"/repos/my-org/a":     `{"full_name": "my-org/renamed", "archived": true}`,
+		"/repos/my-org/b":     `{"full_name": "My-Org/B"}`,
+		"/repos/my-org/moved": `{"full_name": "other-org/moved"}`,
+	})
+	testCases := []struct {
+		remote string
+		expect upstream
+	}{
+		{"https://git.example.com/my-org/a.git", upstream{path: "my-org/renamed", archived: true}},
+		{"git@git.example.com:my-org/b.git", upstream{}},
+		{"ssh://git@git.example.com/my-org/moved", upstream{path: "other-org/moved"}},
+		{"https://git.example.com/my-org/private.git", upstream{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.remote, func(t *testing.T) {
+			u, ok := forgeRemoteURL(tc.remote)
+			if !ok {
+				t.Fatalf("%q should be a remote URL", tc.remote)
+			}
+			got, err := lookupUpstream(context.Background(), u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expect {
+				t.Errorf("got: %+v, expect: %+v", got, tc.expect)
+			}
+		})
+	}
+
+	for _, remote := range []string{"/srv/git/repo.git", "file:///srv/git/repo.git", "../repo"} {
+		if _, ok := forgeRemoteURL(remote); ok {
+			t.Errorf("%q should not be a remote URL of a host", remote)
+		}
+	}
+}
+
+func TestRenamedRemote(t *testing.T) {
+	testCases := []struct {
+		remote, host, path, expect string
+	}{
+		{"https://github.com/old/repo.git", "", "new/repo", "https://github.com/new/repo.git"},
+		{"https://github.com/old/repo", "", "old/renamed", "https://github.com/old/renamed"},
+		{"git@github.com:old/repo.git", "", "new/repo", "git@github.com:new/repo.git"},
+		{"ssh://git@git.example.com/old/repo.git", "git.example.org", "new/repo", "ssh://git@git.example.org/new/repo.git"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.remote, func(t *testing.T) {
+			u, err := newURL(tc.remote, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := renamedRemote(tc.remote, u, tc.host, tc.path); got != tc.expect {
+				t.Errorf("got: %q, expect: %q", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestLsRemoteHead(t *testing.T) {
+	bare, work := newUpstream(t, "repo")
+	got, err := lsRemoteHead(context.Background(), t.TempDir(), bare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := remoteHead{branch: "main", commit: git(t, work, "rev-parse", "HEAD")}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %+v, expect: %+v", got, expect)
+	}
+
+	if _, err := lsRemoteHead(context.Background(), t.TempDir(), (&url.URL{Scheme: "file", Path: filepath.ToSlash(bare) + "-gone"}).String()); err == nil {
+		t.Error("error should be returned for the missing remote")
+	}
+}
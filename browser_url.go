@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A browserRef represents the revision and sub-directory referenced by a URL
+// copied from the web UI of a forge, e.g. "https://github.com/owner/repo/tree/main/pkg".
+type browserRef struct {
+	// ref is a branch, tag or commit, empty if the URL does not point to one.
+	ref string
+	// subpath is the slash separated directory inside the repository.
+	subpath string
+}
+
+var commitHashReg = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// isCommit reports whether the ref looks like a (possibly abbreviated) commit hash.
+func (br browserRef) isCommit() bool {
+	return commitHashReg.MatchString(br.ref)
+}
+
+// parseBrowserURL strips the web UI route from the URL and returns the
+// repository URL with the referenced revision and sub-directory.
+// If the URL is not recognized as a browser URL, it is returned as it is.
+//
+// Two styles are recognized:
+//
+//   - GitLab style, where the route follows a "/-/" separator, e.g.
+//     "https://gitlab.com/group/sub/repo/-/blob/main/x.go"
+//   - GitHub style, where the route follows "owner/repo", e.g.
+//     "https://github.com/owner/repo/tree/main/pkg/foo"
+//
+// Branch names containing slashes cannot be told from the sub-directory,
+// so only the first path component after the route is taken as the ref.
+func parseBrowserURL(u *url.URL) (*url.URL, browserRef) {
+	repoPath, route, ok := strings.Cut(u.Path, "/-/")
+	if !ok {
+		if u.Host != "github.com" {
+			return u, browserRef{}
+		}
+		parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 3)
+		if len(parts) < 3 || !isBrowserRoute(parts[2]) {
+			return u, browserRef{}
+		}
+		repoPath, route = "/"+parts[0]+"/"+parts[1], parts[2]
+	}
+	copied := *u
+	copied.Path = repoPath
+	copied.RawPath = ""
+	return &copied, parseBrowserRoute(route)
+}
+
+var browserRoutes = []string{
+	"tree", "blob", "raw", "blame", "commit", "commits", "releases",
+	"pull", "pulls", "merge_requests", "issues", "actions", "pipelines",
+	"wiki", "wikis", "compare", "tags", "branches", "settings",
+}
+
+func isBrowserRoute(route string) bool {
+	kind, _, _ := strings.Cut(route, "/")
+	return slices.Contains(browserRoutes, kind)
+}
+
+func parseBrowserRoute(route string) browserRef {
+	parts := strings.Split(strings.Trim(route, "/"), "/")
+	switch parts[0] {
+	case "tree":
+		if len(parts) < 2 {
+			return browserRef{}
+		}
+		return browserRef{ref: parts[1], subpath: path.Join(parts[2:]...)}
+	case "blob", "raw", "blame":
+		if len(parts) < 2 {
+			return browserRef{}
+		}
+		// the last component is the file itself
+		br := browserRef{ref: parts[1]}
+		if len(parts) > 3 {
+			br.subpath = path.Join(parts[2 : len(parts)-1]...)
+		}
+		return br
+	case "commit", "commits":
+		if len(parts) < 2 {
+			return browserRef{}
+		}
+		return browserRef{ref: parts[1]}
+	case "releases":
+		if len(parts) < 3 || parts[1] != "tag" {
+			return browserRef{}
+		}
+		return browserRef{ref: parts[2]}
+	}
+	return browserRef{}
+}
@@ -0,0 +1,87 @@
+package main
+
+import "testing"
+
+func TestParseBrowserURL(t *testing.T) {
+	testCases := []struct {
+		name, url, expectURL, ref, subpath string
+		commit                             bool
+	}{{
+		name:      "github tree",
+		url:       "https://github.com/owner/repo/tree/main/pkg/foo",
+		expectURL: "https://github.com/owner/repo",
+		ref:       "main",
+		subpath:   "pkg/foo",
+	}, {
+		name:      "github blob",
+		url:       "https://github.com/owner/repo/blob/v1.0.0/pkg/foo/bar.go",
+		expectURL: "https://github.com/owner/repo",
+		ref:       "v1.0.0",
+		subpath:   "pkg/foo",
+	}, {
+		name:      "github blob at the top level",
+		url:       "https://github.com/owner/repo/blob/main/README.md",
+		expectURL: "https://github.com/owner/repo",
+		ref:       "main",
+	}, {
+		name:      "github commit",
+		url:       "https://github.com/owner/repo/commit/0123abcd",
+		expectURL: "https://github.com/owner/repo",
+		ref:       "0123abcd",
+		commit:    true,
+	}, {
+		name:      "github pull request",
+		url:       "https://github.com/owner/repo/pull/123",
+		expectURL: "https://github.com/owner/repo",
+	}, {
+		name:      "github release",
+		url:       "https://github.com/owner/repo/releases/tag/v1.2.3",
+		expectURL: "https://github.com/owner/repo",
+		ref:       "v1.2.3",
+	}, {
+		name:      "github repository",
+		url:       "https://github.com/owner/repo",
+		expectURL: "https://github.com/owner/repo",
+	}, {
+		name:      "github sub directory without route",
+		url:       "https://github.com/motemen/ghq/logger",
+		expectURL: "https://github.com/motemen/ghq/logger",
+	}, {
+		name:      "gitlab nested blob",
+		url:       "https://gitlab.com/group/sub/repo/-/blob/main/x.go",
+		expectURL: "https://gitlab.com/group/sub/repo",
+		ref:       "main",
+	}, {
+		name:      "gitlab tree",
+		url:       "https://gitlab.com/group/sub/repo/-/tree/develop/cmd/app",
+		expectURL: "https://gitlab.com/group/sub/repo",
+		ref:       "develop",
+		subpath:   "cmd/app",
+	}, {
+		name:      "gitlab merge request",
+		url:       "https://gitlab.example.com/group/repo/-/merge_requests/42",
+		expectURL: "https://gitlab.example.com/group/repo",
+	}, {
+		name:      "tree on other hosts is untouched",
+		url:       "https://example.com/owner/repo/tree/main",
+		expectURL: "https://example.com/owner/repo/tree/main",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, br := parseBrowserURL(mustParseURL(tc.url))
+			if u.String() != tc.expectURL {
+				t.Errorf("url: got: %s, expect: %s", u, tc.expectURL)
+			}
+			if br.ref != tc.ref {
+				t.Errorf("ref: got: %q, expect: %q", br.ref, tc.ref)
+			}
+			if br.subpath != tc.subpath {
+				t.Errorf("subpath: got: %q, expect: %q", br.subpath, tc.subpath)
+			}
+			if br.isCommit() != tc.commit {
+				t.Errorf("isCommit: got: %v, expect: %v", br.isCommit(), tc.commit)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/urfave/cli/v3"
+)
+
+func doAudit(ctx context.Context, cmd *cli.Command) error {
+	var (
+		w             = cmd.Root().Writer
+		filterByQuery = repositoryFilter(cmd.Args().First(), false, false)
+	)
+
+	var (
+		mu    sync.Mutex
+		repos []*LocalRepository
+	)
+	if err := walkLocalRepositories("git", func(repo *LocalRepository) {
+		if !filterByQuery(repo) {
+			return
+		}
+		mu.Lock()
+		repos = append(repos, repo)
+		mu.Unlock()
+	}); err != nil {
+		return err
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].FullPath < repos[j].FullPath })
+
+	audits, err := auditRepositories(ctx, repos)
+	if err != nil {
+		return err
+	}
+	var stale int
+	for _, a := range audits {
+		if isStale(a.stale) {
+			stale++
+		}
+		for _, s := range a.stale {
+			fmt.Fprintln(w, s.describe(filepath.ToSlash(a.repo.RelPath)))
+		}
+	}
+	if stale == 0 {
+		return nil
+	}
+	return cli.Exit(fmt.Sprintf("%d of %d repositories are stale", stale, len(repos)), 1)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// doComplete prints the candidates of the completion which start with the
+// prefix, for the completion scripts.
+func doComplete(_ context.Context, cmd *cli.Command) error {
+	var (
+		w      = cmd.Root().Writer
+		kind   = cmd.Args().Get(0)
+		prefix = cmd.Args().Get(1)
+	)
+
+	idx, err := loadRepositoryIndex()
+	if err != nil {
+		return err
+	}
+	var candidates []string
+	switch kind {
+	case "repositories":
+		candidates = idx.subpaths()
+	case "namespaces":
+		candidates = idx.namespaces()
+	default:
+		return fmt.Errorf("unknown completion %q", kind)
+	}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			fmt.Fprintln(w, c)
+		}
+	}
+	return nil
+}
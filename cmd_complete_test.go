@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDoComplete(t *testing.T) {
+	invalidateRepositoryIndex()
+	defer invalidateRepositoryIndex()
+	defer func(orig string) { _home = orig }(_home)
+	_home = ""
+	homeOnce = &sync.Once{}
+	tmpd := newTempDir(t)
+	defer func(orig []string) { _localRepositoryRoots = orig }(_localRepositoryRoots)
+	setEnv(t, envGhqRoot, tmpd)
+	_localRepositoryRoots = nil
+	localRepoOnce = &sync.Once{}
+
+	mkRepo := func(p string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(tmpd, filepath.FromSlash(p), ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mkRepo("github.com/motemen/ghq")
+	mkRepo("github.com/Songmu/gitogether")
+
+	complete := func(args ...string) []string {
+		t.Helper()
+		var runErr error
+		out, _, err := capture(func() {
+			runErr = newApp().Run(context.Background(), append([]string{"", "__complete"}, args...))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runErr != nil {
+			t.Fatalf("error should be nil, but: %s", runErr)
+		}
+		return strings.Fields(out)
+	}
+
+	testCases := []struct {
+		name   string
+		args   []string
+		expect string
+	}{{
+		name:   "repositories",
+		args:   []string{"repositories"},
+		expect: "Songmu/gitogether ghq github.com/Songmu/gitogether github.com/motemen/ghq gitogether motemen/ghq",
+	}, {
+		name:   "repositories with prefix",
+		args:   []string{"repositories", "github.com/m"},
+		expect: "github.com/motemen/ghq",
+	}, {
+		name:   "namespaces",
+		args:   []string{"namespaces"},
+		expect: "Songmu/ github.com/ github.com/Songmu/ github.com/motemen/ motemen/",
+	}, {
+		name:   "namespaces with prefix",
+		args:   []string{"namespaces", "git"},
+		expect: "github.com/ github.com/Songmu/ github.com/motemen/",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := strings.Join(complete(tc.args...), " "); got != tc.expect {
+				t.Errorf("got: %q, expect: %q", got, tc.expect)
+			}
+		})
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		mkRepo("github.com/motemen/gore")
+		if got := complete("repositories", "gore"); len(got) != 0 {
+			t.Errorf("the index should be used, but: %v", got)
+		}
+		invalidateRepositoryIndex()
+		if got := strings.Join(complete("repositories", "gore"), " "); got != "gore" {
+			t.Errorf("the index should be rebuilt, but: %q", got)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		err := newApp().Run(context.Background(), []string{"", "__complete", "hosts"})
+		if err == nil {
+			t.Error("unknown completion should be an error")
+		}
+	})
+}
+
+func TestStagingCommit_invalidateRepositoryIndex(t *testing.T) {
+	p, err := repositoryIndexPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer invalidateRepositoryIndex()
+
+	s, err := newStaging(filepath.Join(newTempDir(t), "repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(s.dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.commit(s.dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p); !os.IsNotExist(err) {
+		t.Errorf("the index should be removed, but: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/urfave/cli/v3"
 )
@@ -31,7 +32,17 @@ func doCreate(ctx context.Context, cmd *cli.Command) error {
 		return err
 	}
 
+	if cmd.Bool("no-wait") {
+		noWaitLock = true
+		defer func() { noWaitLock = false }()
+	}
 	p := localRepo.FullPath
+	unlock, err := lockRepository(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	ok, err := isNotExistOrEmpty(p)
 	if err != nil {
 		return err
@@ -40,6 +51,10 @@ func doCreate(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("directory %q already exists and not empty", p)
 	}
 
+	if cmd.Bool("offline") {
+		offlineMode = true
+		defer func() { offlineMode = false }()
+	}
 	remoteRepo, err := NewRemoteRepository(u)
 	if err != nil {
 		return err
@@ -47,7 +62,7 @@ func doCreate(ctx context.Context, cmd *cli.Command) error {
 
 	vcsBackend, ok := vcsRegistry[vcs]
 	if !ok {
-		vcsBackend, _, err = remoteRepo.VCS()
+		vcsBackend, _, err = remoteRepo.VCS(ctx)
 		if err != nil {
 			return err
 		}
@@ -56,22 +71,60 @@ func doCreate(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("failed to init: unsupported VCS")
 	}
 
-	initFunc := vcsBackend.Init
-	if initFunc == nil {
+	if vcsBackend.Init == nil {
 		return fmt.Errorf("failed to init: unsupported VCS")
 	}
-
-	if err := os.MkdirAll(p, 0755); err != nil {
+	if err := initRepository(ctx, vcsBackend, p); err != nil {
 		return err
 	}
-
-	if err := initFunc(p); err != nil {
+	applyIdentityOrWarn(ctx, vcsBackend, localRepo.RootPath, p)
+	runPostHooks(ctx, hookEvent{hook: hookPostCreate, action: "create",
+		path: p, url: u.String(), vcs: vcsName(vcsBackend)}, false)
+	if _, err := fmt.Fprintln(w, p); err != nil {
 		return err
 	}
-	_, err = fmt.Fprintln(w, p)
+	_, err = writeLookFile(p, filepath.ToSlash(localRepo.RelPath))
 	return err
 }
 
+// initRepository initializes the repository in a staging directory next to
+// dir, and renames it into place on success, as getter.clone does.
+func initRepository(ctx context.Context, vcs *VCSBackend, dir string) error {
+	if vcs == FossilBackend {
+		// a fossil checkout records the absolute path of its repository file,
+		// so it cannot be moved after init
+		_, statErr := os.Stat(dir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := vcs.Init(ctx, dir); err != nil {
+			if os.IsNotExist(statErr) {
+				os.RemoveAll(dir)
+			}
+			return err
+		}
+		invalidateRepositoryIndex()
+		return nil
+	}
+	s, err := newStaging(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(s.dir, 0755); err != nil {
+		s.cleanup()
+		return err
+	}
+	if err := vcs.Init(ctx, s.dir); err != nil {
+		s.cleanup()
+		return err
+	}
+	if err := s.commit(s.dir); err != nil {
+		s.cleanup()
+		return err
+	}
+	return nil
+}
+
 func isNotExistOrEmpty(name string) (bool, error) {
 	f, err := os.Open(name)
 	if err != nil {
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -88,6 +89,36 @@ func TestDoCreate(t *testing.T) {
 		input:   []string{"create", "--vcs=fossil", "motemen/ghq-fossil"},
 		want:    []string{"fossil", "open", fossilRepoName},
 		wantDir: filepath.Join(tmpd, "github.com/motemen/ghq-fossil"),
+		setup: func(t *testing.T) {
+			// not being staged, the repository invalidates the index by itself
+			idx, err := repositoryIndexPath()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(idx, []byte("{}"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				if _, err := os.Stat(idx); !os.IsNotExist(err) {
+					t.Errorf("the index should be removed, but: %v", err)
+				}
+				invalidateRepositoryIndex()
+			})
+		},
+	}, {
+		name:  "Fossil failure",
+		input: []string{"create", "--vcs=fossil", "motemen/ghq-fossil-failure"},
+		cmdRun: func(cmd *exec.Cmd) error {
+			return fmt.Errorf("[test] failed to %s", cmd.Args[0])
+		},
+		setup: func(t *testing.T) {
+			t.Cleanup(func() {
+				if _, err := os.Stat(filepath.Join(tmpd, "github.com/motemen/ghq-fossil-failure")); !os.IsNotExist(err) {
+					t.Errorf("the directory should be removed on failure: %v", err)
+				}
+			})
+		},
+		errStr: "failed to fossil",
 	}, {
 		name:   "unsupported VCS",
 		input:  []string{"create", "--vcs=svn", "motemen/ghq-svn"},
@@ -153,8 +184,14 @@ func TestDoCreate(t *testing.T) {
 					t.Errorf("cmd.Args = %v, want: %v", lastCmd.Args, tc.want)
 				}
 
-				if lastCmd.Dir != tc.wantDir {
-					t.Errorf("cmd.Dir = %q, want: %q", lastCmd.Dir, tc.wantDir)
+				// the repository is initialized in a staging directory,
+				// except for fossil whose checkout cannot be moved
+				d := lastCmd.Dir
+				if tc.want[0] != "fossil" {
+					d = stagedDestination(d)
+				}
+				if d != tc.wantDir {
+					t.Errorf("cmd.Dir = %q, want: %q", d, tc.wantDir)
 				}
 			}
 
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/urfave/cli/v3"
+)
+
+func doDoctor(ctx context.Context, cmd *cli.Command) error {
+	w := cmd.Root().Writer
+	roots, err := localRepositoryRoots(true)
+	if err != nil {
+		return err
+	}
+	ds := append(diagnoseRootSettings(), diagnoseRoots(roots)...)
+
+	var (
+		mu     sync.Mutex
+		repos  []*LocalRepository
+		strays []string
+		loops  []string
+	)
+	opt := walkOptions{
+		strays: func(fpath string) {
+			mu.Lock()
+			strays = append(strays, fpath)
+			mu.Unlock()
+		},
+		loops: func(fpath string) {
+			mu.Lock()
+			loops = append(loops, fpath)
+			mu.Unlock()
+		},
+	}
+	if err := walkLocalRepositoriesWith("", opt, func(repo *LocalRepository) {
+		mu.Lock()
+		repos = append(repos, repo)
+		mu.Unlock()
+	}); err != nil {
+		return err
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].FullPath < repos[j].FullPath })
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	ds = append(ds, diagnoseRepositories(repos)...)
+	ds = append(ds, diagnoseStrays(roots, strays)...)
+	ds = append(ds, diagnoseLoops(loops)...)
+
+	for _, d := range ds {
+		fmt.Fprintf(w, "%s: %s\n    fix: %s\n", d.check, d.message, d.fix)
+	}
+	if len(ds) == 0 {
+		return nil
+	}
+	return cli.Exit(fmt.Sprintf("%d problems found in %d roots and %d repositories", len(ds), len(roots), len(repos)), 1)
+}
@@ -12,6 +12,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/mattn/go-isatty"
 	"github.com/urfave/cli/v3"
@@ -26,6 +27,10 @@ func doGet(ctx context.Context, cmd *cli.Command) error {
 		andLook  = cmd.Bool("look")
 		parallel = cmd.Bool("parallel")
 		silent   = cmd.Bool("silent")
+
+		keepGoing  = cmd.Bool("keep-going")
+		failFast   = cmd.Bool("fail-fast")
+		failedFile = cmd.String("failed-file")
 	)
 	g := &getter{
 		update:    cmd.Bool("update"),
@@ -37,25 +42,60 @@ func doGet(ctx context.Context, cmd *cli.Command) error {
 		recursive: !cmd.Bool("no-recursive"),
 		bare:      cmd.Bool("bare"),
 		partial:   cmd.String("partial"),
+		timeout:   cmd.Duration("timeout"),
+	}
+	retries := -1 // from gitconfig
+	if cmd.IsSet("retries") {
+		retries = int(cmd.Int("retries"))
+	}
+	g.retry = newRetryPolicy(retries)
+	if cmd.Bool("offline") {
+		offlineMode = true
+		defer func() { offlineMode = false }()
+	}
+	if cmd.Bool("no-wait") {
+		noWaitLock = true
+		defer func() { noWaitLock = false }()
 	}
 	if parallel {
 		// force silent in parallel import
 		g.silent = true
 	}
+	if keepGoing && failFast {
+		return fmt.Errorf("--keep-going and --fail-fast cannot be specified together")
+	}
+	// parallel gets go on after failures by default, while sequential ones stop
+	keepGoing = keepGoing || parallel && !failFast
 	if silent {
 		logger.SetOutput(io.Discard)
 	}
+	if parallel && !silent {
+		defer g.showProgress()()
+	}
 
-	var (
-		firstArg string // Look at the first repo only, if there are more than one
-		argCnt   int
-		getInfo  getInfo // For fetching and looking a single repo
-		scr      scanner
-		err      error
-	)
-	if len(args) > 0 {
+	var scr scanner
+	switch org, user := cmd.String("org"), cmd.String("user"); {
+	case org != "" && user != "":
+		return fmt.Errorf("--org and --user cannot be specified together")
+	case org != "" || user != "":
+		if len(args) > 0 {
+			return fmt.Errorf("repositories cannot be specified with --org or --user")
+		}
+		filter := &forgeFilter{
+			archived:   cmd.Bool("archived"),
+			forks:      cmd.Bool("forks"),
+			topics:     cmd.StringSlice("topic"),
+			language:   cmd.String("language"),
+			visibility: cmd.String("visibility"),
+		}
+		targets, err := forgeTargets(ctx, org+user, user != "", g.ssh, filter)
+		if err != nil {
+			return err
+		}
+		scr = &sliceScanner{slice: targets}
+	case len(args) > 0:
 		scr = &sliceScanner{slice: args}
-	} else {
+	default:
 		fd := os.Stdin.Fd()
 		if isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd) {
 			return fmt.Errorf("no target args specified. see `ghq get -h` for more details")
@@ -63,54 +103,231 @@ func doGet(ctx context.Context, cmd *cli.Command) error {
 		scr = bufio.NewScanner(os.Stdin)
 	}
 
+	results, err := g.getAll(ctx, scr, parallel, keepGoing)
+	if err != nil {
+		return err
+	}
+
+	var (
+		failed   []getResult
+		canceled int
+		targets  []string
+	)
+	for _, r := range results {
+		switch {
+		case r.canceled:
+			canceled++
+		case r.err != nil:
+			failed = append(failed, r)
+		default:
+			continue
+		}
+		targets = append(targets, r.target)
+	}
+	if failedFile != "" {
+		if len(failed) > 0 && !keepGoing {
+			// the targets not attempted after stopping at the failure
+			for scr.Scan() {
+				targets = append(targets, scr.Text())
+			}
+		}
+		if err := writeFailedTargets(failedFile, targets); err != nil {
+			return fmt.Errorf("failed to write failed targets: %w", err)
+		}
+	}
+	if len(failed) > 0 {
+		if !parallel && !keepGoing {
+			return fmt.Errorf("failed to get %q: %w", failed[0].target, failed[0].err)
+		}
+		return summarizeFailures(failed, canceled, len(results))
+	}
+
+	if andLook {
+		if len(results) > 1 {
+			// look at the first repo only
+			return look(results[0].target, g.bare)
+		}
+		if len(results) == 1 && results[0].info.localRepository != nil {
+			info := results[0].info
+			return lookByLocalRepository(info.localRepository, info.subpath)
+		}
+	}
+	return nil
+}
+
+// showProgress shows the live progress of the repositories got in parallel
+// instead of the output of the commands, if the standard error is a
+// terminal. It returns the function to stop it.
+func (g *getter) showProgress() (stop func()) {
+	if logger.JSON() || !isTerminal(os.Stderr) {
+		return func() {}
+	}
+	g.progress = newProgressUI(os.Stderr, terminalWidth(os.Stderr.Fd()))
+	g.progress.start()
+	logger.SetOutput(g.progress)
+	return func() {
+		g.progress.stop()
+		logger.SetOutput(os.Stderr)
+	}
+}
+
+// getAll gets the targets read from scr, in parallel if parallel, printing
+// the full paths of the repositories got. Unless keepGoing, it stops at the
+// first failure. The results are in the order of the targets, and the error
+// is returned only if it cannot go on, e.g. interrupted.
+func (g *getter) getAll(ctx context.Context, scr scanner, parallel, keepGoing bool) ([]getResult, error) {
+	// parent is canceled only by interruption, while ctx is also canceled
+	// to stop the rest on failure
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu      sync.Mutex
+		results []getResult
+	)
+	getTarget := func(i int, target string) (getInfo, error) {
+		start := time.Now()
+		info, err := g.get(ctx, target)
+		r := getResult{target: target, info: info, err: err, elapsed: time.Since(start)}
+		mu.Lock()
+		// stopped by the failure of another target, not failed by itself
+		r.canceled = err != nil && ctx.Err() != nil && parent.Err() == nil
+		results[i] = r
+		if err != nil && !keepGoing {
+			cancel()
+		}
+		mu.Unlock()
+		switch {
+		case err == nil:
+			e := logger.Event{Prefix: "got", Message: target, Repository: target, Duration: r.elapsed}
+			if info.localRepository != nil {
+				e.Path = info.localRepository.FullPath
+			}
+			logger.Emit(e)
+		case keepGoing:
+			logger.Emit(logger.Event{Prefix: "error", Message: fmt.Sprintf("failed to get %q: %s", target, err),
+				Repository: target, Duration: r.elapsed, Err: err})
+			logHiddenStderr(err)
+		}
+		return info, err
+	}
+
 	eg := &errgroup.Group{}
 	sem := make(chan struct{}, 6)
-	for scr.Scan() {
+	for ctx.Err() == nil && scr.Scan() {
 		target := scr.Text()
-		if firstArg == "" {
-			firstArg = target
-		}
-		argCnt += 1
+		mu.Lock()
+		i := len(results)
+		results = append(results, getResult{target: target})
+		mu.Unlock()
 		if parallel {
+			g.progress.enqueue()
 			sem <- struct{}{}
 			eg.Go(func() error {
 				defer func() { <-sem }()
-				info, getErr := g.get(ctx, target)
-				getInfo, err = info, getErr
-				if getErr != nil {
-					logger.Logf("error", "failed to get %q: %s", target, getErr)
-				} else if info.localRepository != nil {
-					fmt.Println(info.localRepository.FullPath)
+				info, getErr := getTarget(i, target)
+				if getErr == nil && info.localRepository != nil {
+					if g.progress != nil {
+						g.progress.println(os.Stdout, info.localRepository.FullPath)
+					} else {
+						fmt.Println(info.localRepository.FullPath)
+					}
 				}
 				return nil
 			})
 		} else {
-			if getInfo, err = g.get(ctx, target); err != nil {
-				return fmt.Errorf("failed to get %q: %w", target, err)
+			info, getErr := getTarget(i, target)
+			if getErr != nil {
+				continue
 			}
-			if getInfo.localRepository != nil {
-				if !silent {
+			if info.localRepository != nil {
+				if !g.silent {
 					fmt.Fprintln(os.Stderr, "Got the repo to the following:")
 				}
-				fmt.Println(getInfo.localRepository.FullPath)
+				fmt.Println(info.localRepository.FullPath)
 			}
 		}
 	}
-	if err = scr.Err(); err != nil {
-		return fmt.Errorf("error occurred while reading input: %w", err)
+	if err := scr.Err(); err != nil {
+		return nil, fmt.Errorf("error occurred while reading input: %w", err)
 	}
-	if err = eg.Wait(); err != nil {
-		return err
+	if err := eg.Wait(); err != nil {
+		return nil, err
 	}
-	if andLook {
-		if argCnt > 1 && firstArg != "" {
-			return look(firstArg, g.bare)
-		}
-		if argCnt == 1 && getInfo.localRepository != nil {
-			return lookByLocalRepository(getInfo.localRepository)
+	g.progress.stop()
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// exitPartialFailure is the exit code when some of the repositories failed
+// to be got while the others succeeded.
+const exitPartialFailure = 3
+
+// A getResult is the result of getting a target of `ghq get`.
+type getResult struct {
+	target  string
+	info    getInfo
+	err     error
+	elapsed time.Duration
+	// canceled is set if the get was stopped by the failure of another
+	// target, in which case the target is not attempted rather than failed.
+	canceled bool
+}
+
+// summarizeFailures logs each failure, and returns the error to exit with.
+// The canceled targets are reported as not attempted.
+func summarizeFailures(failed []getResult, canceled, total int) error {
+	for _, r := range failed {
+		logger.Emit(logger.Event{Prefix: "failed", Message: fmt.Sprintf("%s: %s", r.target, describeGetError(r.err)),
+			Repository: r.target, Duration: r.elapsed, Err: r.err})
+		logHiddenStderr(r.err)
+	}
+	code := 1
+	if len(failed)+canceled < total {
+		code = exitPartialFailure
+	}
+	msg := fmt.Sprintf("failed to get %d of %d repositories", len(failed), total)
+	if canceled > 0 {
+		msg += fmt.Sprintf(" (%d not attempted)", canceled)
+	}
+	return cli.Exit(msg, code)
+}
+
+// describeGetError describes the error with the command which failed, if any.
+func describeGetError(err error) string {
+	var runErr *cmdutil.RunError
+	if errors.As(err, &runErr) {
+		return fmt.Sprintf("%s: %s", strings.Join(runErr.Command.Args, " "), runErr.ExecError)
+	}
+	return err.Error()
+}
+
+// logHiddenStderr logs the error output of the command failed silently, if
+// any, so that the failure can be investigated without running it again.
+func logHiddenStderr(err error) {
+	out := cmdutil.HiddenStderr(err)
+	if out == "" {
+		return
+	}
+	for line := range strings.Lines(out) {
+		// git rewrites its progress meter by "\r"
+		if i := strings.LastIndexByte(strings.TrimRight(line, "\r\n"), '\r'); i >= 0 {
+			line = line[i+1:]
 		}
+		logger.Log("stderr", strings.TrimRight(line, "\r\n"))
 	}
-	return nil
+}
+
+// writeFailedTargets writes the targets failed or not attempted to the file
+// one per line, so that they can be retried by `ghq get < file`.
+func writeFailedTargets(name string, targets []string) error {
+	var b strings.Builder
+	for _, target := range targets {
+		b.WriteString(target + "\n")
+	}
+	return os.WriteFile(name, []byte(b.String()), 0644)
 }
 
 type sliceScanner struct {
@@ -182,8 +399,15 @@ func look(name string, bare bool) error {
 	case 0:
 		return fmt.Errorf("no repository found")
 	case 1:
-		return lookByLocalRepository(reposFound[0])
+		return lookByLocalRepository(reposFound[0], "")
 	default:
+		repo, err := mostFrecent(reposFound)
+		if err != nil {
+			return err
+		}
+		if repo != nil {
+			return lookByLocalRepository(repo, "")
+		}
 		b := &strings.Builder{}
 		b.WriteString("More than one repositories are found; Try more precise name\n")
 		for _, repo := range reposFound {
@@ -193,12 +417,26 @@ func look(name string, bare bool) error {
 	}
 }
 
-func lookByLocalRepository(repo *LocalRepository) error {
+// lookByLocalRepository spawns a shell in the repository. If subpath is given
+// and exists, the shell starts in that directory of the repository.
+func lookByLocalRepository(repo *LocalRepository, subpath string) error {
+	dir := repo.FullPath
+	if subpath != "" {
+		if fi, err := os.Stat(filepath.Join(dir, filepath.FromSlash(subpath))); err == nil && fi.IsDir() {
+			dir = filepath.Join(dir, filepath.FromSlash(subpath))
+		}
+	}
+	recordVisitOrWarn(context.Background(), repo.FullPath)
+	look := filepath.ToSlash(repo.RelPath)
+	if ok, err := writeLookFile(dir, look); ok {
+		// the shell integration changes the directory
+		return err
+	}
 	cmd := exec.Command(detectShell())
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Dir = repo.FullPath
-	cmd.Env = append(os.Environ(), "GHQ_LOOK="+filepath.ToSlash(repo.RelPath))
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GHQ_LOOK="+look)
 	return cmdutil.RunCommand(cmd, true)
 }
@@ -3,15 +3,19 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/Songmu/gitconfig"
+	"github.com/urfave/cli/v3"
 	"github.com/x-motemen/ghq/cmdutil"
 	"github.com/x-motemen/ghq/logger"
 )
@@ -176,6 +180,51 @@ func TestCommandGet(t *testing.T) {
 				t.Errorf("got: %q, expect: %q", cloneArgs.branch, expectBranch)
 			}
 		},
+	}, {
+		name: "browser URL",
+		scenario: func(t *testing.T, tmpRoot string, cloneArgs *_cloneArgs, updateArgs *_updateArgs) {
+			localDir := filepath.Join(tmpRoot, "github.com", "motemen", "ghq-test-repo")
+
+			app.Run(context.Background(), []string{"", "get", "https://github.com/motemen/ghq-test-repo/tree/hello/cmdutil"})
+
+			expect := "https://github.com/motemen/ghq-test-repo"
+			if cloneArgs.remote.String() != expect {
+				t.Errorf("got: %s, expect: %s", cloneArgs.remote, expect)
+			}
+			if filepath.ToSlash(cloneArgs.local) != filepath.ToSlash(localDir) {
+				t.Errorf("got: %s, expect: %s", filepath.ToSlash(cloneArgs.local), filepath.ToSlash(localDir))
+			}
+			if cloneArgs.branch != "hello" {
+				t.Errorf("got: %q, expect: %q", cloneArgs.branch, "hello")
+			}
+		},
+	}, {
+		name: "browser URL of a commit",
+		scenario: func(t *testing.T, tmpRoot string, cloneArgs *_cloneArgs, updateArgs *_updateArgs) {
+			app.Run(context.Background(), []string{"", "get", "https://github.com/motemen/ghq-test-repo/commit/0123abcd"})
+
+			if cloneArgs.branch != "" {
+				t.Errorf("cloneArgs.branch should be empty but: %q", cloneArgs.branch)
+			}
+			if cloneArgs.revision != "0123abcd" {
+				t.Errorf("got: %q, expect: %q", cloneArgs.revision, "0123abcd")
+			}
+		},
+	}, {
+		name: "gitlab nested groups",
+		scenario: func(t *testing.T, tmpRoot string, cloneArgs *_cloneArgs, updateArgs *_updateArgs) {
+			localDir := filepath.Join(tmpRoot, "gitlab.com", "group", "sub", "project")
+
+			app.Run(context.Background(), []string{"", "get", "https://gitlab.com/group/sub/project.git"})
+
+			expect := "https://gitlab.com/group/sub/project.git"
+			if cloneArgs.remote.String() != expect {
+				t.Errorf("got: %s, expect: %s", cloneArgs.remote, expect)
+			}
+			if filepath.ToSlash(cloneArgs.local) != filepath.ToSlash(localDir) {
+				t.Errorf("got: %s, expect: %s", filepath.ToSlash(cloneArgs.local), filepath.ToSlash(localDir))
+			}
+		},
 	}, {
 		name: "with --no-recursive option",
 		scenario: func(t *testing.T, tmpRoot string, cloneArgs *_cloneArgs, updateArgs *_updateArgs) {
@@ -550,3 +599,196 @@ func TestDoGet_bulk(t *testing.T) {
 		}
 	})
 }
+
+func TestCommandGet_timeout(t *testing.T) {
+	withFakeGitBackend(t, func(t *testing.T, tmproot string, _ *_cloneArgs, _ *_updateArgs) {
+		GitBackend.Clone = func(ctx context.Context, vg *vcsGetOption) error {
+			// simulate a clone hanging after writing some files
+			os.MkdirAll(filepath.Join(vg.dir, ".git"), 0755)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		err := newApp().Run(context.Background(),
+			[]string{"", "get", "--timeout", "10ms", "motemen/ghq-test-repo"})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error should be deadline exceeded but: %v", err)
+		}
+		localDir := filepath.Join(tmproot, "github.com", "motemen", "ghq-test-repo")
+		if _, err := os.Stat(localDir); !os.IsNotExist(err) {
+			t.Errorf("partially cloned %s should be removed: %v", localDir, err)
+		}
+	})
+}
+
+func TestDoGet_failures(t *testing.T) {
+	testCases := []struct {
+		name       string
+		args       []string
+		in         []string
+		expectCode int
+		expectGot  []string
+		expectFail []string
+	}{{
+		name:       "parallel keeps going",
+		args:       []string{"-parallel"},
+		in:         []string{"motemen/ok1", "motemen/fail1", "motemen/ok2"},
+		expectCode: exitPartialFailure,
+		expectGot:  []string{"ok1", "ok2"},
+		expectFail: []string{"motemen/fail1"},
+	}, {
+		name:       "all failed",
+		args:       []string{"-parallel"},
+		in:         []string{"motemen/fail1", "motemen/fail2"},
+		expectCode: 1,
+		expectFail: []string{"motemen/fail1", "motemen/fail2"},
+	}, {
+		name:       "sequential keep going",
+		args:       []string{"--keep-going"},
+		in:         []string{"motemen/ok1", "motemen/fail1", "motemen/ok2"},
+		expectCode: exitPartialFailure,
+		expectGot:  []string{"ok1", "ok2"},
+		expectFail: []string{"motemen/fail1"},
+	}, {
+		name:       "sequential stops at the first failure",
+		in:         []string{"motemen/ok1", "motemen/fail1", "motemen/ok2"},
+		expectCode: 1,
+		expectGot:  []string{"ok1"},
+		expectFail: []string{"motemen/fail1", "motemen/ok2"},
+	}}
+
+	buf := &bytes.Buffer{}
+	logger.SetOutput(buf)
+	defer func() { logger.SetOutput(os.Stderr) }()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			withFakeGitBackend(t, func(t *testing.T, tmproot string, _ *_cloneArgs, _ *_updateArgs) {
+				var (
+					mu  sync.Mutex
+					got []string
+				)
+				GitBackend.Clone = func(_ context.Context, vg *vcsGetOption) error {
+					name := filepath.Base(stagedDestination(vg.dir))
+					if strings.HasPrefix(name, "fail") {
+						return &cmdutil.RunError{
+							Command:   exec.Command("git", "clone", vg.url.String()),
+							ExecError: errors.New("exit status 128"),
+							Stderr:    "Receiving objects:  10%\rfatal: early EOF\n",
+							Silent:    true,
+						}
+					}
+					mu.Lock()
+					got = append(got, name)
+					mu.Unlock()
+					return os.MkdirAll(vg.dir, 0755)
+				}
+				for _, r := range tc.in {
+					seen.Delete(filepath.Join(tmproot, "github.com", r))
+				}
+				buf.Reset()
+				failedFile := filepath.Join(tmproot, "failed.txt")
+				var err error
+				captureWithInput(tc.in, func() {
+					args := append([]string{"", "get", "--failed-file", failedFile}, tc.args...)
+					err = newApp().Run(context.Background(), args)
+				})
+
+				code := 0
+				if err != nil {
+					code = 1
+					var exitErr cli.ExitCoder
+					if errors.As(err, &exitErr) {
+						code = exitErr.ExitCode()
+					}
+				}
+				if code != tc.expectCode {
+					t.Errorf("exit code: got: %d, expect: %d (%v)", code, tc.expectCode, err)
+				}
+				slices.Sort(got)
+				if !slices.Equal(got, tc.expectGot) {
+					t.Errorf("got: %v, expect: %v", got, tc.expectGot)
+				}
+				b, _ := os.ReadFile(failedFile)
+				failed := strings.Fields(string(b))
+				slices.Sort(failed)
+				if !slices.Equal(failed, tc.expectFail) {
+					t.Errorf("failed targets: got: %v, expect: %v", failed, tc.expectFail)
+				}
+				if tc.expectCode == exitPartialFailure {
+					summary := "motemen/fail1: git clone https://github.com/motemen/fail1: exit status 128"
+					if !strings.Contains(buf.String(), summary) {
+						t.Errorf("log should contain the summary %q, but: %s", summary, buf.String())
+					}
+					if !strings.Contains(buf.String(), "fatal: early EOF") || strings.Contains(buf.String(), "Receiving objects") {
+						t.Errorf("log should contain the last error output, but: %s", buf.String())
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestDoGet_failFast(t *testing.T) {
+	withFakeGitBackend(t, func(t *testing.T, tmproot string, _ *_cloneArgs, _ *_updateArgs) {
+		GitBackend.Clone = func(ctx context.Context, vg *vcsGetOption) error {
+			if strings.HasSuffix(stagedDestination(vg.dir), "fail") {
+				return errors.New("failed")
+			}
+			// the others hang until canceled
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		failedFile := filepath.Join(tmproot, "failed.txt")
+		err := newApp().Run(context.Background(),
+			[]string{"", "get", "-parallel", "--fail-fast", "--failed-file", failedFile,
+				"motemen/slow1", "motemen/slow2", "motemen/fail"})
+		if err == nil {
+			t.Fatal("error should not be nil")
+		}
+		if !strings.Contains(err.Error(), "failed to get 1 of 3 repositories (2 not attempted)") {
+			t.Errorf("in-flight gets should be canceled and not counted as failures, but: %s", err)
+		}
+		var exitErr cli.ExitCoder
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			t.Errorf("exit code should be 1, but: %v", err)
+		}
+		b, _ := os.ReadFile(failedFile)
+		failed := strings.Fields(string(b))
+		slices.Sort(failed)
+		if expect := []string{"motemen/fail", "motemen/slow1", "motemen/slow2"}; !slices.Equal(failed, expect) {
+			t.Errorf("failed targets: got: %v, expect: %v", failed, expect)
+		}
+	})
+
+	err := newApp().Run(context.Background(), []string{"", "get", "--keep-going", "--fail-fast", "motemen/ghq"})
+	if err == nil {
+		t.Errorf("--keep-going and --fail-fast should be exclusive")
+	}
+}
+
+func TestDoGet_org(t *testing.T) {
+	newFakeForge(t, "github", map[string]string{
+		"/orgs/my-org/repos?type=all&per_page=100": `
+[{"full_name": "my-org/a", "clone_url": "https://github.com/my-org/a.git"},
+ {"full_name": "my-org/b", "clone_url": "https://github.com/my-org/b.git", "archived": true}]`,
+	})
+	withFakeGitBackend(t, func(t *testing.T, tmproot string, _ *_cloneArgs, _ *_updateArgs) {
+		out, _, err := capture(func() {
+			if err := newApp().Run(context.Background(), []string{"", "get", "--org", "git.example.com/my-org"}); err != nil {
+				t.Errorf("error should be nil but: %s", err)
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := filepath.Join(tmproot, "github.com", "my-org", "a") + "\n"
+		if out != expect {
+			t.Errorf("got: %q, expect: %q", out, expect)
+		}
+
+		err = newApp().Run(context.Background(), []string{"", "get", "--org", "git.example.com/my-org", "motemen/ghq"})
+		if err == nil {
+			t.Error("repositories with --org should be an error")
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v3"
+)
+
+func doIdentityCheck(ctx context.Context, cmd *cli.Command) error {
+	var (
+		w     = cmd.Root().Writer
+		query = cmd.Args().First()
+		fix   = cmd.Bool("fix")
+	)
+
+	var (
+		mu    sync.Mutex
+		repos []*LocalRepository
+	)
+	if err := walkLocalRepositories("git", func(repo *LocalRepository) {
+		if query != "" && !strings.Contains(filepath.ToSlash(repo.RelPath), query) {
+			return
+		}
+		mu.Lock()
+		repos = append(repos, repo)
+		mu.Unlock()
+	}); err != nil {
+		return err
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].FullPath < repos[j].FullPath })
+
+	var mismatched int
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		mismatches, err := checkIdentity(ctx, repo)
+		if err != nil {
+			return err
+		}
+		if len(mismatches) == 0 {
+			continue
+		}
+		mismatched++
+		for _, m := range mismatches {
+			fmt.Fprintf(w, "%s: %s is %q, expected %q\n",
+				filepath.ToSlash(repo.RelPath), m.setting.key, m.effective, m.setting.value)
+		}
+		if fix {
+			if err := applyIdentity(ctx, repo.RootPath, repo.FullPath); err != nil {
+				return err
+			}
+		}
+	}
+	if mismatched == 0 || fix {
+		return nil
+	}
+	return cli.Exit(fmt.Sprintf("%d of %d repositories have a mismatched identity", mismatched, len(repos)), 1)
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/urfave/cli/v3"
 )
@@ -20,51 +24,18 @@ func doList(ctx context.Context, cmd *cli.Command) error {
 		printFullPaths   = cmd.Bool("full-path")
 		printUniquePaths = cmd.Bool("unique")
 		bare             = cmd.Bool("bare")
+		order            = cmd.String("sort")
+		stale            = cmd.Bool("stale")
+		opt              = walkOptions{nested: cmd.Bool("nested"), submodules: cmd.Bool("submodules")}
 	)
 
-	filterByQuery := func(_ *LocalRepository) bool {
-		return true
-	}
-	if query != "" {
-		if hasSchemePattern.MatchString(query) || scpLikeURLPattern.MatchString(query) {
-			if url, err := newURL(query, false, false); err == nil {
-				if repo, err := LocalRepositoryFromURL(url, bare); err == nil {
-					query = filepath.ToSlash(repo.RelPath)
-				}
-			}
-		}
-
-		if exact {
-			filterByQuery = func(repo *LocalRepository) bool {
-				return repo.Matches(query)
-			}
-		} else {
-			var host string
-			paths := strings.Split(query, "/")
-			if len(paths) > 1 && looksLikeAuthorityPattern.MatchString(paths[0]) {
-				query = strings.Join(paths[1:], "/")
-				host = paths[0]
-			}
-			// Using smartcase searching
-			if strings.ToLower(query) == query {
-				filterByQuery = func(repo *LocalRepository) bool {
-					return strings.Contains(strings.ToLower(repo.NonHostPath()), query) &&
-						(host == "" || repo.PathParts[0] == host)
-				}
-			} else {
-				filterByQuery = func(repo *LocalRepository) bool {
-					return strings.Contains(repo.NonHostPath(), query) &&
-						(host == "" || repo.PathParts[0] == host)
-				}
-			}
-		}
-	}
+	filterByQuery := repositoryFilter(query, exact, bare)
 
 	var (
 		repos []*LocalRepository
 		mu    sync.Mutex
 	)
-	if err := walkLocalRepositories(vcsBackend, func(repo *LocalRepository) {
+	if err := walkLocalRepositoriesWith(vcsBackend, opt, func(repo *LocalRepository) {
 		if !filterByQuery(repo) {
 			return
 		}
@@ -74,6 +45,25 @@ func doList(ctx context.Context, cmd *cli.Command) error {
 	}); err != nil {
 		return fmt.Errorf("failed to filter repos while walkLocalRepositories(repo): %w", err)
 	}
+	if stale {
+		audits, err := auditRepositories(ctx, repos)
+		if err != nil {
+			return err
+		}
+		repos = repos[:0]
+		for _, a := range audits {
+			if isStale(a.stale) {
+				repos = append(repos, a.repo)
+			}
+		}
+	}
+
+	byName := order == "" || order == "name"
+	if !byName {
+		if err := sortRepositories(repos, order); err != nil {
+			return err
+		}
+	}
 
 	repoList := make([]string, 0, len(repos))
 	if printUniquePaths {
@@ -98,7 +88,7 @@ func doList(ctx context.Context, cmd *cli.Command) error {
 
 			for _, p := range repo.Subpaths() {
 				if subpathCount[p] == 1 {
-					repoList = append(repoList, p)
+					repoList = append(repoList, p+repo.annotation(false))
 					break
 				}
 			}
@@ -106,15 +96,136 @@ func doList(ctx context.Context, cmd *cli.Command) error {
 	} else {
 		for _, repo := range repos {
 			if printFullPaths {
-				repoList = append(repoList, repo.FullPath)
+				repoList = append(repoList, repo.FullPath+repo.annotation(true))
 			} else {
-				repoList = append(repoList, repo.RelPath)
+				repoList = append(repoList, repo.RelPath+repo.annotation(false))
 			}
 		}
 	}
-	sort.Strings(repoList)
+	if byName {
+		sort.Strings(repoList)
+	}
 	for _, r := range repoList {
 		fmt.Fprintln(w, r)
 	}
 	return nil
 }
+
+// annotation returns the parent of the repository found by walking with
+// walkOptions after a tab, such as "\t(nested in github.com/me/app)", or ""
+// if it has no parent.
+func (repo *LocalRepository) annotation(fullPath bool) string {
+	if repo.parent == nil {
+		return ""
+	}
+	parent := repo.parent.RelPath
+	if fullPath {
+		parent = repo.parent.FullPath
+	}
+	if repo.submodule {
+		return fmt.Sprintf("\t(submodule of %s)", parent)
+	}
+	return fmt.Sprintf("\t(nested in %s)", parent)
+}
+
+// repositoryFilter returns the filter of local repositories by the query of
+// 'ghq list'. Unless exact, the query is searched for in the paths without
+// hosts in smart case, and can be prefixed by a host.
+func repositoryFilter(query string, exact, bare bool) func(*LocalRepository) bool {
+	filterByQuery := func(_ *LocalRepository) bool {
+		return true
+	}
+	if query != "" {
+		if hasSchemePattern.MatchString(query) || scpLikeURLPattern.MatchString(query) {
+			if url, err := newURL(query, false, false); err == nil {
+				if repo, err := LocalRepositoryFromURL(url, bare); err == nil {
+					query = filepath.ToSlash(repo.RelPath)
+				}
+			}
+		}
+
+		if exact {
+			filterByQuery = func(repo *LocalRepository) bool {
+				return repo.Matches(query)
+			}
+		} else {
+			var host string
+			paths := strings.Split(query, "/")
+			if len(paths) > 1 && looksLikeAuthorityPattern.MatchString(paths[0]) {
+				query = strings.Join(paths[1:], "/")
+				host = paths[0]
+			}
+			// Using smartcase searching
+			if strings.ToLower(query) == query {
+				filterByQuery = func(repo *LocalRepository) bool {
+					return strings.Contains(strings.ToLower(repo.NonHostPath()), query) &&
+						(host == "" || repo.PathParts[0] == host)
+				}
+			} else {
+				filterByQuery = func(repo *LocalRepository) bool {
+					return strings.Contains(repo.NonHostPath(), query) &&
+						(host == "" || repo.PathParts[0] == host)
+				}
+			}
+		}
+	}
+	return filterByQuery
+}
+
+// listSortOrders are the orders of 'ghq list --sort'.
+var listSortOrders = []string{"name", "frecency", "recent", "mtime"}
+
+// sortRepositories sorts the repositories in the order, which is one of
+// listSortOrders. The ones in the same rank are sorted by name.
+func sortRepositories(repos []*LocalRepository, order string) error {
+	var rank func(*LocalRepository) float64 // the larger comes first
+	switch order {
+	case "name":
+		rank = func(*LocalRepository) float64 { return 0 }
+	case "frecency", "recent":
+		visits, err := readVisits()
+		if err != nil {
+			return err
+		}
+		now := visitNow()
+		rank = func(repo *LocalRepository) float64 {
+			if order == "recent" {
+				return float64(visits[repo.FullPath].Last.UnixMilli())
+			}
+			return visits[repo.FullPath].frecency(now)
+		}
+	case "mtime":
+		rank = func(repo *LocalRepository) float64 {
+			return float64(repositoryModTime(repo).UnixMilli())
+		}
+	default:
+		return fmt.Errorf("unknown sort order %q: %s are available", order, strings.Join(listSortOrders, ", "))
+	}
+	ranks := make(map[*LocalRepository]float64, len(repos))
+	for _, repo := range repos {
+		ranks[repo] = rank(repo)
+	}
+	slices.SortStableFunc(repos, func(a, b *LocalRepository) int {
+		if c := cmp.Compare(ranks[b], ranks[a]); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.RelPath, b.RelPath); c != 0 {
+			return c
+		}
+		return strings.Compare(a.FullPath, b.FullPath)
+	})
+	return nil
+}
+
+// repositoryModTime returns the latest modification time of the directory
+// of the repository and its VCS metadata, such as .git, which is updated
+// by commits and fetches.
+func repositoryModTime(repo *LocalRepository) time.Time {
+	var latest time.Time
+	for _, p := range append([]string{""}, vcsContents[:]...) {
+		if fi, err := os.Stat(filepath.Join(repo.FullPath, p)); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest
+}
@@ -233,3 +233,52 @@ func TestDoList_withSystemHiddenDir(t *testing.T) {
 		t.Errorf("error should be nil, but: %v", err)
 	}
 }
+
+func TestDoList_nested(t *testing.T) {
+	setSyncRoot(t)
+	root, err := primaryLocalRepositoryRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, lib := newUpstream(t, "lib")
+	app := initGitRepo(t, filepath.Join(root, "github.com", "alice", "app"), "https://github.com/alice/app.git")
+	initGitRepo(t, filepath.Join(app, "vendor", "vendored"), "https://github.com/carol/vendored.git")
+	git(t, app, "-c", "protocol.file.allow=always", "submodule", "--quiet", "add", lib, "third_party/lib")
+
+	testCases := []struct {
+		name   string
+		args   []string
+		expect string
+	}{{
+		name:   "default",
+		args:   nil,
+		expect: "github.com/alice/app\n",
+	}, {
+		name:   "nested",
+		args:   []string{"--nested"},
+		expect: "github.com/alice/app\ngithub.com/alice/app/vendor/vendored\t(nested in github.com/alice/app)\n",
+	}, {
+		name:   "submodules",
+		args:   []string{"--submodules"},
+		expect: "github.com/alice/app\ngithub.com/alice/app/third_party/lib\t(submodule of github.com/alice/app)\n",
+	}, {
+		name:   "full path",
+		args:   []string{"--nested", "-p"},
+		expect: app + "\n" + filepath.Join(app, "vendor", "vendored") + "\t(nested in " + app + ")\n",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, _, err := capture(func() {
+				if err := newApp().Run(context.Background(), append([]string{"", "list"}, tc.args...)); err != nil {
+					t.Errorf("error should be nil, but: %s", err)
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tc.expect {
+				t.Errorf("got: %q, expect: %q", out, tc.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli/v3"
+)
+
+func doLook(ctx context.Context, cmd *cli.Command) error {
+	var (
+		w         = cmd.Root().Writer
+		query     = cmd.Args().First()
+		exact     = cmd.Bool("exact")
+		bare      = cmd.Bool("bare")
+		printPath = cmd.Bool("print")
+	)
+
+	filterByQuery := repositoryFilter(query, exact, bare)
+	var (
+		repos []*LocalRepository
+		mu    sync.Mutex
+	)
+	if err := walkAllLocalRepositories(func(repo *LocalRepository) {
+		if !filterByQuery(repo) {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		repos = append(repos, repo)
+	}); err != nil {
+		return err
+	}
+
+	repo, err := chooseRepository(ctx, query, repos, cmd.Bool("select"))
+	if err != nil {
+		return err
+	}
+	if printPath {
+		_, err := fmt.Fprintln(w, repo.FullPath)
+		return err
+	}
+	return lookByLocalRepository(repo, "")
+}
+
+// chooseRepository chooses the repository to look from the candidates found
+// by the query. The one which exactly matches the query wins, and the most
+// frecently visited one is preferred if ambiguous, unless selectAlways.
+// Otherwise the user chooses one by $GHQ_SELECTOR or the built-in selector,
+// in which the candidates are ordered by frecency.
+func chooseRepository(ctx context.Context, query string, repos []*LocalRepository, selectAlways bool) (*LocalRepository, error) {
+	// a repository in the primary root hides the same one in the others
+	byPath := map[string]*LocalRepository{}
+	for _, repo := range repos {
+		p := filepath.ToSlash(repo.RelPath)
+		if r, ok := byPath[p]; !ok || !r.IsUnderPrimaryRoot() && repo.IsUnderPrimaryRoot() {
+			byPath[p] = repo
+		}
+	}
+	paths := slices.Sorted(maps.Keys(byPath))
+	uniq := make([]*LocalRepository, 0, len(paths))
+	var matched []*LocalRepository
+	for _, p := range paths {
+		repo := byPath[p]
+		if query != "" && repo.Matches(query) {
+			matched = append(matched, repo)
+		}
+		uniq = append(uniq, repo)
+	}
+	// the query may match more than one exactly, e.g. "ghq" for a/ghq and
+	// b/ghq, which are chosen from as well as the others
+	if len(matched) == 1 {
+		return matched[0], nil
+	} else if len(matched) > 1 {
+		uniq = matched
+	}
+
+	switch len(uniq) {
+	case 0:
+		return nil, fmt.Errorf("no repository found")
+	case 1:
+		return uniq[0], nil
+	}
+	if query != "" && !selectAlways {
+		repo, err := mostFrecent(uniq)
+		if err != nil {
+			return nil, err
+		}
+		if repo != nil {
+			return repo, nil
+		}
+	}
+	if err := sortRepositories(uniq, "frecency"); err != nil {
+		return nil, err
+	}
+	candidates := make([]string, 0, len(uniq))
+	for _, repo := range uniq {
+		candidates = append(candidates, filepath.ToSlash(repo.RelPath))
+	}
+
+	var (
+		chosen string
+		err    error
+	)
+	if selector := os.Getenv("GHQ_SELECTOR"); selector != "" {
+		chosen, err = selectByCommand(ctx, selector, candidates)
+	} else if isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		chosen, err = selectByPrompt(os.Stdin, os.Stderr, candidates)
+	} else {
+		b := &strings.Builder{}
+		b.WriteString("More than one repositories are found; Try more precise name\n")
+		for _, c := range candidates {
+			b.WriteString(fmt.Sprintf("       - %s\n", c))
+		}
+		return nil, fmt.Errorf("%s", b.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	repo, ok := byPath[chosen]
+	if !ok {
+		return nil, fmt.Errorf("unknown repository is selected: %q", chosen)
+	}
+	return repo, nil
+}
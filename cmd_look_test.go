@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDoLook_print(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command is not available")
+	}
+	defer func(orig string) { _home = orig }(_home)
+	_home = ""
+	homeOnce = &sync.Once{}
+	tmpd := newTempDir(t)
+	defer func(orig []string) { _localRepositoryRoots = orig }(_localRepositoryRoots)
+	setEnv(t, envGhqRoot, tmpd)
+	_localRepositoryRoots = nil
+	localRepoOnce = &sync.Once{}
+
+	for _, p := range []string{
+		"github.com/motemen/ghq",
+		"github.com/motemen/gore",
+		"github.com/Songmu/ghq-handler",
+		"github.com/a/dup",
+		"github.com/b/dup",
+		"github.com/b/dup-tool",
+	} {
+		if err := os.MkdirAll(filepath.Join(tmpd, filepath.FromSlash(p), ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		args      []string
+		selector  string
+		expect    string
+		expectErr bool
+	}{{
+		name:   "unique",
+		args:   []string{"gore"},
+		expect: "github.com/motemen/gore",
+	}, {
+		name:   "exact match wins",
+		args:   []string{"ghq"},
+		expect: "github.com/motemen/ghq",
+	}, {
+		name:     "exact matches are chosen from",
+		args:     []string{"dup"},
+		selector: "tail -n 1",
+		expect:   "github.com/b/dup",
+	}, {
+		name:      "exact matches are ambiguous",
+		args:      []string{"dup"},
+		expectErr: true,
+	}, {
+		name:     "selector",
+		args:     []string{"motemen"},
+		selector: "tail -n 1",
+		expect:   "github.com/motemen/gore",
+	}, {
+		name:      "ambiguous",
+		args:      []string{"motemen"},
+		expectErr: true,
+	}, {
+		name:      "exact",
+		args:      []string{"-e", "motemen"},
+		expectErr: true,
+	}, {
+		name:      "not found",
+		args:      []string{"unknown"},
+		expectErr: true,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setEnv(t, "GHQ_SELECTOR", tc.selector)
+			var runErr error
+			// the standard input is not a terminal
+			out, _, err := captureWithInput(nil, func() {
+				runErr = newApp().Run(context.Background(),
+					append([]string{"", "look", "--print"}, tc.args...))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotErr := runErr != nil; gotErr != tc.expectErr {
+				t.Fatalf("error = %v, expectErr = %v", runErr, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+			expect := filepath.Join(tmpd, filepath.FromSlash(tc.expect))
+			if got := strings.TrimSpace(out); got != expect {
+				t.Errorf("got: %q, expect: %q", got, expect)
+			}
+		})
+	}
+}
@@ -5,13 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"syscall"
 
 	"github.com/otiai10/copy"
 	"github.com/urfave/cli/v3"
-	"github.com/x-motemen/ghq/logger"
 )
 
 func doMigrate(ctx context.Context, cmd *cli.Command) error {
@@ -100,6 +98,9 @@ func doMigrate(ctx context.Context, cmd *cli.Command) error {
 		}
 	}
 
+	home, _ := getHome()
+	warnNestedRepositories(absDir, home, "moved")
+
 	// Dry-run mode
 	if dry {
 		fmt.Fprintf(w, "Would migrate %s to %s\n", absDir, destPath)
@@ -120,6 +121,20 @@ func doMigrate(ctx context.Context, cmd *cli.Command) error {
 		}
 	}
 
+	if cmd.Bool("no-wait") {
+		noWaitLock = true
+		defer func() { noWaitLock = false }()
+	}
+	unlock, err := lockRepository(ctx, absDir, destPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// another process may have cloned it while we were waiting for the lock
+	if _, err := os.Stat(destPath); err == nil {
+		return fmt.Errorf("destination directory %q already exists", destPath)
+	}
+
 	// Create parent directories
 	destDir := filepath.Dir(destPath)
 	if err := os.MkdirAll(destDir, 0755); err != nil {
@@ -130,31 +145,15 @@ func doMigrate(ctx context.Context, cmd *cli.Command) error {
 	if err := moveDir(absDir, destPath); err != nil {
 		return fmt.Errorf("failed to move repository: %w", err)
 	}
+	invalidateRepositoryIndex()
 
-	// Repair linked worktrees so their .git files reference the new location.
-	//
-	// For each worktree, two pointers exist:
-	//   back-pointer:    .git/worktrees/<name>/gitdir  → worktree working dir
-	//   forward ref:     <worktree>/.git               → main repo's .git/worktrees/<name>
-	//
-	// External worktrees (outside the repo) didn't move, so only the forward
-	// ref is stale. Internal worktrees (inside the repo) moved along with
-	// the repo, so BOTH pointers are stale. We fix the back-pointers first
-	// so that "git worktree repair" can match entries to update the forward refs.
 	if hasWorktrees {
-		wtPaths, wtErr := repairWorktreeBackPointers(absDir, destPath)
-		if wtErr != nil {
-			logger.Log("warning", fmt.Sprintf("failed to discover linked worktree paths: %v", wtErr))
-		} else if len(wtPaths) > 0 {
-			args := append([]string{"worktree", "repair"}, wtPaths...)
-			cmd := exec.Command("git", args...)
-			cmd.Dir = destPath
-			if out, err := cmd.CombinedOutput(); err != nil {
-				logger.Log("warning", fmt.Sprintf("git worktree repair failed: %v\n%s", err, out))
-			}
-		}
+		repairWorktrees(absDir, destPath)
 	}
 
+	runPostHooks(ctx, hookEvent{hook: hookPostMigrate, action: "migrate",
+		path: destPath, url: u.String(), vcs: vcsName(vcsBackend)}, false)
+
 	fmt.Fprintln(w, destPath)
 	return nil
 }
@@ -175,7 +174,8 @@ func moveDir(src, dst string) error {
 		return renameErr
 	}
 
-	// Fallback: copy directory tree using otiai10/copy, then remove source
+	// Fallback: copy directory tree using otiai10/copy into a staging
+	// directory, rename it into place, then remove source
 	opt := copy.Options{
 		// Preserve symlinks as-is
 		OnSymlink: func(src string) copy.SymlinkAction {
@@ -192,13 +192,18 @@ func moveDir(src, dst string) error {
 		},
 	}
 
-	copyErr := copy.Copy(src, dst, opt)
-	if copyErr != nil {
-		// Attempt to cleanup partial copy
-		if cleanupErr := os.RemoveAll(dst); cleanupErr != nil {
-			return fmt.Errorf("copy failed: %w (cleanup also failed: %v)", copyErr, cleanupErr)
-		}
-		return copyErr
+	s, err := newStaging(dst)
+	if err != nil {
+		return err
+	}
+	if err := copy.Copy(src, s.dir, opt); err != nil {
+		// partial copy is removed with the staging directory
+		s.cleanup()
+		return err
+	}
+	if err := s.commit(s.dir); err != nil {
+		s.cleanup()
+		return err
 	}
 
 	return os.RemoveAll(src)
@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+
+	"github.com/x-motemen/ghq/filelock"
 )
 
 // initGitRepo creates a git repo at dir with the given remote URL and an
@@ -65,6 +68,15 @@ func TestDoMigrate(t *testing.T) {
 		c2.Dir = srcdir
 		c2.Run()
 
+		idx, err := repositoryIndexPath()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(idx, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		defer invalidateRepositoryIndex()
+
 		a := newApp()
 		e := a.Run(context.Background(), []string{"ghq", "migrate", "-y", srcdir})
 		if e != nil {
@@ -75,6 +87,9 @@ func TestDoMigrate(t *testing.T) {
 		if _, err := os.Stat(dest); os.IsNotExist(err) {
 			t.Error("dest not found")
 		}
+		if _, err := os.Stat(idx); !os.IsNotExist(err) {
+			t.Errorf("the index should be removed, but: %v", err)
+		}
 	})
 
 	// Test case: nonexistent directory
@@ -309,6 +324,26 @@ func TestMigrateEdgeCases(t *testing.T) {
 			t.Errorf("expected 'not supported' error, got: %v", e)
 		}
 	})
+
+	t.Run("source_locked", func(t *testing.T) {
+		srcdir := initGitRepo(t, filepath.Join(tmpdir, "src7", "locked"), "https://github.com/alice/locked.git")
+
+		// updated by another ghq process
+		unlock, err := lockRepository(context.Background(), srcdir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer unlock()
+
+		a := newApp()
+		e := a.Run(context.Background(), []string{"ghq", "migrate", "-y", "--no-wait", srcdir})
+		if !errors.Is(e, filelock.ErrLocked) {
+			t.Errorf("error should be ErrLocked but: %v", e)
+		}
+		if _, err := os.Stat(srcdir); err != nil {
+			t.Errorf("locked repository should not be moved, but: %v", err)
+		}
+	})
 }
 
 func TestMoveDir(t *testing.T) {
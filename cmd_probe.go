@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+func doProbe(ctx context.Context, cmd *cli.Command) error {
+	var (
+		arg = cmd.Args().First()
+		w   = cmd.Root().Writer
+	)
+	if arg == "" {
+		return fmt.Errorf("repository URL is required")
+	}
+
+	u, err := newURL(arg, false, false)
+	if err != nil {
+		return fmt.Errorf("could not parse URL %q: %w", arg, err)
+	}
+	u, _ = parseBrowserURL(u)
+	remote, err := NewRemoteRepository(u)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%-14s %s\n", "url", u)
+
+	if _, ok := remote.(*OtherRepository); !ok {
+		backend, repoURL, err := remote.VCS(ctx)
+		if err != nil {
+			return err
+		}
+		kind := strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", remote), "*main."), "Repository")
+		fmt.Fprintf(w, "%-14s %s is handled as %s without probing\n", "host", u.Host, kind)
+		fmt.Fprintf(w, "=> %s %s (detected by host)\n", vcsName(backend), repoURL)
+		return nil
+	}
+
+	d := newVCSDetector()
+	d.offline = offlineMode || cmd.Bool("offline")
+	d.noCache = cmd.Bool("no-cache")
+	d.trace = func(detector, message string) {
+		fmt.Fprintf(w, "%-14s %s\n", detector, message)
+	}
+	detected, err := d.detect(ctx, u)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "=> %s %s (detected by %s)\n", vcsName(detected.backend), detected.url, detected.detector)
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/x-motemen/ghq/cmdutil"
+)
+
+func TestDoProbe(t *testing.T) {
+	defer func(orig func(cmd *exec.Cmd) error) {
+		cmdutil.CommandRunner = orig
+	}(cmdutil.CommandRunner)
+	var probed []string
+	cmdutil.CommandRunner = func(cmd *exec.Cmd) error {
+		probed = append(probed, strings.Join(cmd.Args[:2], " "))
+		if cmd.Args[0] == "git" {
+			return nil
+		}
+		return fmt.Errorf("[test] failed to %s", cmd.Args[0])
+	}
+	defer func(orig *vcsCache, once *sync.Once) { _vcsCache, vcsCacheOnce = orig, once }(_vcsCache, vcsCacheOnce)
+	_vcsCache, _ = readVCSCache(filepath.Join(newTempDir(t), "vcs.json"))
+	vcsCacheOnce = &sync.Once{}
+	vcsCacheOnce.Do(func() {})
+
+	run := func(args ...string) (string, error) {
+		app := newApp()
+		out := &bytes.Buffer{}
+		app.Writer = out
+		err := app.Run(context.Background(), append([]string{"", "probe"}, args...))
+		return out.String(), err
+	}
+
+	out, err := run("--offline", "https://git.example.com/foo/bar")
+	if err == nil {
+		t.Errorf("error should occur in offline mode without cache, but: %s", out)
+	}
+	if len(probed) > 0 {
+		t.Errorf("network should not be probed in offline mode, but: %v", probed)
+	}
+
+	out, err = run("https://git.example.com/foo/bar")
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if !strings.Contains(out, "=> git https://git.example.com/foo/bar (detected by git ls-remote)") {
+		t.Errorf("output should tell the detector, but: %s", out)
+	}
+	if !strings.Contains(out, `svn info       deferred: host does not start with "svn."`) {
+		t.Errorf("output should tell why svn is not probed, but: %s", out)
+	}
+
+	probed = nil
+	out, err = run("--offline", "https://git.example.com/foo/bar")
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if !strings.Contains(out, "=> git https://git.example.com/foo/bar (detected by cache)") {
+		t.Errorf("cached backend should be used, but: %s", out)
+	}
+	if len(probed) > 0 {
+		t.Errorf("network should not be probed with cache, but: %v", probed)
+	}
+	out, err = run("--offline", "https://git.example.com/foo/baz")
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if !strings.Contains(out, "=> git https://git.example.com/foo/baz (detected by cache)") {
+		t.Errorf("other repositories on the host should be cached, but: %s", out)
+	}
+
+	out, err = run("--no-cache", "https://svn.example.com/foo/baz")
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if probed[0] != "svn info" || probed[1] != "git ls-remote" {
+		t.Errorf("svn should be probed first for svn.* hosts, but: %v", probed)
+	}
+}
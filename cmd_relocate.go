@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v3"
+	"github.com/x-motemen/ghq/logger"
+)
+
+// errNothingToRelocate is returned by planRelocation when the repository is
+// already at the path of the URL.
+var errNothingToRelocate = errors.New("nothing to relocate")
+
+func doRelocate(ctx context.Context, cmd *cli.Command) error {
+	var (
+		query     = cmd.Args().Get(0)
+		newRemote = cmd.Args().Get(1)
+		all       = cmd.Bool("all")
+		w         = cmd.Root().Writer
+	)
+	if cmd.Bool("no-wait") {
+		noWaitLock = true
+		defer func() { noWaitLock = false }()
+	}
+	if all {
+		if query != "" {
+			return fmt.Errorf("--all takes no arguments")
+		}
+		return relocateAll(ctx, cmd)
+	}
+	if query == "" {
+		return fmt.Errorf("repository is required")
+	}
+
+	repo, err := findLocalRepository(query)
+	if err != nil {
+		return err
+	}
+	r, err := planRelocation(repo, newRemote)
+	if errors.Is(err, errNothingToRelocate) {
+		return fmt.Errorf("repository is already at the correct location: %s", repo.FullPath)
+	}
+	if err != nil {
+		return err
+	}
+	ok, err := r.run(ctx, w, cmd.Bool("dry-run"), cmd.Bool("y"))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("relocation aborted by user")
+	}
+	return nil
+}
+
+// relocateAll relocates all the local repositories whose paths disagree
+// with their remote URLs.
+func relocateAll(ctx context.Context, cmd *cli.Command) error {
+	var (
+		mu    sync.Mutex
+		repos []*LocalRepository
+	)
+	if err := walkAllLocalRepositories(func(repo *LocalRepository) {
+		mu.Lock()
+		repos = append(repos, repo)
+		mu.Unlock()
+	}); err != nil {
+		return err
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].FullPath < repos[j].FullPath })
+
+	var failed int
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		r, err := relocationOf(repo)
+		if err != nil {
+			logger.Log("warning", fmt.Sprintf("%s: %s", repo.RelPath, err))
+			failed++
+			continue
+		}
+		if r == nil {
+			continue
+		}
+		if _, err := r.run(ctx, cmd.Root().Writer, cmd.Bool("dry-run"), cmd.Bool("y")); err != nil {
+			logger.Log("error", fmt.Sprintf("%s: %s", repo.RelPath, err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to relocate %d repositories", failed)
+	}
+	return nil
+}
+
+// relocationOf plans the relocation of the local repository to the path of
+// its remote URL, or returns nil if it is already there or its remote URL is
+// not of a host.
+func relocationOf(repo *LocalRepository) (*relocation, error) {
+	vcs, _ := repo.VCS()
+	if vcs == nil || vcs.SetRemoteURL == nil {
+		return nil, nil
+	}
+	// worktrees and submodules follow their parent repositories
+	if linked, _, _ := isLinkedGitDir(repo.FullPath); linked {
+		return nil, nil
+	}
+	remote, err := vcs.RemoteURL(repo.FullPath)
+	if err != nil {
+		logger.Log("debug", fmt.Sprintf("%s: %s", repo.RelPath, err))
+		return nil, nil
+	}
+	if _, ok := forgeRemoteURL(remote); !ok {
+		return nil, nil
+	}
+	r, err := planRelocation(repo, "")
+	if errors.Is(err, errNothingToRelocate) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// findLocalRepository finds the only local repository which matches the
+// query exactly, as 'ghq list -e' does.
+func findLocalRepository(query string) (*LocalRepository, error) {
+	filterByQuery := repositoryFilter(query, true, false)
+	var (
+		mu    sync.Mutex
+		repos []*LocalRepository
+	)
+	if err := walkAllLocalRepositories(func(repo *LocalRepository) {
+		if !filterByQuery(repo) {
+			return
+		}
+		mu.Lock()
+		repos = append(repos, repo)
+		mu.Unlock()
+	}); err != nil {
+		return nil, err
+	}
+	switch len(repos) {
+	case 0:
+		return nil, fmt.Errorf("no repository found for %q", query)
+	case 1:
+		return repos[0], nil
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].FullPath < repos[j].FullPath })
+	paths := make([]string, len(repos))
+	for i, repo := range repos {
+		paths[i] = repo.FullPath
+	}
+	return nil, fmt.Errorf("more than one repository found for %q:\n  %s", query, strings.Join(paths, "\n  "))
+}
+
+// A relocation updates the remote URL of a local repository and moves it to
+// the path of the new URL under the root of the URL.
+type relocation struct {
+	repo         *LocalRepository
+	vcs          *VCSBackend
+	oldURL       string
+	newURL       string
+	destPath     string
+	hasWorktrees bool
+}
+
+// planRelocation checks that the repository can be relocated to the new
+// URL, or to the path of its current remote URL if newRemote is empty.
+func planRelocation(repo *LocalRepository, newRemote string) (*relocation, error) {
+	dir := repo.FullPath
+	vcs, _ := repo.VCS()
+	if vcs == nil || vcs.SetRemoteURL == nil {
+		return nil, fmt.Errorf("relocate supports only Git, Mercurial and Fossil repositories: %s", dir)
+	}
+	if vcs == GitBackend {
+		if linked, target, err := isLinkedGitDir(dir); err != nil {
+			return nil, fmt.Errorf("failed to check .git link status: %w", err)
+		} else if linked {
+			return nil, fmt.Errorf("directory %q has a .git file linking to %q; it is a worktree or submodule and cannot be relocated independently", dir, target)
+		}
+	}
+	oldURL, err := vcs.RemoteURL(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote URL: %w", err)
+	}
+	if newRemote == "" {
+		if _, ok := forgeRemoteURL(oldURL); !ok {
+			return nil, fmt.Errorf("the remote URL %q is not of a host; give the new URL", oldURL)
+		}
+		newRemote = oldURL
+	}
+	u, err := newURL(newRemote, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL %q: %w", newRemote, err)
+	}
+	r := &relocation{
+		repo:     repo,
+		vcs:      vcs,
+		oldURL:   oldURL,
+		newURL:   newRemote,
+		destPath: dir,
+	}
+	relPath, _ := relPathFromURL(u, strings.HasSuffix(dir, ".git"))
+	if relPath == repo.RelPath {
+		if r.newURL == r.oldURL {
+			return nil, errNothingToRelocate
+		}
+		return r, nil
+	}
+	if vcs == FossilBackend {
+		// the checkout would still use the repository file at the old path,
+		// which has been moved
+		return nil, fmt.Errorf("a fossil checkout records the absolute path of its repository file and cannot be moved: %s; clone %s again instead", dir, newRemote)
+	}
+	// The destination is in the root where 'ghq get' clones the URL, as
+	// LocalRepositoryFromURL chooses, without walking all the repositories
+	// for each relocation. It should not exist in any root.
+	root, err := rootOf(u)
+	if err != nil {
+		return nil, err
+	}
+	r.destPath = filepath.Join(root, relPath)
+	roots, err := localRepositoryRoots(true)
+	if err != nil {
+		return nil, err
+	}
+	for _, root := range roots {
+		p := filepath.Join(root, relPath)
+		if _, err := os.Stat(p); err == nil {
+			return nil, fmt.Errorf("destination directory %q already exists", p)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to check destination directory: %w", err)
+		}
+	}
+	if vcs == GitBackend {
+		if r.hasWorktrees, err = hasLinkedWorktrees(dir); err != nil {
+			return nil, fmt.Errorf("failed to check for linked worktrees: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// run prints what would be done on dry run, or relocates the repository
+// after confirmation unless yes. It returns false if not confirmed.
+func (r *relocation) run(ctx context.Context, w io.Writer, dry, yes bool) (bool, error) {
+	if dry {
+		r.printDryRun(w)
+		return true, nil
+	}
+	if !yes {
+		ok, err := confirm(r.confirmMessage())
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if err := r.relocate(ctx); err != nil {
+		return true, err
+	}
+	fmt.Fprintln(w, r.destPath)
+	return true, nil
+}
+
+func (r *relocation) printDryRun(w io.Writer) {
+	if r.newURL != r.oldURL {
+		fmt.Fprintf(w, "Would set the remote URL of %s from %s to %s\n", r.repo.FullPath, r.oldURL, r.newURL)
+	}
+	if r.destPath != r.repo.FullPath {
+		fmt.Fprintf(w, "Would move %s to %s\n", r.repo.FullPath, r.destPath)
+		if r.hasWorktrees {
+			fmt.Fprintf(w, "Would run 'git worktree repair' to update linked worktrees\n")
+		}
+	}
+}
+
+func (r *relocation) confirmMessage() string {
+	switch {
+	case r.destPath == r.repo.FullPath:
+		return fmt.Sprintf("Set the remote URL of %s to %s?", r.repo.FullPath, r.newURL)
+	case r.newURL == r.oldURL:
+		return fmt.Sprintf("Move %s to %s?", r.repo.FullPath, r.destPath)
+	}
+	return fmt.Sprintf("Set the remote URL to %s and move %s to %s?", r.newURL, r.repo.FullPath, r.destPath)
+}
+
+// relocate updates the remote URL and moves the repository.
+func (r *relocation) relocate(ctx context.Context) error {
+	src := r.repo.FullPath
+	// the repository is not updated or removed by another process while
+	// being moved, nor is the destination cloned
+	unlock, err := lockRepository(ctx, src, r.destPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("failed to check repository: %w", err)
+	}
+	if r.destPath != src {
+		// another process may have cloned it while we were waiting for the lock
+		if _, err := os.Stat(r.destPath); err == nil {
+			return fmt.Errorf("destination directory %q already exists", r.destPath)
+		}
+	}
+	if r.newURL != r.oldURL {
+		if err := r.vcs.SetRemoteURL(src, r.newURL); err != nil {
+			return err
+		}
+	}
+	if r.destPath == src {
+		return nil
+	}
+	if err := r.move(); err != nil {
+		if r.newURL != r.oldURL {
+			// the remote URL should agree with the path left unchanged
+			if rerr := r.vcs.SetRemoteURL(src, r.oldURL); rerr != nil {
+				logger.Log("warning", fmt.Sprintf("failed to restore the remote URL %s of %s: %s", r.oldURL, src, rerr))
+			}
+		}
+		return err
+	}
+	if r.hasWorktrees {
+		repairWorktrees(src, r.destPath)
+	}
+	invalidateRepositoryIndex()
+	if err := forgetVisits(ctx, src); err != nil {
+		logger.Log("warning", fmt.Sprintf("failed to forget the visits to %s: %s", src, err))
+	}
+	return nil
+}
+
+func (r *relocation) move() error {
+	if err := os.MkdirAll(filepath.Dir(r.destPath), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directories: %w", err)
+	}
+	if err := moveDir(r.repo.FullPath, r.destPath); err != nil {
+		return fmt.Errorf("failed to move repository: %w", err)
+	}
+	return nil
+}
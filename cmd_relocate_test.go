@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoRelocate(t *testing.T) {
+	setSyncRoot(t)
+	root, err := primaryLocalRepositoryRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := initGitRepo(t, filepath.Join(root, "github.com", "alice", "old"), "https://github.com/alice/old.git")
+	wt := filepath.Join(t.TempDir(), "wt")
+	addWorktree(t, src, wt, "feature")
+	dest := filepath.Join(root, "github.com", "bob", "new")
+
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		var runErr error
+		out, _, err := capture(func() {
+			runErr = newApp().Run(context.Background(), append([]string{"", "relocate"}, args...))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out, runErr
+	}
+
+	out, err := run("--dry-run", "github.com/alice/old", "https://github.com/bob/new.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"Would set the remote URL of " + src + " from https://github.com/alice/old.git to https://github.com/bob/new.git\n",
+		"Would move " + src + " to " + dest + "\n",
+		"Would run 'git worktree repair'",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("out should contain %q, but: %q", s, out)
+		}
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("nothing should be moved on dry run: %s", err)
+	}
+
+	if _, err := run("-y", "github.com/nobody/none", "https://github.com/bob/new.git"); err == nil || !strings.Contains(err.Error(), "no repository found") {
+		t.Errorf("error should be no repository found, but: %v", err)
+	}
+
+	out, err = run("-y", "github.com/alice/old", "https://github.com/bob/new.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != dest {
+		t.Errorf("got: %q, expect: %q", out, dest)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("the old directory should not exist: %v", err)
+	}
+	if got := git(t, dest, "remote", "get-url", "origin"); got != "https://github.com/bob/new.git" {
+		t.Errorf("remote URL: %q", got)
+	}
+	// the worktree still works after the move
+	git(t, wt, "status")
+
+	if _, err := run("-y", "github.com/bob/new", "git@github.com:bob/new.git"); err != nil {
+		t.Fatal(err)
+	}
+	if got := git(t, dest, "remote", "get-url", "origin"); got != "git@github.com:bob/new.git" {
+		t.Errorf("the URL should be updated in place, but: %q", got)
+	}
+}
+
+func TestDoRelocate_mercurial(t *testing.T) {
+	if _, err := exec.LookPath("hg"); err != nil {
+		t.Skip("hg command is not available")
+	}
+	setSyncRoot(t)
+	root, err := primaryLocalRepositoryRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(root, "example.com", "alice", "old")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := MercurialBackend.Init(context.Background(), src); err != nil {
+		t.Fatal(err)
+	}
+	if err := MercurialBackend.SetRemoteURL(src, "https://example.com/alice/old"); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(root, "example.com", "bob", "new")
+
+	out, _, err := capture(func() {
+		if err := newApp().Run(context.Background(),
+			[]string{"", "relocate", "-y", "example.com/alice/old", "https://example.com/bob/new"}); err != nil {
+			t.Errorf("error should be nil, but: %s", err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != dest {
+		t.Errorf("got: %q, expect: %q", out, dest)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("the old directory should not exist: %v", err)
+	}
+	if got, err := MercurialBackend.RemoteURL(dest); err != nil || got != "https://example.com/bob/new" {
+		t.Errorf("remote URL: %q, %v", got, err)
+	}
+}
+
+func TestDoRelocate_fossil(t *testing.T) {
+	if _, err := exec.LookPath("fossil"); err != nil {
+		t.Skip("fossil command is not available")
+	}
+	setSyncRoot(t)
+	root, err := primaryLocalRepositoryRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(root, "example.com", "alice", "old")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := capture(func() {
+		if err := FossilBackend.Init(context.Background(), src); err != nil {
+			t.Fatal(err)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := FossilBackend.SetRemoteURL(src, "https://example.com/alice/old"); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) error {
+		t.Helper()
+		var runErr error
+		if _, _, err := capture(func() {
+			runErr = newApp().Run(context.Background(), append([]string{"", "relocate", "-y"}, args...))
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return runErr
+	}
+
+	if err := run("example.com/alice/old", "https://example.com/bob/new"); err == nil || !strings.Contains(err.Error(), "cannot be moved") {
+		t.Errorf("a fossil checkout should not be moved, but: %v", err)
+	}
+	if got, err := FossilBackend.RemoteURL(src); err != nil || got != "https://example.com/alice/old" {
+		t.Errorf("the remote URL should be kept, but: %q, %v", got, err)
+	}
+
+	// the remote URL is still updated in place
+	if err := run("example.com/alice/old", "http://example.com/alice/old"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := FossilBackend.RemoteURL(src); err != nil || got != "http://example.com/alice/old" {
+		t.Errorf("remote URL: %q, %v", got, err)
+	}
+}
+
+func TestDoRelocate_root(t *testing.T) {
+	setSyncRoot(t)
+	primary, secondary := newTempDir(t), newTempDir(t)
+	setEnv(t, envGhqRoot, primary+string(filepath.ListSeparator)+secondary)
+	src := initGitRepo(t, filepath.Join(secondary, "github.com", "alice", "old"), "https://github.com/alice/old.git")
+	initGitRepo(t, filepath.Join(secondary, "github.com", "alice", "taken"), "https://github.com/alice/taken.git")
+
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		var runErr error
+		out, _, err := capture(func() {
+			runErr = newApp().Run(context.Background(), append([]string{"", "relocate", "-y"}, args...))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out, runErr
+	}
+
+	// the destination in another root is taken
+	if _, err := run("github.com/alice/old", "https://github.com/alice/taken.git"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("error should be already exists, but: %v", err)
+	}
+
+	// moved to the root where 'ghq get' clones the new URL
+	out, err := run("github.com/alice/old", "https://github.com/bob/new.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dest := filepath.Join(primary, "github.com", "bob", "new"); strings.TrimSpace(out) != dest {
+		t.Errorf("got: %q, expect: %q", out, dest)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("the old directory should not exist: %v", err)
+	}
+}
+
+func TestDoRelocate_moveFailure(t *testing.T) {
+	setSyncRoot(t)
+	root, err := primaryLocalRepositoryRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := initGitRepo(t, filepath.Join(root, "github.com", "alice", "old"), "https://github.com/alice/old.git")
+	repo, err := findLocalRepository("github.com/alice/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := planRelocation(repo, "https://github.com/bob/new.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the parent of the destination cannot be created after planned
+	if err := os.WriteFile(filepath.Join(root, "github.com", "bob"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.relocate(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to create parent directories") {
+		t.Errorf("error should be returned, but: %v", err)
+	}
+	if got := git(t, src, "remote", "get-url", "origin"); got != "https://github.com/alice/old.git" {
+		t.Errorf("the remote URL should be restored, but: %q", got)
+	}
+}
+
+func TestDoRelocate_all(t *testing.T) {
+	setSyncRoot(t)
+	root, err := primaryLocalRepositoryRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the remote URLs have been changed by hand
+	moved := initGitRepo(t, filepath.Join(root, "github.com", "alice", "old"), "https://github.com/bob/new.git")
+	kept := initGitRepo(t, filepath.Join(root, "github.com", "alice", "kept"), "git@github.com:alice/kept.git")
+	local := initGitRepo(t, filepath.Join(root, "example.com", "local", "repo"), filepath.Join(t.TempDir(), "repo.git"))
+	dest := filepath.Join(root, "github.com", "bob", "new")
+
+	out, _, err := capture(func() {
+		if err := newApp().Run(context.Background(), []string{"", "relocate", "--all", "--dry-run"}); err != nil {
+			t.Errorf("error should be nil, but: %s", err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "Would move " + moved + " to " + dest + "\n"; out != expect {
+		t.Errorf("got: %q, expect: %q", out, expect)
+	}
+
+	out, _, err = capture(func() {
+		if err := newApp().Run(context.Background(), []string{"", "relocate", "--all", "-y"}); err != nil {
+			t.Errorf("error should be nil, but: %s", err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != dest {
+		t.Errorf("got: %q, expect: %q", out, dest)
+	}
+	for _, p := range []string{dest, kept, local} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("%s should exist: %s", p, err)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -42,70 +43,123 @@ func doRm(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("directory %q does not exist", p)
 	}
 
+	r, err := planRemoval(localRepo, u.String())
+	if err != nil {
+		return err
+	}
+
+	warnNestedRepositories(p, localRepo.RootPath, "removed")
+
+	// Dry-run
+	if dry {
+		r.printDryRun(w)
+		return nil
+	}
+
+	ok, err = confirm(r.confirmMessage())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("aborted")
+	}
+
+	if cmd.Bool("no-wait") {
+		noWaitLock = true
+		defer func() { noWaitLock = false }()
+	}
+	if err := r.remove(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Removed %s\n", p)
+	return nil
+}
+
+// A removal is the plan to remove a local repository, which may be a
+// linked worktree or have linked worktrees.
+type removal struct {
+	repo *LocalRepository
+	url  string
+
+	isWorktree    bool
+	gitdirTarget  string
+	worktreePaths []string
+}
+
+func planRemoval(localRepo *LocalRepository, url string) (*removal, error) {
+	r := &removal{repo: localRepo, url: url}
+	p := localRepo.FullPath
+
 	// Scenario A: Is this path itself a linked worktree?
-	isWorktree := false
-	var gitdirTarget string
 	if linked, target, linkErr := isLinkedGitDir(p); linkErr != nil {
-		return fmt.Errorf("failed to check worktree status: %w", linkErr)
+		return nil, fmt.Errorf("failed to check worktree status: %w", linkErr)
 	} else if linked && isWorktreeGitDir(target) {
-		isWorktree = true
-		gitdirTarget = target
+		r.isWorktree = true
+		r.gitdirTarget = target
 	}
 
 	// Scenario B: Does this repo have linked worktrees?
-	var worktreePaths []string
-	if !isWorktree {
+	if !r.isWorktree {
 		if hasWt, wtErr := hasLinkedWorktrees(p); wtErr != nil {
-			return fmt.Errorf("failed to check for linked worktrees: %w", wtErr)
+			return nil, fmt.Errorf("failed to check for linked worktrees: %w", wtErr)
 		} else if hasWt {
-			worktreePaths, err = listLinkedWorktreePaths(p)
+			paths, err := listLinkedWorktreePaths(p)
 			if err != nil {
-				return fmt.Errorf("failed to list linked worktrees: %w", err)
+				return nil, fmt.Errorf("failed to list linked worktrees: %w", err)
 			}
+			r.worktreePaths = paths
 		}
 	}
+	return r, nil
+}
 
-	// Dry-run
-	if dry {
-		if isWorktree {
-			fmt.Fprintf(w, "Would remove worktree %s (linked to %s)\n", p, gitdirTarget)
-		} else if len(worktreePaths) > 0 {
-			fmt.Fprintf(w, "Would remove %s and its %d linked worktree(s):\n", p, len(worktreePaths))
-			for _, wt := range worktreePaths {
-				fmt.Fprintf(w, "  %s\n", wt)
-			}
-		} else {
-			fmt.Fprintf(w, "Would remove %s\n", p)
+func (r *removal) printDryRun(w io.Writer) {
+	p := r.repo.FullPath
+	if r.isWorktree {
+		fmt.Fprintf(w, "Would remove worktree %s (linked to %s)\n", p, r.gitdirTarget)
+	} else if len(r.worktreePaths) > 0 {
+		fmt.Fprintf(w, "Would remove %s and its %d linked worktree(s):\n", p, len(r.worktreePaths))
+		for _, wt := range r.worktreePaths {
+			fmt.Fprintf(w, "  %s\n", wt)
 		}
-		return nil
+	} else {
+		fmt.Fprintf(w, "Would remove %s\n", p)
 	}
+}
 
-	// Confirmation
-	var confirmMsg string
-	if isWorktree {
-		confirmMsg = fmt.Sprintf("Remove worktree %s?", p)
-	} else if len(worktreePaths) > 0 {
-		confirmMsg = fmt.Sprintf("Remove %s and its %d linked worktree(s)?\n  %s",
-			p, len(worktreePaths), strings.Join(worktreePaths, "\n  "))
-	} else {
-		confirmMsg = fmt.Sprintf("Remove %s?", p)
+func (r *removal) confirmMessage() string {
+	p := r.repo.FullPath
+	if r.isWorktree {
+		return fmt.Sprintf("Remove worktree %s?", p)
+	} else if len(r.worktreePaths) > 0 {
+		return fmt.Sprintf("Remove %s and its %d linked worktree(s)?\n  %s",
+			p, len(r.worktreePaths), strings.Join(r.worktreePaths, "\n  "))
 	}
+	return fmt.Sprintf("Remove %s?", p)
+}
 
-	ok, err = confirm(confirmMsg)
+// remove removes the repository, running the hooks.
+func (r *removal) remove(ctx context.Context) error {
+	p := r.repo.FullPath
+	unlock, err := lockRepository(ctx, p)
 	if err != nil {
 		return err
 	}
-	if !ok {
-		return fmt.Errorf("aborted")
+	defer unlock()
+
+	vcsBackend, _ := r.repo.VCS()
+	if err := runHooks(ctx, hookEvent{hook: hookPreRm, action: "rm",
+		path: p, url: r.url, vcs: vcsName(vcsBackend)}, false); err != nil {
+		return fmt.Errorf("removal vetoed: %w", err)
 	}
 
 	// Removal
-	if isWorktree {
+	if r.isWorktree {
 		// Use git worktree remove to properly unregister from parent repo.
 		// Resolve the main repo directory so we don't run git from inside
 		// the directory being deleted.
 		removed := false
-		if mainRepoDir, dirErr := resolveMainRepoDir(gitdirTarget); dirErr == nil {
+		if mainRepoDir, dirErr := resolveMainRepoDir(r.gitdirTarget); dirErr == nil {
 			gitCmd := exec.Command("git", "worktree", "remove", "--force", p)
 			gitCmd.Dir = mainRepoDir
 			if out, gitErr := gitCmd.CombinedOutput(); gitErr != nil {
@@ -122,13 +176,13 @@ func doRm(ctx context.Context, cmd *cli.Command) error {
 				return err
 			}
 			// Best-effort cleanup of dangling .git/worktrees/<name> entry
-			if gitdirTarget != "" {
-				os.RemoveAll(gitdirTarget)
+			if r.gitdirTarget != "" {
+				os.RemoveAll(r.gitdirTarget)
 			}
 		}
 	} else {
 		// Prune linked worktrees before removing main repo
-		for _, wt := range worktreePaths {
+		for _, wt := range r.worktreePaths {
 			if _, statErr := os.Stat(wt); os.IsNotExist(statErr) {
 				continue // already gone
 			}
@@ -143,7 +197,10 @@ func doRm(ctx context.Context, cmd *cli.Command) error {
 		}
 	}
 
-	fmt.Fprintf(w, "Removed %s\n", p)
+	invalidateRepositoryIndex()
+	if err := forgetVisits(ctx, p); err != nil {
+		logger.Log("warning", fmt.Sprintf("failed to forget the visits to %s: %s", p, err))
+	}
 	return nil
 }
 
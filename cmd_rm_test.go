@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"os/exec"
@@ -11,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/x-motemen/ghq/cmdutil"
+	"github.com/x-motemen/ghq/logger"
 )
 
 func TestRmCommand(t *testing.T) {
@@ -373,3 +375,47 @@ func TestRmWorktree(t *testing.T) {
 		}
 	})
 }
+
+func TestRmNested(t *testing.T) {
+	setSyncRoot(t)
+	root, err := primaryLocalRepositoryRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := initGitRepo(t, filepath.Join(root, "github.com", "alice", "app"), "https://github.com/alice/app.git")
+	vendored := initGitRepo(t, filepath.Join(app, "vendor", "github.com", "carol", "vendored"), "https://github.com/carol/vendored.git")
+
+	buf := &bytes.Buffer{}
+	logger.SetOutput(buf)
+	defer func() { logger.SetOutput(os.Stderr) }()
+
+	testCases := []struct {
+		name   string
+		target string
+		expect string
+	}{{
+		name:   "parent",
+		target: "github.com/alice/app",
+		expect: vendored + " is nested in " + app + " and will be removed along with it",
+	}, {
+		name:   "nested",
+		target: "github.com/alice/app/vendor/github.com/carol/vendored",
+		expect: vendored + " is nested in the working tree of " + app,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			_, _, err := capture(func() {
+				if err := newApp().Run(context.Background(), []string{"", "rm", "--dry-run", tc.target}); err != nil {
+					t.Errorf("error should be nil, but: %s", err)
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), tc.expect) {
+				t.Errorf("warnings should contain %q, but: %q", tc.expect, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// shellInitShells are the shells supported by 'ghq shell-init'.
+var shellInitShells = []string{"bash", "zsh", "fish"}
+
+// envLookFile is set by the shell integration to the file which ghq writes
+// the directory to look into, instead of starting a shell in it, so that
+// the shell function changes the directory of the current shell.
+const envLookFile = "GHQ_LOOK_FILE"
+
+// writeLookFile writes the directory to look into and the value of
+// GHQ_LOOK to the file of envLookFile. It reports whether the file is set.
+func writeLookFile(dir, look string) (bool, error) {
+	f := os.Getenv(envLookFile)
+	if f == "" {
+		return false, nil
+	}
+	return true, os.WriteFile(f, []byte(dir+"\n"+look+"\n"), 0600)
+}
+
+// posixShellInit wraps ghq for bash and zsh.
+const posixShellInit = `ghq() {
+  local __ghq_look __ghq_status __ghq_dir __ghq_rel
+  __ghq_look="$(mktemp "${TMPDIR:-/tmp}/ghq-look.XXXXXX")" || return
+  GHQ_LOOK_FILE="$__ghq_look" command ghq "$@"
+  __ghq_status=$?
+  if [ -s "$__ghq_look" ]; then
+    { IFS= read -r __ghq_dir; IFS= read -r __ghq_rel; } < "$__ghq_look"
+    cd -- "$__ghq_dir" && export GHQ_LOOK="$__ghq_rel"
+  fi
+  command rm -f -- "$__ghq_look"
+  return "$__ghq_status"
+}
+
+`
+
+const bashShellBind = `__ghq_jump() {
+  ghq look
+}
+
+if [[ $- = *i* ]]; then
+  bind -x '"\C-]": __ghq_jump'
+fi
+
+`
+
+const zshShellBind = `__ghq_jump() {
+  ghq look </dev/tty
+  zle reset-prompt
+}
+
+if [[ -o interactive ]]; then
+  zle -N __ghq_jump
+  bindkey '^]' __ghq_jump
+fi
+
+`
+
+const fishShellInit = `function ghq
+    set -l look (mktemp)
+    or return
+    env GHQ_LOOK_FILE=$look ghq $argv
+    set -l ghq_status $status
+    if test -s $look
+        set -l lines (cat $look)
+        cd $lines[1]
+        and set -gx GHQ_LOOK $lines[2]
+    end
+    command rm -f $look
+    return $ghq_status
+end
+
+`
+
+const fishShellBind = `function __ghq_jump
+    ghq look </dev/tty
+    commandline -f repaint
+end
+
+if status is-interactive
+    bind \x1d __ghq_jump
+end
+
+`
+
+func doShellInit(_ context.Context, cmd *cli.Command) error {
+	var (
+		w              = cmd.Root().Writer
+		shell          = cmd.Args().First()
+		completionOnly = cmd.Bool("completion")
+		noBind         = cmd.Bool("no-bind")
+	)
+
+	root := newCompletionCommand(cmd.Root(), nil)
+	b := &strings.Builder{}
+	switch shell {
+	case "bash":
+		if completionOnly {
+			b.WriteString(completionHeader(shell) + "\n")
+		} else {
+			b.WriteString(posixShellInit)
+			if !noBind {
+				b.WriteString(bashShellBind)
+			}
+		}
+		if err := writeBashCompletion(b, root); err != nil {
+			return err
+		}
+	case "zsh":
+		if !completionOnly {
+			b.WriteString(posixShellInit)
+			if !noBind {
+				b.WriteString(zshShellBind)
+			}
+		}
+		if err := writeZshCompletion(b, root, completionOnly); err != nil {
+			return err
+		}
+	case "fish":
+		if completionOnly {
+			b.WriteString(completionHeader(shell) + "\n")
+		} else {
+			b.WriteString(fishShellInit)
+			if !noBind {
+				b.WriteString(fishShellBind)
+			}
+		}
+		if err := writeFishCompletion(b, root); err != nil {
+			return err
+		}
+	case "":
+		return fmt.Errorf("shell is required: %s", strings.Join(shellInitShells, ", "))
+	default:
+		return fmt.Errorf("unsupported shell %q: %s are supported", shell, strings.Join(shellInitShells, ", "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoShellInit(t *testing.T) {
+	testCases := []struct {
+		shell     string
+		args      []string
+		contains  []string
+		excludes  []string
+		expectErr bool
+	}{{
+		shell:    "bash",
+		contains: []string{"ghq() {", "GHQ_LOOK_FILE=", `bind -x '"\C-]": __ghq_jump'`, "complete -F _ghq ghq"},
+	}, {
+		shell:    "bash",
+		args:     []string{"--no-bind"},
+		contains: []string{"ghq() {", "complete -F _ghq ghq"},
+		excludes: []string{"bind -x"},
+	}, {
+		shell:    "zsh",
+		contains: []string{"ghq() {", "bindkey '^]' __ghq_jump", "compdef _ghq ghq"},
+		excludes: []string{"#compdef", `_ghq "$@"`},
+	}, {
+		shell:    "fish",
+		contains: []string{"function ghq", "bind \\x1d __ghq_jump", "complete -c ghq"},
+	}, {
+		shell:     "",
+		expectErr: true,
+	}, {
+		shell:     "tcsh",
+		expectErr: true,
+	}}
+	for _, tc := range testCases {
+		t.Run(strings.Join(append([]string{tc.shell}, tc.args...), " "), func(t *testing.T) {
+			var runErr error
+			out, _, err := capture(func() {
+				args := append(append([]string{"", "shell-init"}, tc.args...), tc.shell)
+				runErr = newApp().Run(context.Background(), args)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotErr := runErr != nil; gotErr != tc.expectErr {
+				t.Fatalf("error = %v, expectErr = %v", runErr, tc.expectErr)
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("output should contain %q", s)
+				}
+			}
+			for _, s := range tc.excludes {
+				if strings.Contains(out, s) {
+					t.Errorf("output should not contain %q", s)
+				}
+			}
+			if tc.expectErr {
+				return
+			}
+			// check the syntax if the shell is available
+			if _, err := exec.LookPath(tc.shell); err != nil {
+				return
+			}
+			script := filepath.Join(t.TempDir(), "init")
+			if err := os.WriteFile(script, []byte(out), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if b, err := exec.Command(tc.shell, "-n", script).CombinedOutput(); err != nil {
+				t.Errorf("syntax error: %s\n%s", err, b)
+			}
+		})
+	}
+}
+
+func TestLookByLocalRepository_lookFile(t *testing.T) {
+	tmpd := newTempDir(t)
+	lookFile := filepath.Join(t.TempDir(), "look")
+	setEnv(t, envLookFile, lookFile)
+
+	repo := &LocalRepository{
+		FullPath: filepath.Join(tmpd, "github.com", "motemen", "ghq"),
+		RelPath:  filepath.Join("github.com", "motemen", "ghq"),
+	}
+	if err := lookByLocalRepository(repo, ""); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(lookFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := repo.FullPath + "\ngithub.com/motemen/ghq\n"
+	if string(b) != expect {
+		t.Errorf("got: %q, expect: %q", b, expect)
+	}
+}
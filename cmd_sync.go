@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v3"
+	"github.com/x-motemen/ghq/logger"
+)
+
+// The reasons why a local repository is out of the set of 'ghq sync'.
+const (
+	strayArchived = "archived" // archived upstream
+	strayDeleted  = "deleted"  // not found on the forge any longer
+	strayUnlisted = "unlisted" // not in the set
+)
+
+// A syncSet is the set of the repositories which should be cloned locally.
+type syncSet struct {
+	targets []string
+	// paths are the relative paths of the targets, e.g. "github.com/my-org/repo"
+	paths map[string]bool
+	// scopes are the directories, e.g. "github.com/my-org", in which the
+	// local repositories out of the set are reported
+	scopes []string
+	// strays are the reasons of the repositories listed by the forge but
+	// out of the set, keyed by their relative paths
+	strays map[string]string
+	// forge is the forge by which the scope is listed, if any, so that the
+	// repositories missing from it are looked up
+	forge forge
+}
+
+// A stray is a local repository out of the set.
+type stray struct {
+	repo   *LocalRepository
+	reason string
+}
+
+func (s stray) String() string {
+	switch s.reason {
+	case strayArchived:
+		return fmt.Sprintf("%s is archived upstream", s.repo.RelPath)
+	case strayDeleted:
+		return fmt.Sprintf("%s is not found upstream", s.repo.RelPath)
+	}
+	return fmt.Sprintf("%s is not in the set", s.repo.RelPath)
+}
+
+func (set *syncSet) add(target string) error {
+	p, err := targetRelPath(target)
+	if err != nil {
+		return err
+	}
+	if set.paths[p] {
+		return nil
+	}
+	set.targets = append(set.targets, target)
+	set.paths[p] = true
+	return nil
+}
+
+// targetRelPath returns the relative path of the local repository of the
+// target of 'ghq get', with slashes.
+func targetRelPath(target string) (string, error) {
+	u, err := newURL(target, false, false)
+	if err != nil {
+		return "", fmt.Errorf("could not parse URL %q: %w", target, err)
+	}
+	u, _ = parseBrowserURL(u)
+	if pos := strings.LastIndexByte(u.Path, '@'); pos >= 0 {
+		u.Path = u.Path[:pos]
+	}
+	repo, err := LocalRepositoryFromURL(u, false)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(repo.RelPath), nil
+}
+
+// readSyncManifest reads the set from the manifest, which lists the targets
+// of 'ghq get' one per line. Empty lines and lines starting with "#" are
+// ignored.
+func readSyncManifest(r io.Reader) (*syncSet, error) {
+	set := &syncSet{paths: map[string]bool{}}
+	scr := bufio.NewScanner(r)
+	for scr.Scan() {
+		line := strings.TrimSpace(scr.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := set.add(line); err != nil {
+			return nil, err
+		}
+	}
+	if err := scr.Err(); err != nil {
+		return nil, err
+	}
+	for p := range set.paths {
+		if dir := path.Dir(p); !slices.Contains(set.scopes, dir) {
+			set.scopes = append(set.scopes, dir)
+		}
+	}
+	return set, nil
+}
+
+// listSyncSet lists the set of the repositories of the owner on the forge.
+func listSyncSet(ctx context.Context, owner string, isUser, ssh bool, filter *forgeFilter) (*syncSet, error) {
+	host, name, err := forgeOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+	set := &syncSet{
+		paths:  map[string]bool{},
+		scopes: []string{host + "/" + name},
+		strays: map[string]string{},
+	}
+	var addErr error
+	set.forge, err = listForge(ctx, owner, isUser, filter, func(repo forgeRepository, ok bool) {
+		if addErr != nil {
+			return
+		}
+		if ok {
+			addErr = set.add(repo.target(ssh))
+			return
+		}
+		p, err := targetRelPath(repo.target(ssh))
+		if err != nil {
+			addErr = err
+			return
+		}
+		set.strays[p] = strayUnlisted
+		if repo.Archived {
+			set.strays[p] = strayArchived
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set, addErr
+}
+
+// strayOf returns the reason why the local repository is out of the set, or
+// "" if it is in the set or out of the scopes. A repository missing from
+// the listing of the forge is looked up, and deleted only if it is not
+// found with a credential, since the private ones are not visible without.
+func (set *syncSet) strayOf(ctx context.Context, repo *LocalRepository) (string, error) {
+	p := filepath.ToSlash(repo.RelPath)
+	if set.paths[p] || !slices.ContainsFunc(set.scopes, func(scope string) bool {
+		return strings.HasPrefix(p, scope+"/")
+	}) {
+		return "", nil
+	}
+	if reason, ok := set.strays[p]; ok {
+		return reason, nil
+	}
+	if set.forge == nil || !set.forge.authenticated(ctx) {
+		return strayUnlisted, nil
+	}
+	_, name, _ := strings.Cut(p, "/")
+	if _, err := set.forge.repository(ctx, name); err != nil {
+		if isForgeNotFound(err) {
+			return strayDeleted, nil
+		}
+		return "", fmt.Errorf("failed to look up %s: %w", p, err)
+	}
+	return strayUnlisted, nil
+}
+
+func doSync(ctx context.Context, cmd *cli.Command) error {
+	var (
+		from     = cmd.String("from")
+		dry      = cmd.Bool("dry-run")
+		prune    = cmd.Bool("prune")
+		yes      = cmd.Bool("y")
+		parallel = cmd.Bool("parallel")
+		w        = cmd.Root().Writer
+	)
+	g := &getter{
+		update:    true,
+		ssh:       cmd.Bool("p"),
+		silent:    parallel,
+		recursive: true,
+		retry:     newRetryPolicy(-1),
+	}
+	if from == "" {
+		return fmt.Errorf("--from is required")
+	}
+	if cmd.Bool("no-wait") {
+		noWaitLock = true
+		defer func() { noWaitLock = false }()
+	}
+
+	var (
+		set *syncSet
+		err error
+	)
+	if fi, statErr := os.Stat(from); from == "-" || statErr == nil && !fi.IsDir() {
+		set, err = readSyncManifestFile(from)
+	} else {
+		set, err = listSyncSet(ctx, from, cmd.Bool("user"), g.ssh, &forgeFilter{forks: cmd.Bool("forks")})
+	}
+	if err != nil {
+		return err
+	}
+	if prune && set.forge != nil && !set.forge.authenticated(ctx) {
+		return fmt.Errorf("--prune needs a token for %s, without which the private repositories are not listed", from)
+	}
+
+	var (
+		mu    sync.Mutex
+		local = map[string]bool{}
+		repos []*LocalRepository
+	)
+	if err := walkAllLocalRepositories(func(repo *LocalRepository) {
+		mu.Lock()
+		defer mu.Unlock()
+		local[filepath.ToSlash(repo.RelPath)] = true
+		repos = append(repos, repo)
+	}); err != nil {
+		return err
+	}
+	slices.SortFunc(repos, func(a, b *LocalRepository) int { return strings.Compare(a.RelPath, b.RelPath) })
+	var strays []stray
+	for _, repo := range repos {
+		reason, err := set.strayOf(ctx, repo)
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			strays = append(strays, stray{repo: repo, reason: reason})
+		}
+	}
+
+	var results []getResult
+	if dry {
+		for _, target := range set.targets {
+			verb := "clone"
+			if p, _ := targetRelPath(target); local[p] {
+				verb = "update"
+			}
+			fmt.Fprintf(w, "Would %s %s\n", verb, target)
+		}
+	} else {
+		if parallel {
+			defer g.showProgress()()
+		}
+		results, err = g.getAll(ctx, &sliceScanner{slice: set.targets}, parallel, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, s := range strays {
+		logger.Emit(logger.Event{Prefix: s.reason, Message: s.String(), Path: s.repo.FullPath})
+		if !prune {
+			continue
+		}
+		r, err := planRemoval(s.repo, "https://"+filepath.ToSlash(s.repo.RelPath))
+		if err != nil {
+			return err
+		}
+		if dry {
+			r.printDryRun(w)
+			continue
+		}
+		if !yes {
+			ok, err := confirm(r.confirmMessage())
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		if err := r.remove(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Removed %s\n", s.repo.FullPath)
+	}
+
+	var failed []getResult
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) > 0 {
+		return summarizeFailures(failed, 0, len(results))
+	}
+	return nil
+}
+
+func readSyncManifestFile(name string) (*syncSet, error) {
+	if name == "-" {
+		return readSyncManifest(os.Stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readSyncManifest(f)
+}
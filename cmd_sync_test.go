@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Songmu/gitconfig"
+	"github.com/x-motemen/ghq/logger"
+)
+
+// setSyncRoot sets a temporary root, which the local paths of the targets
+// are resolved under.
+func setSyncRoot(t *testing.T) {
+	t.Helper()
+	origHome, origRoots := _home, _localRepositoryRoots
+	t.Cleanup(func() { _home, _localRepositoryRoots = origHome, origRoots })
+	_home = ""
+	homeOnce = &sync.Once{}
+	setEnv(t, envGhqRoot, newTempDir(t))
+	_localRepositoryRoots = nil
+	localRepoOnce = &sync.Once{}
+}
+
+func TestReadSyncManifest(t *testing.T) {
+	setSyncRoot(t)
+	set, err := readSyncManifest(strings.NewReader(`# repositories of my-org
+github.com/my-org/a
+https://github.com/my-org/b.git
+
+git@github.com:my-org/a.git
+gitlab.com/group/sub/c
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"github.com/my-org/a", "https://github.com/my-org/b.git", "gitlab.com/group/sub/c"}
+	if !reflect.DeepEqual(set.targets, expect) {
+		t.Errorf("got: %q, expect: %q", set.targets, expect)
+	}
+
+	testCases := []struct {
+		path, expect string
+	}{
+		{"github.com/my-org/a", ""},
+		{"github.com/my-org/b", ""},
+		{"github.com/my-org/old", strayUnlisted},
+		{"github.com/other/x", ""},
+		{"gitlab.com/group/sub/d", strayUnlisted},
+		{"gitlab.com/group/e", ""},
+	}
+	for _, tc := range testCases {
+		repo := &LocalRepository{RelPath: filepath.FromSlash(tc.path)}
+		got, err := set.strayOf(context.Background(), repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.expect {
+			t.Errorf("strayOf(%q) = %q, expect: %q", tc.path, got, tc.expect)
+		}
+	}
+}
+
+func TestListSyncSet(t *testing.T) {
+	setSyncRoot(t)
+	newFakeForge(t, "github", map[string]string{
+		"/orgs/my-org/repos?type=all&per_page=100": `
+[{"full_name": "my-org/a", "clone_url": "https://git.example.com/my-org/a.git"},
+ {"full_name": "my-org/old", "clone_url": "https://git.example.com/my-org/old.git", "archived": true},
+ {"full_name": "my-org/fork", "clone_url": "https://git.example.com/my-org/fork.git", "fork": true}]`,
+		"/repos/my-org/internal": `{"full_name": "my-org/internal", "clone_url": "https://git.example.com/my-org/internal.git"}`,
+	})
+	set, err := listSyncSet(context.Background(), "git.example.com/my-org", false, false, &forgeFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"https://git.example.com/my-org/a.git"}
+	if !reflect.DeepEqual(set.targets, expect) {
+		t.Errorf("got: %q, expect: %q", set.targets, expect)
+	}
+
+	testCases := []struct {
+		path, expect string
+	}{
+		{"git.example.com/my-org/a", ""},
+		{"git.example.com/my-org/old", strayArchived},
+		{"git.example.com/my-org/fork", strayUnlisted},
+		{"git.example.com/my-org/gone", strayDeleted},
+		{"git.example.com/my-org/internal", strayUnlisted},
+		{"git.example.com/other/a", ""},
+	}
+	for _, tc := range testCases {
+		repo := &LocalRepository{RelPath: filepath.FromSlash(tc.path)}
+		got, err := set.strayOf(context.Background(), repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.expect {
+			t.Errorf("strayOf(%q) = %q, expect: %q", tc.path, got, tc.expect)
+		}
+	}
+}
+
+func TestSyncSet_strayOf_withoutToken(t *testing.T) {
+	setSyncRoot(t)
+	set := &syncSet{
+		paths:  map[string]bool{"git.example.com/my-org/a": true},
+		scopes: []string{"git.example.com/my-org"},
+		strays: map[string]string{},
+		// without a token, the private repositories are not visible
+		forge: &githubForge{&forgeClient{host: "git.example.com", kind: forgeGitHub, credential: &forgeCredential{}}},
+	}
+	repo := &LocalRepository{RelPath: filepath.FromSlash("git.example.com/my-org/private")}
+	got, err := set.strayOf(context.Background(), repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != strayUnlisted {
+		t.Errorf("strayOf(%q) = %q, expect: %q", repo.RelPath, got, strayUnlisted)
+	}
+}
+
+func TestDoSync_pruneWithoutToken(t *testing.T) {
+	setSyncRoot(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `[{"full_name": "my-org/a", "clone_url": "https://git.example.com/my-org/a.git"}]`)
+	}))
+	defer srv.Close()
+	defer gitconfig.WithConfig(t, fmt.Sprintf(`
+[ghq "https://git.example.com/"]
+	forge = github
+	forgeApi = %s/
+[credential]
+	helper =
+`, srv.URL))()
+	setEnv(t, "GH_ENTERPRISE_TOKEN", "")
+	setEnv(t, "GITHUB_ENTERPRISE_TOKEN", "")
+
+	var err error
+	if _, _, cerr := capture(func() {
+		err = newApp().Run(context.Background(),
+			[]string{"", "sync", "--from", "git.example.com/my-org", "--prune", "--dry-run"})
+	}); cerr != nil {
+		t.Fatal(cerr)
+	}
+	if err == nil || !strings.Contains(err.Error(), "--prune needs a token") {
+		t.Errorf("--prune should be refused without a token, but: %v", err)
+	}
+}
+
+func TestDoSync(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetOutput(buf)
+	defer func() { logger.SetOutput(os.Stderr) }()
+
+	withFakeGitBackend(t, func(t *testing.T, tmproot string, cloneArgs *_cloneArgs, updateArgs *_updateArgs) {
+		for _, r := range []string{"github.com/my-org/a", "github.com/my-org/old", "github.com/other/x"} {
+			if err := os.MkdirAll(filepath.Join(tmproot, r, ".git"), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		manifest := filepath.Join(t.TempDir(), "repositories.txt")
+		if err := os.WriteFile(manifest, []byte("github.com/my-org/a\ngithub.com/my-org/b\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		run := func(args ...string) string {
+			t.Helper()
+			buf.Reset()
+			out, _, err := capture(func() {
+				args = append([]string{"", "sync", "--from", manifest}, args...)
+				if err := newApp().Run(context.Background(), args); err != nil {
+					t.Errorf("error should be nil, but: %s", err)
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return out
+		}
+
+		out := run("--prune", "--dry-run")
+		for _, s := range []string{
+			"Would update github.com/my-org/a\n",
+			"Would clone github.com/my-org/b\n",
+			"Would remove " + filepath.Join(tmproot, "github.com", "my-org", "old") + "\n",
+		} {
+			if !strings.Contains(out, s) {
+				t.Errorf("out should contain %q, but: %q", s, out)
+			}
+		}
+		if !strings.Contains(buf.String(), filepath.FromSlash("github.com/my-org/old is not in the set")) {
+			t.Errorf("the stray should be reported, but: %q", buf.String())
+		}
+		if _, err := os.Stat(filepath.Join(tmproot, "github.com", "my-org", "b")); err == nil {
+			t.Error("nothing should be cloned on dry run")
+		}
+
+		run("--prune", "-y")
+		if got, expect := filepath.ToSlash(cloneArgs.local), filepath.ToSlash(filepath.Join(tmproot, "github.com", "my-org", "b")); got != expect {
+			t.Errorf("cloned: %s, expect: %s", got, expect)
+		}
+		if got, expect := updateArgs.local, filepath.Join(tmproot, "github.com", "my-org", "a"); got != expect {
+			t.Errorf("updated: %s, expect: %s", got, expect)
+		}
+		if _, err := os.Stat(filepath.Join(tmproot, "github.com", "my-org", "old")); !os.IsNotExist(err) {
+			t.Errorf("the stray should be removed, but: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(tmproot, "github.com", "other", "x")); err != nil {
+			t.Errorf("the repository out of the scopes should be kept, but: %v", err)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+func doVisit(ctx context.Context, cmd *cli.Command) error {
+	dir := cmd.Args().First()
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+	if dir, err = evalSymlinks(dir); err != nil {
+		return err
+	}
+	repo, err := localRepositoryOf(dir)
+	if err != nil {
+		return err
+	}
+	return recordVisit(ctx, repo.FullPath)
+}
+
+// localRepositoryOf returns the local repository containing dir, which is
+// the innermost directory of a VCS under the roots.
+func localRepositoryOf(dir string) (*LocalRepository, error) {
+	roots, err := localRepositoryRoots(true)
+	if err != nil {
+		return nil, err
+	}
+	for p := dir; ; p = filepath.Dir(p) {
+		var root string
+		for _, r := range roots {
+			if strings.HasPrefix(p, r+string(filepath.Separator)) {
+				root = r
+				break
+			}
+		}
+		if root == "" {
+			break
+		}
+		if backend := findVCSBackend(p, ""); backend != nil {
+			return LocalRepositoryFromFullPath(p, backend)
+		}
+	}
+	return nil, fmt.Errorf("no local repository found for: %s", dir)
+}
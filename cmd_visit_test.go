@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDoVisit(t *testing.T) {
+	resetVisits(t)
+	defer func(orig string) { _home = orig }(_home)
+	_home = ""
+	homeOnce = &sync.Once{}
+	tmpd := newTempDir(t)
+	defer func(orig []string) { _localRepositoryRoots = orig }(_localRepositoryRoots)
+	setEnv(t, envGhqRoot, tmpd)
+	_localRepositoryRoots = nil
+	localRepoOnce = &sync.Once{}
+
+	for _, p := range []string{"github.com/motemen/ghq", "github.com/motemen/gore"} {
+		if err := os.MkdirAll(filepath.Join(tmpd, filepath.FromSlash(p), ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sub := filepath.Join(tmpd, "github.com", "motemen", "gore", "cmd")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) (string, error) {
+		var runErr error
+		// the standard input is not a terminal
+		out, _, err := captureWithInput(nil, func() {
+			runErr = newApp().Run(context.Background(), append([]string{""}, args...))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out, runErr
+	}
+
+	if _, err := run("visit", sub); err != nil {
+		t.Fatalf("error should be nil, but: %s", err)
+	}
+	if _, err := run("visit", filepath.Join(tmpd, "github.com")); err == nil {
+		t.Error("visiting a directory out of repositories should be an error")
+	}
+
+	out, err := run("list", "--sort", "frecency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "github.com/motemen/gore\ngithub.com/motemen/ghq\n"
+	if out != filepath.FromSlash(expect) {
+		t.Errorf("visited one should come first, but: %q", out)
+	}
+
+	// the visited one is preferred on ambiguity
+	out, err = run("look", "--print", "motemen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(out); got != filepath.Dir(sub) {
+		t.Errorf("got: %q, expect: %q", got, filepath.Dir(sub))
+	}
+	// unless --select is given, which fails without a terminal
+	if _, err := run("look", "--print", "--select", "motemen"); err == nil {
+		t.Error("ambiguous query should be an error with --select")
+	}
+}
@@ -1,18 +1,45 @@
 package cmdutil
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/mattn/go-isatty"
 	"github.com/x-motemen/ghq/logger"
 )
 
+// WaitDelay is how long a canceled command is given to exit after being
+// interrupted, before it is killed.
+const WaitDelay = 5 * time.Second
+
+// Command returns the exec.Cmd to run the command with the context.
+// When the context is done, the command is interrupted first so that it can
+// clean up its child processes and temporary files, then killed after WaitDelay.
+func Command(ctx context.Context, command string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Cancel = func() error {
+		// os.Interrupt is not supported on Windows
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = WaitDelay
+	return cmd
+}
+
 // Run the command
-func Run(command string, args ...string) error {
-	cmd := exec.Command(command, args...)
+func Run(ctx context.Context, command string, args ...string) error {
+	cmd := Command(ctx, command, args...)
 	cmd.Stdout = os.Stderr
 	cmd.Stderr = os.Stderr
 
@@ -20,8 +47,8 @@ func Run(command string, args ...string) error {
 }
 
 // RunSilently runs the command silently
-func RunSilently(command string, args ...string) error {
-	cmd := exec.Command(command, args...)
+func RunSilently(ctx context.Context, command string, args ...string) error {
+	cmd := Command(ctx, command, args...)
 	cmd.Stdout = io.Discard
 	cmd.Stderr = io.Discard
 
@@ -29,8 +56,8 @@ func RunSilently(command string, args ...string) error {
 }
 
 // RunInDir runs the command in the specified directory
-func RunInDir(dir, command string, args ...string) error {
-	cmd := exec.Command(command, args...)
+func RunInDir(ctx context.Context, dir, command string, args ...string) error {
+	cmd := Command(ctx, command, args...)
 	cmd.Stdout = os.Stderr
 	cmd.Stderr = os.Stderr
 	cmd.Dir = dir
@@ -39,8 +66,8 @@ func RunInDir(dir, command string, args ...string) error {
 }
 
 // RunInDirSilently run the command in the specified directory silently
-func RunInDirSilently(dir, command string, args ...string) error {
-	cmd := exec.Command(command, args...)
+func RunInDirSilently(ctx context.Context, dir, command string, args ...string) error {
+	cmd := Command(ctx, command, args...)
 	cmd.Stdout = io.Discard
 	cmd.Stderr = io.Discard
 	cmd.Dir = dir
@@ -56,26 +83,162 @@ var CommandRunner = func(cmd *exec.Cmd) error {
 	return cmd.Run()
 }
 
+// StderrTailSize is the size of the tail of stderr kept in RunError.
+const StderrTailSize = 4096
+
 // RunCommand run the command
 func RunCommand(cmd *exec.Cmd, silent bool) error {
-	if !silent {
+	if logger.Enabled(logger.LevelVerbose) {
+		// the full command line is logged even if it runs silently
+		logCommand(cmd)
+	} else if !silent {
 		logger.Log(cmd.Args[0], strings.Join(cmd.Args[1:], " "))
 	}
+	if logger.JSON() {
+		// The output of the command would break the JSON lines of the log,
+		// so it is hidden and logged with the error instead.
+		if cmd.Stdout == os.Stderr {
+			cmd.Stdout = io.Discard
+		}
+		if cmd.Stderr == os.Stderr {
+			cmd.Stderr = io.Discard
+			silent = true
+		}
+	}
+	tail := &tailBuffer{size: StderrTailSize}
+	switch w := cmd.Stderr.(type) {
+	case nil:
+		cmd.Stderr = tail
+	case *os.File:
+		// A terminal is passed to the command as is, since commands such as
+		// git show their progress only on a terminal. The user sees the
+		// error output there anyway.
+		if !isTerminal(w) {
+			cmd.Stderr = io.MultiWriter(w, tail)
+		}
+	default:
+		cmd.Stderr = io.MultiWriter(w, tail)
+	}
 	err := CommandRunner(cmd)
 	if err != nil {
-		return &RunError{cmd, err}
+		return &RunError{Command: cmd, ExecError: err, Stderr: tail.String(), Silent: silent}
 	}
 
 	return nil
 }
 
+var isTerminal = func(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
 // RunError is the error type for cmdutil
 type RunError struct {
 	Command   *exec.Cmd
 	ExecError error
+	// Stderr is the tail of the error output of the command, unless it was
+	// written to a terminal.
+	Stderr string
+	// Silent reports whether the command ran silently, in which case Stderr
+	// has not been shown to the user.
+	Silent bool
+}
+
+// HiddenStderr returns the tail of the error output of the failed command in
+// the error chain, if it ran silently. Since the output has not been shown,
+// it should be printed with the error.
+func HiddenStderr(err error) string {
+	var runErr *RunError
+	if !errors.As(err, &runErr) || !runErr.Silent {
+		return ""
+	}
+	return strings.TrimRight(runErr.Stderr, "\r\n")
+}
+
+// ExitStatus returns the exit status of the command, or -1 if it did not exit
+// normally (e.g. not found or killed by a signal). It is not named ExitCode
+// not to be taken for the exit code of ghq.
+func (e *RunError) ExitStatus() int {
+	var exitErr *exec.ExitError
+	if errors.As(e.ExecError, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
 }
 
 // Error to implement error interface
 func (e *RunError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Command.Path, e.ExecError)
 }
+
+// tailBuffer keeps the last size bytes written.
+type tailBuffer struct {
+	mu   sync.Mutex
+	size int
+	buf  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.size; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
+
+// logCommand logs the command line, the directory and the environment
+// variables overriding those of ghq.
+func logCommand(cmd *exec.Cmd) {
+	logger.Log("exec", QuoteArgs(cmd.Args))
+	if cmd.Dir != "" {
+		logger.Log("exec", "  in "+cmd.Dir)
+	}
+	for _, kv := range envOverrides(cmd.Env, os.Environ()) {
+		logger.Log("exec", "  with "+kv)
+	}
+}
+
+// QuoteArgs joins the arguments into a command line, quoting those which
+// would be split or interpreted by a shell.
+func QuoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`&|;<>()*?[]#~!{}") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+var secretEnvReg = regexp.MustCompile(`(?i)token|secret|password|passwd|credential|_key$`)
+
+// envOverrides returns the entries of env which are not in base, with the
+// values of secret-looking variables masked.
+func envOverrides(env, base []string) []string {
+	if env == nil {
+		return nil
+	}
+	inherited := make(map[string]bool, len(base))
+	for _, kv := range base {
+		inherited[kv] = true
+	}
+	var overrides []string
+	for _, kv := range env {
+		if inherited[kv] {
+			continue
+		}
+		if k, _, ok := strings.Cut(kv, "="); ok && secretEnvReg.MatchString(k) {
+			kv = k + "=***"
+		}
+		overrides = append(overrides, kv)
+	}
+	return overrides
+}
@@ -1,13 +1,24 @@
 package cmdutil
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/x-motemen/ghq/logger"
 )
 
 func TestRunInDirSilently(t *testing.T) {
-	err := RunInDirSilently(".", "/path/to/unknown")
+	err := RunInDirSilently(context.Background(), ".", "/path/to/unknown")
 	expect := "/path/to/unknown: "
 	if !strings.HasPrefix(fmt.Sprintf("%s", err), expect) {
 		t.Errorf("error message should have prefix %q, but: %q", expect, err)
@@ -15,22 +26,219 @@ func TestRunInDirSilently(t *testing.T) {
 }
 
 func TestRun(t *testing.T) {
-	err := Run("echo")
+	err := Run(context.Background(), "echo")
 	if err != nil {
 		t.Errorf("error should be nil but: %s", err)
 	}
 }
 
 func TestRunInDir(t *testing.T) {
-	err := RunInDir(".", "echo")
+	err := RunInDir(context.Background(), ".", "echo")
 	if err != nil {
 		t.Errorf("error should be nil but: %s", err)
 	}
 }
 
 func TestRunSilently(t *testing.T) {
-	err := RunSilently("echo")
+	err := RunSilently(context.Background(), "echo")
 	if err != nil {
 		t.Errorf("error should be nil but: %s", err)
 	}
 }
+
+func TestRunSilently_canceled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep command is not available")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := RunSilently(ctx, "sleep", "10")
+	if err == nil {
+		t.Fatalf("error should occur when the context is done")
+	}
+	var runErr *RunError
+	if !errors.As(err, &runErr) {
+		t.Errorf("error should be *RunError but: %T", err)
+	}
+	if elapsed := time.Since(start); elapsed > WaitDelay {
+		t.Errorf("command should be interrupted, but took %s", elapsed)
+	}
+}
+
+func TestRunSilently_stderr(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command is not available")
+	}
+	err := RunSilently(context.Background(), "sh", "-c", "echo 'fatal: early EOF' >&2; exit 128")
+	var runErr *RunError
+	if !errors.As(err, &runErr) {
+		t.Fatalf("error should be *RunError but: %T", err)
+	}
+	if runErr.Stderr != "fatal: early EOF\n" {
+		t.Errorf("stderr should be kept, but: %q", runErr.Stderr)
+	}
+	if runErr.ExitStatus() != 128 {
+		t.Errorf("exit code should be 128, but: %d", runErr.ExitStatus())
+	}
+}
+
+func TestRun_json(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command is not available")
+	}
+	if err := logger.SetFormat(logger.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.SetFormat(logger.FormatText)
+	buf := &bytes.Buffer{}
+	logger.SetOutput(buf)
+	defer logger.SetOutput(os.Stderr)
+
+	f, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	defer func(orig *os.File) { os.Stderr = orig }(os.Stderr)
+	os.Stderr = f
+
+	err = Run(context.Background(), "sh", "-c", "echo progress; echo 'fatal: early EOF' >&2; exit 128")
+	if stderr, _ := os.ReadFile(f.Name()); len(stderr) > 0 {
+		t.Errorf("the output of the command should not be written to stderr, but: %q", stderr)
+	}
+	if got := HiddenStderr(err); got != "fatal: early EOF" {
+		t.Errorf("stderr should be kept to be logged with the error, but: %q", got)
+	}
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Errorf("the command should be logged in JSON, but: %q", buf.String())
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{size: 8}
+	b.Write([]byte("0123"))
+	b.Write([]byte("456789abc"))
+	if got := b.String(); got != "56789abc" {
+		t.Errorf("got: %q, expect: %q", got, "56789abc")
+	}
+}
+
+func TestHiddenStderr(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		expect string
+	}{{
+		name:   "silent",
+		err:    &RunError{ExecError: errors.New("exit status 128"), Stderr: "fatal: early EOF\n", Silent: true},
+		expect: "fatal: early EOF",
+	}, {
+		name:   "wrapped",
+		err:    fmt.Errorf("failed: %w", &RunError{ExecError: errors.New("exit status 1"), Stderr: "oops\r\n", Silent: true}),
+		expect: "oops",
+	}, {
+		name: "shown",
+		err:  &RunError{ExecError: errors.New("exit status 128"), Stderr: "fatal: early EOF\n"},
+	}, {
+		name: "not a RunError",
+		err:  errors.New("oops"),
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := HiddenStderr(tc.err); got != tc.expect {
+				t.Errorf("got: %q, expect: %q", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestQuoteArgs(t *testing.T) {
+	testCases := []struct {
+		args   []string
+		expect string
+	}{{
+		args:   []string{"git", "clone", "https://github.com/x-motemen/ghq"},
+		expect: "git clone https://github.com/x-motemen/ghq",
+	}, {
+		args:   []string{"git", "clone", "/path/to/my repo", ""},
+		expect: `git clone "/path/to/my repo" ""`,
+	}, {
+		args:   []string{"sh", "-c", "echo $HOME"},
+		expect: `sh -c "echo $HOME"`,
+	}}
+	for _, tc := range testCases {
+		if got := QuoteArgs(tc.args); got != tc.expect {
+			t.Errorf("got: %q, expect: %q", got, tc.expect)
+		}
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	base := []string{"HOME=/home/ghq", "GIT_TERMINAL_PROMPT=1"}
+	env := append(base[:len(base):len(base)], "GIT_TERMINAL_PROMPT=0", "GITHUB_TOKEN=secret")
+	got := envOverrides(env, base)
+	expect := []string{"GIT_TERMINAL_PROMPT=0", "GITHUB_TOKEN=***"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %q, expect: %q", got, expect)
+	}
+	if got := envOverrides(nil, base); got != nil {
+		t.Errorf("no overrides should be reported for the inherited environment, but: %q", got)
+	}
+}
+
+func TestRunCommand_verbose(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetOutput(buf)
+	defer logger.SetOutput(os.Stderr)
+	logger.SetLevel(logger.LevelVerbose)
+	defer logger.SetLevel(logger.LevelNormal)
+
+	cmd := Command(context.Background(), "echo", "hello world")
+	cmd.Stdout = io.Discard
+	cmd.Dir = "."
+	cmd.Env = append(os.Environ(), "GHQ_TEST=1")
+	if err := RunCommand(cmd, true); err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	log := buf.String()
+	for _, expect := range []string{`echo "hello world"`, "in .", "with GHQ_TEST=1"} {
+		if !strings.Contains(log, expect) {
+			t.Errorf("log should contain %q, but: %q", expect, log)
+		}
+	}
+}
+
+func TestRunCommand_terminal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command is not available")
+	}
+	defer func(orig func(*os.File) bool) { isTerminal = orig }(isTerminal)
+	isTerminal = func(*os.File) bool { return true }
+
+	f, err := os.CreateTemp(t.TempDir(), "tty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cmd := exec.Command("sh", "-c", "echo 'fatal: early EOF' >&2; exit 128")
+	cmd.Stderr = f
+	err = RunCommand(cmd, true)
+	var runErr *RunError
+	if !errors.As(err, &runErr) {
+		t.Fatalf("error should be *RunError but: %T", err)
+	}
+	if cmd.Stderr != f {
+		t.Errorf("the terminal should be passed to the command as is, but: %T", cmd.Stderr)
+	}
+	if runErr.Stderr != "" {
+		t.Errorf("stderr written to the terminal should not be kept, but: %q", runErr.Stderr)
+	}
+	if b, _ := os.ReadFile(f.Name()); string(b) != "fatal: early EOF\n" {
+		t.Errorf("stderr should be written to the terminal, but: %q", b)
+	}
+	if runErr.ExitStatus() != 128 {
+		t.Errorf("exit code should be 128, but: %d", runErr.ExitStatus())
+	}
+}
@@ -12,12 +12,25 @@ import (
 var commands = []*cli.Command{
 	commandGet,
 	commandList,
+	commandLook,
+	commandVisit,
 	commandRm,
+	commandSync,
 	commandRoot,
 	commandCreate,
 	commandMigrate,
+	commandRelocate,
+	commandAudit,
+	commandDoctor,
+	commandProbe,
+	commandIdentity,
+	commandShellInit,
+	commandComplete,
 }
 
+// partialModes are the modes of 'ghq get --partial'.
+var partialModes = []string{"blobless", "treeless"}
+
 var commandGet = &cli.Command{
 	Name:    "get",
 	Aliases: []string{"clone"},
@@ -26,7 +39,10 @@ var commandGet = &cli.Command{
     Clone a repository under ghq root directory. If the repository is
     already cloned to local, nothing will happen unless '-u' ('--update')
     flag is supplied, in which case 'git remote update' is executed.
-    When you use '-p' option, the repository is cloned via SSH.`,
+    When you use '-p' option, the repository is cloned via SSH.
+    '--org' and '--user' get all the repositories of an organization or a
+    user listed through the API of GitHub, GitLab, Gitea, Forgejo or
+    Bitbucket, except archived and forked ones.`,
 	Action: doGet,
 	Flags: []cli.Flag{
 		&cli.BoolFlag{Name: "update", Aliases: []string{"u"},
@@ -40,17 +56,44 @@ var commandGet = &cli.Command{
 		&cli.StringFlag{Name: "branch", Aliases: []string{"b"},
 			Usage: "Specify `branch` name. This flag implies --single-branch on Git"},
 		&cli.BoolFlag{Name: "parallel", Aliases: []string{"P"}, Usage: "Import parallelly"},
+		&cli.BoolFlag{Name: "keep-going", Usage: "Go on after failures, then summarize them (default with --parallel)"},
+		&cli.BoolFlag{Name: "fail-fast", Usage: "Stop at the first failure (default without --parallel)"},
+		&cli.StringFlag{Name: "failed-file", Usage: "Write the targets failed to get to `file`, to retry by 'ghq get < file'"},
 		&cli.BoolFlag{Name: "bare", Usage: "Do a bare clone"},
+		&cli.BoolFlag{Name: "offline", Usage: "Detect VCS without probing the network"},
+		&cli.DurationFlag{Name: "timeout",
+			Usage: "Abort getting each repository after `duration` (e.g. 10m)"},
+		&cli.IntFlag{Name: "retries",
+			Usage: "Retry cloning or updating up to `count` times on transient network failures"},
+		&cli.BoolFlag{Name: "no-wait", Usage: "Fail instead of waiting when the repository is in use by another ghq process"},
 		&cli.StringFlag{
 			Name:  "partial",
 			Usage: "Do a partial clone. Can specify either \"blobless\" or \"treeless\"",
 			Action: func(ctx context.Context, cmd *cli.Command, v string) error {
-				expected := []string{"blobless", "treeless"}
-				if !slices.Contains(expected, v) {
+				if !slices.Contains(partialModes, v) {
 					return fmt.Errorf("flag partial value \"%v\" is not allowed", v)
 				}
 				return nil
 			}},
+		&cli.StringFlag{Name: "org",
+			Usage: "Get the repositories of the organization `owner` (e.g. github.com/my-org)"},
+		&cli.StringFlag{Name: "user",
+			Usage: "Get the repositories of the user `owner` (e.g. github.com/me)"},
+		&cli.BoolFlag{Name: "archived", Usage: "Include archived repositories with --org and --user"},
+		&cli.BoolFlag{Name: "forks", Usage: "Include forked repositories with --org and --user"},
+		&cli.StringSliceFlag{Name: "topic",
+			Usage: "Get only the repositories with the `topic` with --org and --user"},
+		&cli.StringFlag{Name: "language",
+			Usage: "Get only the repositories in the `language` with --org and --user"},
+		&cli.StringFlag{
+			Name:  "visibility",
+			Usage: "Get only the repositories of the `visibility`: public, private or internal",
+			Action: func(ctx context.Context, cmd *cli.Command, v string) error {
+				if !slices.Contains(forgeVisibilities, v) {
+					return fmt.Errorf("flag visibility value \"%v\" is not allowed", v)
+				}
+				return nil
+			}},
 	},
 }
 
@@ -62,7 +105,10 @@ var commandList = &cli.Command{
     repositories whose names contain that query text are listed.
     '-e' ('--exact') forces the match to be an exact one (i.e. the query equals to
     project or user/project) If '-p' ('--full-path') is given, the full paths
-    to the repository root are printed instead of relative ones.`,
+    to the repository root are printed instead of relative ones. '--stale'
+    lists only the Git repositories whose upstream is stale, as 'ghq audit'
+    reports. '--nested' and '--submodules' also list the repositories in the
+    working trees of others and the submodules, with their parents.`,
 	Action: doList,
 	Flags: []cli.Flag{
 		&cli.BoolFlag{Name: "exact", Aliases: []string{"e"}, Usage: "Perform an exact match"},
@@ -70,9 +116,51 @@ var commandList = &cli.Command{
 		&cli.BoolFlag{Name: "full-path", Aliases: []string{"p"}, Usage: "Print full paths"},
 		&cli.BoolFlag{Name: "unique", Usage: "Print unique subpaths"},
 		&cli.BoolFlag{Name: "bare", Usage: "Query bare repositories"},
+		&cli.BoolFlag{Name: "stale", Usage: "List only the repositories whose upstream is stale"},
+		&cli.BoolFlag{Name: "nested", Usage: "Also list the repositories in the working trees of others"},
+		&cli.BoolFlag{Name: "submodules", Usage: "Also list the submodules of the repositories"},
+		&cli.StringFlag{
+			Name:  "sort",
+			Value: "name",
+			Usage: "Sort the repositories by `order`: name, frecency, recent or mtime",
+			Action: func(ctx context.Context, cmd *cli.Command, v string) error {
+				if !slices.Contains(listSortOrders, v) {
+					return fmt.Errorf("flag sort value \"%v\" is not allowed", v)
+				}
+				return nil
+			}},
+	},
+}
+
+var commandLook = &cli.Command{
+	Name:  "look",
+	Usage: "Look into a local repository",
+	Description: `
+    Start a shell in the local repository which matches the query, as
+    'ghq list' does. When more than one repositories are found, one of them
+    is chosen by $GHQ_SELECTOR (e.g. fzf or peco) if set, or by the built-in
+    selector on a terminal. '--print' prints its full path instead, which is
+    handy to change the directory of the current shell.`,
+	Action: doLook,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{Name: "exact", Aliases: []string{"e"}, Usage: "Perform an exact match"},
+		&cli.BoolFlag{Name: "print", Usage: "Print the full path instead of starting a shell"},
+		&cli.BoolFlag{Name: "select", Usage: "Choose from the candidates even if one of them has been visited"},
+		&cli.BoolFlag{Name: "bare", Usage: "Query bare repositories"},
 	},
 }
 
+var commandVisit = &cli.Command{
+	Name:  "visit",
+	Usage: "Record a visit to a local repository",
+	Description: `
+    Record a visit to the local repository containing the directory, or the
+    current directory if omitted, which ranks the repository higher in
+    'ghq list --sort frecency' and 'ghq look'. Visits by 'ghq look' and
+    'ghq get --look' are recorded automatically.`,
+	Action: doVisit,
+}
+
 var commandRm = &cli.Command{
 	Name:   "rm",
 	Usage:  "Remove local repository",
@@ -80,6 +168,33 @@ var commandRm = &cli.Command{
 	Flags: []cli.Flag{
 		&cli.BoolFlag{Name: "dry-run", Usage: "Do not remove actually"},
 		&cli.BoolFlag{Name: "bare", Usage: "Remove a bare repository"},
+		&cli.BoolFlag{Name: "no-wait", Usage: "Fail instead of waiting when the repository is in use by another ghq process"},
+	},
+}
+
+var commandSync = &cli.Command{
+	Name:  "sync",
+	Usage: "Reconcile local repositories with a set of repositories",
+	Description: `
+    Clone the repositories in the set which are missing locally, and update
+    the others. The set is read from a manifest file ("-" for the standard
+    input), which lists repositories one per line as 'ghq get' accepts, or
+    listed from an organization or a user on a forge, e.g. github.com/my-org.
+    Local repositories under the same owners but out of the set, or archived
+    or deleted upstream, are reported, and removed with '--prune', which
+    needs a token of the forge when the set is listed from it.`,
+	Action: doSync,
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "from",
+			Usage: "Read the set from `manifest` file, or list it from <host>/<owner> on a forge"},
+		&cli.BoolFlag{Name: "user", Usage: "The owner given to --from is a user"},
+		&cli.BoolFlag{Name: "forks", Usage: "Include forked repositories of the owner"},
+		&cli.BoolFlag{Name: "p", Usage: "Clone with SSH"},
+		&cli.BoolFlag{Name: "parallel", Aliases: []string{"P"}, Usage: "Clone and update parallelly"},
+		&cli.BoolFlag{Name: "prune", Usage: "Remove the local repositories out of the set"},
+		&cli.BoolFlag{Name: "y", Usage: "Remove without confirmation with --prune"},
+		&cli.BoolFlag{Name: "dry-run", Usage: "Show what would happen without cloning, updating or removing"},
+		&cli.BoolFlag{Name: "no-wait", Usage: "Fail instead of waiting when the repository is in use by another ghq process"},
 	},
 }
 
@@ -92,6 +207,32 @@ var commandRoot = &cli.Command{
 	},
 }
 
+var commandShellInit = &cli.Command{
+	Name:  "shell-init",
+	Usage: "Print the shell integration script",
+	Description: `
+    Print the script to integrate ghq with the shell, which wraps ghq by
+    a shell function so that 'ghq look', 'ghq get --look' and 'ghq create'
+    change the directory of the current shell instead of starting a new one,
+    binds Ctrl-] to jump to a repository, and registers the completion.
+    Add 'eval "$(ghq shell-init bash)"' to ~/.bashrc, 'eval "$(ghq
+    shell-init zsh)"' to ~/.zshrc, or 'ghq shell-init fish | source' to
+    ~/.config/fish/config.fish.`,
+	Action: doShellInit,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{Name: "completion", Usage: "Print the completion script only"},
+		&cli.BoolFlag{Name: "no-bind", Usage: "Do not bind the key to jump to a repository"},
+	},
+}
+
+// commandComplete is called by the completion scripts.
+var commandComplete = &cli.Command{
+	Name:   "__complete",
+	Usage:  "Print the candidates of the completion",
+	Hidden: true,
+	Action: doComplete,
+}
+
 var commandCreate = &cli.Command{
 	Name:   "create",
 	Usage:  "Create a new repository",
@@ -99,21 +240,38 @@ var commandCreate = &cli.Command{
 	Flags: []cli.Flag{
 		&cli.StringFlag{Name: "vcs", Usage: "Specify `vcs` backend explicitly"},
 		&cli.BoolFlag{Name: "bare", Usage: "Create a bare repository"},
+		&cli.BoolFlag{Name: "offline", Usage: "Detect VCS without probing the network"},
+		&cli.BoolFlag{Name: "no-wait", Usage: "Fail instead of waiting when the repository is in use by another ghq process"},
 	},
 }
 
 type commandDoc struct {
 	Parent    string
 	Arguments string
+	// Complete is what the arguments are completed with by the completion
+	// scripts, such as completeRepository.
+	Complete string
 }
 
 var commandDocs = map[string]commandDoc{
-	"get":     {"", "[-u] [-p] [--shallow] [--vcs <vcs>] [--look] [--silent] [--branch <branch>] [--no-recursive] [--bare] [--partial blobless|treeless] <repository URL>|<project>|<user>/<project>|<host>/<user>/<project>"},
-	"list":    {"", "[-p] [-e] [<query>]"},
-	"create":  {"", "<project>|<user>/<project>|<host>/<user>/<project>"},
-	"rm":      {"", "<project>|<user>/<project>|<host>/<user>/<project>"},
-	"root":    {"", "[-all]"},
-	"migrate": {"", "[-y] [--dry-run] <repository-directory>"},
+	"get":        {"", "[-u] [-p] [--shallow] [--vcs <vcs>] [--look] [--silent] [--branch <branch>] [--no-recursive] [--bare] [--offline] [--timeout <duration>] [--retries <count>] [--no-wait] [--keep-going|--fail-fast] [--failed-file <file>] [--partial blobless|treeless] <repository URL>|<project>|<user>/<project>|<host>/<user>/<project>|(--org|--user) <host>/<owner> [--archived] [--forks] [--topic <topic>] [--language <language>] [--visibility public|private|internal]", completeUpdatedRepository},
+	"list":       {"", "[-p] [-e] [--stale] [--nested] [--submodules] [--sort name|frecency|recent|mtime] [<query>]", completeNothing},
+	"look":       {"", "[-e] [--print] [--select] [--bare] [<query>]", completeRepository},
+	"visit":      {"", "[<directory>]", completeDirectory},
+	"create":     {"", "[--vcs <vcs>] [--bare] [--offline] [--no-wait] <project>|<user>/<project>|<host>/<user>/<project>", completeNamespace},
+	"rm":         {"", "[--dry-run] [--bare] [--no-wait] <project>|<user>/<project>|<host>/<user>/<project>", completeRepository},
+	"root":       {"", "[-all]", completeNothing},
+	"sync":       {"", "--from <manifest>|<host>/<owner> [--user] [--forks] [-p] [-P] [--prune [-y]] [--dry-run] [--no-wait]", completeNothing},
+	"migrate":    {"", "[-y] [--dry-run] [--no-wait] <repository-directory>", completeDirectory},
+	"relocate":   {"", "[-y] [--dry-run] [--no-wait] <repository> [<new-url>]|--all", completeRepository},
+	"audit":      {"", "[<query>]", completeNothing},
+	"doctor":     {"", "", completeNothing},
+	"probe":      {"", "[--offline] [--no-cache] <repository URL>", completeNothing},
+	"identity":   {"", "check [--fix] [<query>]", completeNothing},
+	"check":      {"identity", "[--fix] [<query>]", completeNothing},
+	"shell-init": {"", "[--completion] [--no-bind] bash|zsh|fish", completeShell},
+	"help":       {"", "[<command>]", completeCommand},
+	"__complete": {"", "repositories|namespaces [<prefix>]", completeNothing},
 }
 
 // Makes template conditionals to generate per-command documents.
@@ -121,6 +279,9 @@ func mkCommandsTemplate(genTemplate func(commandDoc) string) string {
 	template := "{{if false}}"
 	for _, command := range commands {
 		template = template + fmt.Sprintf("{{else if (eq .Name %q)}}%s", command.Name, genTemplate(commandDocs[command.Name]))
+		for _, sub := range command.Commands {
+			template = template + fmt.Sprintf("{{else if (eq .Name %q)}}%s", sub.Name, genTemplate(commandDocs[sub.Name]))
+		}
 	}
 	return template + "{{end}}"
 }
@@ -154,5 +315,91 @@ var commandMigrate = &cli.Command{
 	Flags: []cli.Flag{
 		&cli.BoolFlag{Name: "y", Usage: "Skip confirmation prompt"},
 		&cli.BoolFlag{Name: "dry-run", Usage: "Show what would happen without moving"},
+		&cli.BoolFlag{Name: "no-wait", Usage: "Fail instead of waiting when the repository is in use by another ghq process"},
+	},
+}
+
+var commandRelocate = &cli.Command{
+	Name:  "relocate",
+	Usage: "Update the remote URL of a repository and move it to the new path",
+	Description: `
+    Set the remote URL of a local Git, Mercurial or Fossil repository to the
+    new one, such as the one reported by 'ghq audit' after the upstream is
+    renamed, and move the repository to the path of the new URL under the
+    root where 'ghq get' would clone it. Without the new URL, the repository is moved to the path of
+    its current remote URL. '--all' moves every repository whose path
+    disagrees with its remote URL.`,
+	Action: doRelocate,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{Name: "all", Usage: "Relocate all the repositories whose paths disagree with their remote URLs"},
+		&cli.BoolFlag{Name: "y", Usage: "Skip confirmation prompt"},
+		&cli.BoolFlag{Name: "dry-run", Usage: "Show what would happen without relocating"},
+		&cli.BoolFlag{Name: "no-wait", Usage: "Fail instead of waiting when the repository is in use by another ghq process"},
+	},
+}
+
+var commandAudit = &cli.Command{
+	Name:  "audit",
+	Usage: "Report local repositories whose upstream is stale",
+	Description: `
+    Check the upstream of each local Git repository, or the ones matching
+    the query, and print the stale ones: unreachable, renamed or redirected,
+    archived, or whose default branch has been force-pushed, which is known
+    only if its commit upstream has been fetched. The forges are asked
+    through their APIs as 'ghq get --org' does. Exits with 1 if any.`,
+	Action: doAudit,
+}
+
+var commandDoctor = &cli.Command{
+	Name:  "doctor",
+	Usage: "Check the roots and the local repositories for problems",
+	Description: `
+    Check that the roots exist, are writable and do not overlap, and that
+    GHQ_ROOT does not override ghq.root configured differently. Then check
+    the local repositories for paths disagreeing with their remote URLs,
+    duplicate clones of the same remote, repositories nested in the working
+    tree of another, broken links of worktrees, and missing commands of
+    their VCS, for directories at the depth of the repositories of their
+    hosts (ghq.<url>.depth) which are not repositories, and for symlink
+    loops under the roots. Each problem is printed with a command to fix
+    it, and exits with 1 if any.`,
+	Action: doDoctor,
+}
+
+var commandProbe = &cli.Command{
+	Name:  "probe",
+	Usage: "Show how the VCS of a remote repository is detected",
+	Description: `
+    Detect the VCS backend of a remote repository and print each detector
+    tried with its outcome. Backends detected by probing the network are
+    cached per host, or per repository for go-import meta tags, for a week,
+    so that later 'ghq get' does not probe again.`,
+	Action: doProbe,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{Name: "offline", Usage: "Detect VCS without probing the network"},
+		&cli.BoolFlag{Name: "no-cache", Usage: "Probe the network even if the result is cached"},
+	},
+}
+
+var commandIdentity = &cli.Command{
+	Name:  "identity",
+	Usage: "Manage the identity configured per host or organization",
+	Description: `
+    The identity configured by 'ghq.<url>.userEmail' and so on is applied
+    to the local configuration of Git repositories when they are cloned or
+    created. 'ghq identity check' audits the existing ones.`,
+	Commands: []*cli.Command{commandIdentityCheck},
+}
+
+var commandIdentityCheck = &cli.Command{
+	Name:  "check",
+	Usage: "Report repositories whose identity does not match the configuration",
+	Description: `
+    Compare the identity configured for each local Git repository with the
+    one in effect in it, and print the mismatches. Exits with 1 if any,
+    unless '--fix' is given to apply the configured identity.`,
+	Action: doIdentityCheck,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{Name: "fix", Usage: "Apply the configured identity to the mismatched repositories"},
 	},
 }
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"net/url"
+	"os"
 	"path/filepath"
 	"sync"
 	"testing"
@@ -12,6 +14,7 @@ type _cloneArgs struct {
 	local     string
 	shallow   bool
 	branch    string
+	revision  string
 	recursive bool
 	bare      bool
 	silent    bool
@@ -22,6 +25,17 @@ type _updateArgs struct {
 	local string
 }
 
+// stagedDestination returns the path where the repository staged at dir is
+// renamed into.
+func stagedDestination(dir string) string {
+	stage, base := filepath.Split(dir)
+	stage = filepath.Clean(stage)
+	if !isStagingDir(stage) {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(stage), base)
+}
+
 func withFakeGitBackend(t *testing.T, block func(*testing.T, string, *_cloneArgs, *_updateArgs)) {
 	tmpRoot := newTempDir(t)
 
@@ -33,12 +47,16 @@ func withFakeGitBackend(t *testing.T, block func(*testing.T, string, *_cloneArgs
 
 	var originalGitBackend = GitBackend
 	tmpBackend := &VCSBackend{
-		Clone: func(vg *vcsGetOption) error {
+		Clone: func(_ context.Context, vg *vcsGetOption) error {
+			if err := os.MkdirAll(vg.dir, 0755); err != nil {
+				return err
+			}
 			cloneArgs = _cloneArgs{
 				remote:    vg.url,
-				local:     filepath.FromSlash(vg.dir),
+				local:     stagedDestination(filepath.FromSlash(vg.dir)),
 				shallow:   vg.shallow,
 				branch:    vg.branch,
+				revision:  vg.revision,
 				recursive: vg.recursive,
 				bare:      vg.bare,
 				silent:    vg.silent,
@@ -46,7 +64,7 @@ func withFakeGitBackend(t *testing.T, block func(*testing.T, string, *_cloneArgs
 			}
 			return nil
 		},
-		Update: func(vg *vcsGetOption) error {
+		Update: func(_ context.Context, vg *vcsGetOption) error {
 			updateArgs = _updateArgs{
 				local: vg.dir,
 			}
@@ -0,0 +1,616 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/urfave/cli/v3"
+)
+
+// What the arguments of commands and the values of flags are completed with.
+const (
+	completeNothing           = ""
+	completeRepository        = "repository"
+	completeUpdatedRepository = "updated-repository" // with --update only
+	completeNamespace         = "namespace"          // directories to create in
+	completeDirectory         = "directory"
+	completeFile              = "file"
+	completeCommand           = "command"
+	completeVCS               = "vcs"
+	completePartial           = "partial"
+	completeShell             = "shell"
+	completeSortOrder         = "sort-order"
+	completeVisibility        = "visibility"
+)
+
+// flagCompletions are what the values of the flags are completed with.
+var flagCompletions = map[string]string{
+	"vcs":         completeVCS,
+	"partial":     completePartial,
+	"failed-file": completeFile,
+	"log-file":    completeFile,
+	"sort":        completeSortOrder,
+	"visibility":  completeVisibility,
+	"from":        completeFile,
+}
+
+// completionWords returns the fixed candidates of the completion, or nil if
+// they are not fixed.
+func completionWords(complete string) []string {
+	switch complete {
+	case completeVCS:
+		return slices.Sorted(maps.Keys(vcsRegistry))
+	case completePartial:
+		return partialModes
+	case completeShell:
+		return shellInitShells
+	case completeSortOrder:
+		return listSortOrders
+	case completeVisibility:
+		return forgeVisibilities
+	}
+	return nil
+}
+
+// A completionFlag is a flag in the completion scripts.
+type completionFlag struct {
+	names       []string // with dashes, e.g. "--update" and "-u"
+	desc        string
+	placeholder string
+	takesValue  bool
+	repeatable  bool
+	local       bool // of the root command only
+	complete    string
+}
+
+// A completionCommand is a command in the completion scripts, which are
+// generated from the definitions of the commands and commandDocs, not to
+// drift from them.
+type completionCommand struct {
+	names    []string
+	path     []string // names of the command and its parents
+	desc     string
+	flags    []completionFlag
+	complete string
+	commands []*completionCommand
+}
+
+func newCompletionCommand(cmd *cli.Command, parent *completionCommand) *completionCommand {
+	c := &completionCommand{
+		names:    append([]string{cmd.Name}, cmd.Aliases...),
+		desc:     completionDesc(cmd.Usage),
+		complete: commandDocs[cmd.Name].Complete,
+	}
+	if parent != nil {
+		c.path = append(slices.Clone(parent.path), cmd.Name)
+	}
+	for _, f := range cmd.Flags {
+		if vf, ok := f.(cli.VisibleFlag); ok && !vf.IsVisible() {
+			continue
+		}
+		cf := newCompletionFlag(f)
+		// every command has --help, which is completed as a global one
+		if parent != nil && cf.names[0] == "--help" {
+			continue
+		}
+		c.flags = append(c.flags, cf)
+	}
+	for _, sub := range cmd.Commands {
+		if sub.Hidden || parent != nil && sub.Name == "help" {
+			continue
+		}
+		c.commands = append(c.commands, newCompletionCommand(sub, c))
+	}
+	return c
+}
+
+func newCompletionFlag(f cli.Flag) completionFlag {
+	names := f.Names()
+	cf := completionFlag{complete: flagCompletions[names[0]]}
+	for _, n := range names {
+		if len(n) == 1 {
+			cf.names = append(cf.names, "-"+n)
+		} else {
+			cf.names = append(cf.names, "--"+n)
+		}
+	}
+	if df, ok := f.(cli.DocGenerationFlag); ok {
+		cf.desc = completionDesc(df.GetUsage())
+		cf.takesValue = df.TakesValue()
+		cf.placeholder = names[0]
+		if _, p, ok := strings.Cut(df.GetUsage(), "`"); ok {
+			cf.placeholder, _, _ = strings.Cut(p, "`")
+		}
+	}
+	if bf, ok := f.(*cli.BoolFlag); ok && bf.Config.Count != nil {
+		cf.repeatable = true
+	}
+	if lf, ok := f.(cli.LocalFlag); ok {
+		cf.local = lf.IsLocal() && cf.names[0] != "--help"
+	}
+	return cf
+}
+
+// completionDesc returns the first sentence of the usage, capitalized.
+func completionDesc(usage string) string {
+	usage = strings.ReplaceAll(usage, "`", "")
+	for i := 0; i+2 < len(usage); i++ {
+		// not to cut "e.g. 10m"
+		if usage[i:i+2] == ". " && unicode.IsUpper(rune(usage[i+2])) {
+			usage = usage[:i]
+			break
+		}
+	}
+	if usage == "" {
+		return ""
+	}
+	r, size := utf8.DecodeRuneInString(usage)
+	return string(unicode.ToUpper(r)) + usage[size:]
+}
+
+// commandNames returns the names and the aliases of the subcommands.
+func (c *completionCommand) commandNames() []string {
+	var names []string
+	for _, sub := range c.commands {
+		names = append(names, sub.names...)
+	}
+	return names
+}
+
+func (c *completionCommand) flagNames() []string {
+	var names []string
+	for _, f := range c.flags {
+		names = append(names, f.names...)
+	}
+	return names
+}
+
+// globalFlags returns the flags of the root command available for all the
+// commands if global, or the ones of the root command only.
+func (c *completionCommand) globalFlags(global bool) []completionFlag {
+	var flags []completionFlag
+	for _, f := range c.flags {
+		if f.local != global {
+			flags = append(flags, f)
+		}
+	}
+	return flags
+}
+
+func (c *completionCommand) updateFlag() (completionFlag, bool) {
+	for _, f := range c.flags {
+		if f.names[0] == "--update" {
+			return f, true
+		}
+	}
+	return completionFlag{}, false
+}
+
+func completionHeader(shell string) string {
+	return fmt.Sprintf("# Code generated by 'ghq shell-init --completion %s'; DO NOT EDIT.\n", shell)
+}
+
+// writeBashCompletion writes the completion script for bash, which depends
+// on bash-completion.
+func writeBashCompletion(w io.Writer, root *completionCommand) error {
+	b := &strings.Builder{}
+	b.WriteString(`__ghq_complete() {
+  local IFS=$'\n'
+  COMPREPLY=( $(command ghq __complete "$1" "$cur" 2>/dev/null) )
+}
+
+`)
+	b.WriteString("_ghq() {\n")
+	b.WriteString("  local cur prev words cword\n")
+	b.WriteString("  _init_completion || return\n\n")
+	fmt.Fprintf(b, "  local subcommands=\"%s\"\n", strings.Join(root.commandNames(), " "))
+	global := &completionCommand{flags: root.globalFlags(true)}
+	fmt.Fprintf(b, "  local global_opts=\"%s\"\n\n", strings.Join(global.flagNames(), " "))
+	writeBashFlagValues(b, "  ", global.flags)
+	b.WriteString("  if [[ $cword = 1 ]]; then\n")
+	rootOnly := &completionCommand{flags: root.globalFlags(false)}
+	fmt.Fprintf(b, "    COMPREPLY=( $(compgen -W \"$subcommands $global_opts %s\" -- \"$cur\") )\n",
+		strings.Join(rootOnly.flagNames(), " "))
+	b.WriteString("    return 0\n")
+	b.WriteString("  fi\n\n")
+	b.WriteString("  case \"${words[1]}\" in\n")
+	for _, sub := range root.commands {
+		writeBashCommand(b, "    ", 1, sub)
+	}
+	b.WriteString("  esac\n")
+	b.WriteString("}\n\n")
+	b.WriteString("complete -F _ghq ghq\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeBashCommand(b *strings.Builder, indent string, depth int, c *completionCommand) {
+	fmt.Fprintf(b, "%s%s)\n", indent, strings.Join(c.names, "|"))
+	in := indent + "  "
+	if len(c.commands) > 0 {
+		fmt.Fprintf(b, "%sif [[ $cword = %d ]]; then\n", in, depth+1)
+		fmt.Fprintf(b, "%s  COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n", in, strings.Join(c.commandNames(), " "))
+		fmt.Fprintf(b, "%s  return 0\n", in)
+		fmt.Fprintf(b, "%sfi\n", in)
+		fmt.Fprintf(b, "%scase \"${words[%d]}\" in\n", in, depth+1)
+		for _, sub := range c.commands {
+			writeBashCommand(b, in+"  ", depth+1, sub)
+		}
+		fmt.Fprintf(b, "%sesac;;\n", in)
+		return
+	}
+	writeBashFlagValues(b, in, c.flags)
+	fmt.Fprintf(b, "%sif [[ $cur = -* ]]; then\n", in)
+	fmt.Fprintf(b, "%s  COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n", in,
+		strings.Join(append(c.flagNames(), "$global_opts"), " "))
+	fmt.Fprintf(b, "%s  return 0\n", in)
+	fmt.Fprintf(b, "%sfi\n", in)
+	switch c.complete {
+	case completeRepository:
+		fmt.Fprintf(b, "%s__ghq_complete repositories\n", in)
+	case completeUpdatedRepository:
+		if f, ok := c.updateFlag(); ok {
+			fmt.Fprintf(b, "%slocal arg\n", in)
+			fmt.Fprintf(b, "%sfor arg in \"${words[@]}\"; do\n", in)
+			fmt.Fprintf(b, "%s  case \"$arg\" in\n", in)
+			fmt.Fprintf(b, "%s    %s)\n", in, strings.Join(f.names, "|"))
+			fmt.Fprintf(b, "%s      __ghq_complete repositories\n", in)
+			fmt.Fprintf(b, "%s      break;;\n", in)
+			fmt.Fprintf(b, "%s  esac\n", in)
+			fmt.Fprintf(b, "%sdone\n", in)
+		}
+	case completeNamespace:
+		fmt.Fprintf(b, "%s__ghq_complete namespaces\n", in)
+		fmt.Fprintf(b, "%scompopt -o nospace\n", in)
+	case completeDirectory:
+		fmt.Fprintf(b, "%s_filedir -d\n", in)
+	case completeFile:
+		fmt.Fprintf(b, "%s_filedir\n", in)
+	case completeCommand:
+		fmt.Fprintf(b, "%sCOMPREPLY=( $(compgen -W \"$subcommands\" -- \"$cur\") )\n", in)
+	default:
+		if words := completionWords(c.complete); words != nil {
+			fmt.Fprintf(b, "%sCOMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n", in, strings.Join(words, " "))
+		}
+	}
+	fmt.Fprintf(b, "%s;;\n", in)
+}
+
+// writeBashFlagValues writes the completion of the values of the flags
+// after them.
+func writeBashFlagValues(b *strings.Builder, indent string, flags []completionFlag) {
+	var (
+		cases   []string
+		noValue []string
+	)
+	for _, f := range flags {
+		if !f.takesValue {
+			continue
+		}
+		pattern := strings.Join(f.names, "|")
+		switch f.complete {
+		case completeFile:
+			cases = append(cases, pattern+")\n"+indent+"    _filedir\n")
+		case completeDirectory:
+			cases = append(cases, pattern+")\n"+indent+"    _filedir -d\n")
+		default:
+			if words := completionWords(f.complete); words != nil {
+				cases = append(cases, fmt.Sprintf("%s)\n%s    COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n",
+					pattern, indent, strings.Join(words, " ")))
+			} else {
+				noValue = append(noValue, pattern)
+			}
+		}
+	}
+	if len(noValue) > 0 {
+		// expects an arbitrary value
+		cases = append(cases, strings.Join(noValue, "|")+")\n")
+	}
+	if len(cases) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%scase \"$prev\" in\n", indent)
+	for _, c := range cases {
+		fmt.Fprintf(b, "%s  %s%s    return 0;;\n", indent, c, indent)
+	}
+	fmt.Fprintf(b, "%sesac\n", indent)
+	if indent == "  " {
+		b.WriteString("\n")
+	}
+}
+
+// writeZshCompletion writes the completion script for zsh. If autoload, it
+// is written as a file in $fpath, otherwise as a script to be sourced.
+func writeZshCompletion(w io.Writer, root *completionCommand, autoload bool) error {
+	b := &strings.Builder{}
+	if autoload {
+		b.WriteString("#compdef ghq ghq-dev\n")
+		b.WriteString(completionHeader("zsh"))
+		b.WriteString("\n")
+	}
+	writeZshParent(b, "_ghq", root, append(root.globalFlags(true), root.globalFlags(false)...))
+	writeZshCommand(b, root)
+	b.WriteString(`__ghq_repositories () {
+    local -a _repos
+    _repos=( ${(@f)"$(_call_program repositories command ghq __complete repositories ${(q)PREFIX} 2>/dev/null)"} )
+    _describe -t repositories Repositories _repos
+}
+
+__ghq_namespaces () {
+    local -a _namespaces
+    _namespaces=( ${(@f)"$(_call_program namespaces command ghq __complete namespaces ${(q)PREFIX} 2>/dev/null)"} )
+    compadd -S '' -a _namespaces
+}
+`)
+	if f, ok := root.findUpdateFlag(); ok {
+		var cond []string
+		for _, n := range f.names {
+			cond = append(cond, fmt.Sprintf("${words[(I)%s]}", n))
+		}
+		fmt.Fprintf(b, `
+__ghq_updated_repositories () {
+    if (( %s )); then
+        __ghq_repositories
+    fi
+}
+`, strings.Join(cond, " || "))
+	}
+	if autoload {
+		b.WriteString("\n_ghq \"$@\"\n")
+	} else {
+		b.WriteString("\nif (( $+functions[compdef] )); then\n    compdef _ghq ghq\nfi\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// findUpdateFlag returns --update of any command.
+func (c *completionCommand) findUpdateFlag() (completionFlag, bool) {
+	if f, ok := c.updateFlag(); ok {
+		return f, true
+	}
+	for _, sub := range c.commands {
+		if f, ok := sub.findUpdateFlag(); ok {
+			return f, true
+		}
+	}
+	return completionFlag{}, false
+}
+
+func zshFunctionName(c *completionCommand) string {
+	if len(c.path) == 0 {
+		return "_ghq"
+	}
+	return "__ghq_" + strings.ReplaceAll(strings.Join(c.path, "_"), "-", "_")
+}
+
+func zshCommandsFunctionName(c *completionCommand) string {
+	if len(c.path) == 0 {
+		return "__ghq_commands"
+	}
+	return zshFunctionName(c) + "_commands"
+}
+
+// writeZshParent writes the function completing the command which has
+// subcommands.
+func writeZshParent(b *strings.Builder, name string, c *completionCommand, flags []completionFlag) {
+	fmt.Fprintf(b, "function %s () {\n", name)
+	b.WriteString("    local context curcontext=$curcontext state line\n")
+	b.WriteString("    declare -A opt_args\n")
+	b.WriteString("    local ret=1\n\n")
+	b.WriteString("    _arguments -C \\\n")
+	for _, f := range flags {
+		fmt.Fprintf(b, "        %s \\\n", zshFlagSpec(f))
+	}
+	fmt.Fprintf(b, "        '1: :%s' \\\n", zshCommandsFunctionName(c))
+	b.WriteString("        '*:: :->args' \\\n")
+	b.WriteString("        && ret=0\n\n")
+	b.WriteString("    case $state in\n")
+	b.WriteString("        (args)\n")
+	b.WriteString("            case $words[1] in\n")
+	for _, sub := range c.commands {
+		fmt.Fprintf(b, "                (%s)\n", strings.Join(sub.names, "|"))
+		fmt.Fprintf(b, "                    %s && ret=0\n", zshFunctionName(sub))
+		b.WriteString("                    ;;\n")
+	}
+	b.WriteString("            esac\n")
+	b.WriteString("            ;;\n")
+	b.WriteString("    esac\n\n")
+	b.WriteString("    return ret\n")
+	b.WriteString("}\n\n")
+}
+
+// writeZshCommand writes the functions completing the subcommands of c.
+func writeZshCommand(b *strings.Builder, c *completionCommand) {
+	fmt.Fprintf(b, "%s () {\n", zshCommandsFunctionName(c))
+	b.WriteString("    local -a _c\n")
+	b.WriteString("    _c=(\n")
+	for _, sub := range c.commands {
+		for _, n := range sub.names {
+			fmt.Fprintf(b, "        %s\n", zshQuote(strings.ReplaceAll(n, ":", `\:`)+":"+sub.desc))
+		}
+	}
+	b.WriteString("    )\n\n")
+	b.WriteString("    _describe -t commands Commands _c\n")
+	b.WriteString("}\n\n")
+
+	for _, sub := range c.commands {
+		if len(sub.commands) > 0 {
+			writeZshParent(b, zshFunctionName(sub), sub, sub.flags)
+			writeZshCommand(b, sub)
+			continue
+		}
+		fmt.Fprintf(b, "%s () {\n", zshFunctionName(sub))
+		b.WriteString("    _arguments \\\n")
+		for _, f := range sub.flags {
+			fmt.Fprintf(b, "        %s \\\n", zshFlagSpec(f))
+		}
+		switch sub.complete {
+		case completeRepository:
+			b.WriteString("        '*: :__ghq_repositories'\n")
+		case completeUpdatedRepository:
+			b.WriteString("        '*: :__ghq_updated_repositories'\n")
+		case completeNamespace:
+			b.WriteString("        '1: :__ghq_namespaces'\n")
+		case completeDirectory:
+			b.WriteString("        '1: :_directories'\n")
+		case completeFile:
+			b.WriteString("        '*: :_files'\n")
+		case completeCommand:
+			b.WriteString("        '1: :__ghq_commands'\n")
+		default:
+			if words := completionWords(sub.complete); words != nil {
+				fmt.Fprintf(b, "        '1: :(%s)'\n", strings.Join(words, " "))
+			} else {
+				b.WriteString("        '*: :_nothing'\n")
+			}
+		}
+		b.WriteString("}\n\n")
+	}
+}
+
+func zshFlagSpec(f completionFlag) string {
+	spec := "[" + strings.NewReplacer("[", `\[`, "]", `\]`).Replace(f.desc) + "]"
+	if f.takesValue {
+		spec += ":" + f.placeholder
+		switch f.complete {
+		case completeFile:
+			spec += ":_files"
+		case completeDirectory:
+			spec += ":_directories"
+		default:
+			if words := completionWords(f.complete); words != nil {
+				spec += ":(" + strings.Join(words, " ") + ")"
+			}
+		}
+	}
+	var prefix string
+	if f.repeatable {
+		prefix = "*"
+	}
+	if len(f.names) == 1 {
+		return zshQuote(prefix + f.names[0] + spec)
+	}
+	if prefix == "" {
+		prefix = "(" + strings.Join(f.names, " ") + ")"
+	}
+	return zshQuote(prefix) + "{" + strings.Join(f.names, ",") + "}" + zshQuote(spec)
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeFishCompletion writes the completion script for fish.
+func writeFishCompletion(w io.Writer, root *completionCommand) error {
+	b := &strings.Builder{}
+	b.WriteString("function __fish_ghq_needs_subcommand\n")
+	b.WriteString("    set -l cmd (commandline -opc)\n")
+	fmt.Fprintf(b, "    for subcmd in %s\n", strings.Join(root.commandNames(), " "))
+	b.WriteString(`        if contains -- $subcmd $cmd
+            return 1
+        end
+    end
+    return 0
+end
+
+# Remove any previous completion
+complete -c ghq -e
+# Don't suggest files
+complete -c ghq -f
+
+# Global arguments
+`)
+	for _, f := range root.globalFlags(true) {
+		fmt.Fprintf(b, "complete -c ghq%s -d %s\n", fishFlagSpec(f), fishQuote(f.desc))
+	}
+	for _, f := range root.globalFlags(false) {
+		fmt.Fprintf(b, "complete -c ghq -n __fish_ghq_needs_subcommand%s -d %s\n", fishFlagSpec(f), fishQuote(f.desc))
+	}
+	b.WriteString("\n# Global subcommands\n")
+	for _, sub := range root.commands {
+		fmt.Fprintf(b, "complete -c ghq -n __fish_ghq_needs_subcommand -a %s -d %s\n",
+			fishQuote(strings.Join(sub.names, " ")), fishQuote(sub.desc))
+	}
+	b.WriteString("\n# Arguments for subcommands\n")
+	for i, sub := range root.commands {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		writeFishCommand(b, root, sub)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFishCommand(b *strings.Builder, root, c *completionCommand) {
+	cond := " -n " + fishQuote("__fish_seen_subcommand_from "+strings.Join(c.names, " "))
+	if len(c.commands) > 0 {
+		notSeen := " -n " + fishQuote("not __fish_seen_subcommand_from "+strings.Join(c.commandNames(), " "))
+		for _, sub := range c.commands {
+			fmt.Fprintf(b, "complete -c ghq%s%s -a %s -d %s\n", cond, notSeen,
+				fishQuote(strings.Join(sub.names, " ")), fishQuote(sub.desc))
+		}
+		for _, sub := range c.commands {
+			writeFishCommand(b, root, sub)
+		}
+		return
+	}
+	for _, f := range c.flags {
+		fmt.Fprintf(b, "complete -c ghq%s%s -d %s\n", cond, fishFlagSpec(f), fishQuote(f.desc))
+	}
+	switch c.complete {
+	case completeRepository:
+		fmt.Fprintf(b, "complete -c ghq%s -xa '(command ghq __complete repositories (commandline -ct))'\n", cond)
+	case completeUpdatedRepository:
+		if f, ok := c.updateFlag(); ok {
+			fmt.Fprintf(b, "complete -c ghq%s -n %s -xa '(command ghq __complete repositories (commandline -ct))'\n", cond,
+				fishQuote("__fish_seen_argument"+fishFlagSpec(f)))
+		}
+	case completeNamespace:
+		fmt.Fprintf(b, "complete -c ghq%s -xa '(command ghq __complete namespaces (commandline -ct))'\n", cond)
+	case completeDirectory:
+		fmt.Fprintf(b, "complete -c ghq%s -xa '(__fish_complete_directories)'\n", cond)
+	case completeFile:
+		fmt.Fprintf(b, "complete -c ghq%s -F\n", cond)
+	case completeCommand:
+		fmt.Fprintf(b, "complete -c ghq%s -xa %s\n", cond, fishQuote(strings.Join(root.commandNames(), " ")))
+	default:
+		if words := completionWords(c.complete); words != nil {
+			fmt.Fprintf(b, "complete -c ghq%s -xa %s\n", cond, fishQuote(strings.Join(words, " ")))
+		}
+	}
+}
+
+func fishFlagSpec(f completionFlag) string {
+	var spec string
+	for _, n := range f.names {
+		if strings.HasPrefix(n, "--") {
+			spec += " -l " + n[2:]
+		} else {
+			spec += " -s " + n[1:]
+		}
+	}
+	if !f.takesValue {
+		return spec
+	}
+	switch f.complete {
+	case completeFile:
+		return spec + " -r -F"
+	case completeDirectory:
+		return spec + " -xa '(__fish_complete_directories)'"
+	}
+	if words := completionWords(f.complete); words != nil {
+		return spec + " -xa " + fishQuote(strings.Join(words, " "))
+	}
+	return spec + " -x"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
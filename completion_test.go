@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompletionFiles(t *testing.T) {
+	testCases := []struct {
+		shell string
+		file  string
+	}{
+		{"bash", "misc/bash/_ghq"},
+		{"zsh", "misc/zsh/_ghq"},
+		{"fish", "misc/fish/ghq.fish"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.shell, func(t *testing.T) {
+			var runErr error
+			out, _, err := capture(func() {
+				runErr = newApp().Run(context.Background(), []string{"", "shell-init", "--completion", tc.shell})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if runErr != nil {
+				t.Fatal(runErr)
+			}
+			b, err := os.ReadFile(filepath.FromSlash(tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != out {
+				t.Errorf("%s is outdated; run 'make completions' to regenerate it", tc.file)
+			}
+		})
+	}
+}
+
+func TestCompletionDesc(t *testing.T) {
+	testCases := []struct {
+		usage  string
+		expect string
+	}{
+		{"clone or update silently", "Clone or update silently"},
+		{"Specify `branch` name. This flag implies --single-branch on Git", "Specify branch name"},
+		{"Abort getting each repository after `duration` (e.g. 10m)", "Abort getting each repository after duration (e.g. 10m)"},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.usage, func(t *testing.T) {
+			if got := completionDesc(tc.usage); got != tc.expect {
+				t.Errorf("got: %q, expect: %q", got, tc.expect)
+			}
+		})
+	}
+}
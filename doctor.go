@@ -0,0 +1,340 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/Songmu/gitconfig"
+	"github.com/x-motemen/ghq/cmdutil"
+)
+
+// A diagnosis is a problem found by 'ghq doctor' with the command to fix it.
+type diagnosis struct {
+	check   string
+	message string
+	fix     string
+}
+
+// vcsCommands are the commands of the VCS backends, which should be in PATH
+// if any repository of the backend is cloned.
+var vcsCommands = map[*VCSBackend]string{
+	GitBackend:        "git",
+	GitsvnBackend:     "git",
+	SubversionBackend: "svn",
+	MercurialBackend:  "hg",
+	DarcsBackend:      "darcs",
+	PijulBackend:      "pijul",
+	BazaarBackend:     "bzr",
+	FossilBackend:     "fossil",
+}
+
+// diagnoseRoots checks that the roots exist, are writable and do not
+// overlap each other.
+func diagnoseRoots(roots []string) []diagnosis {
+	var ds []diagnosis
+	for _, root := range roots {
+		fi, err := os.Stat(root)
+		switch {
+		case errors.Is(err, syscall.ELOOP):
+			ds = append(ds, diagnosis{"root", fmt.Sprintf("%s is a symlink loop", root),
+				cmdutil.QuoteArgs([]string{"ls", "-l", root})})
+			continue
+		case os.IsNotExist(err):
+			ds = append(ds, diagnosis{"root", fmt.Sprintf("%s does not exist", root),
+				cmdutil.QuoteArgs([]string{"mkdir", "-p", root})})
+			continue
+		case err != nil:
+			ds = append(ds, diagnosis{"root", err.Error(), cmdutil.QuoteArgs([]string{"ls", "-ld", root})})
+			continue
+		case !fi.IsDir():
+			ds = append(ds, diagnosis{"root", fmt.Sprintf("%s is not a directory", root),
+				cmdutil.QuoteArgs([]string{"ls", "-ld", root})})
+			continue
+		}
+		f, err := os.CreateTemp(root, ".ghq-doctor-")
+		if err != nil {
+			ds = append(ds, diagnosis{"root", fmt.Sprintf("%s is not writable", root),
+				cmdutil.QuoteArgs([]string{"chmod", "u+w", root})})
+			continue
+		}
+		f.Close()
+		os.Remove(f.Name())
+	}
+	for _, root := range roots {
+		for _, other := range roots {
+			if root != other && strings.HasPrefix(other, root+string(filepath.Separator)) {
+				ds = append(ds, diagnosis{"root", fmt.Sprintf("%s is inside another root %s", other, root),
+					rootSettingFix(other)})
+			}
+		}
+	}
+	return ds
+}
+
+// rootSettingFix returns the command to remove the root from where it is
+// set, that is, GHQ_ROOT or the config file setting ghq.root or
+// ghq.<url>.root to it.
+func rootSettingFix(root string) string {
+	if envRoot := os.Getenv(envGhqRoot); envRoot != "" {
+		var rest []string
+		for _, r := range filepath.SplitList(envRoot) {
+			if resolveRootPath(r) != root {
+				rest = append(rest, r)
+			}
+		}
+		return cmdutil.QuoteArgs([]string{"export", envGhqRoot + "=" + strings.Join(rest, string(filepath.ListSeparator))})
+	}
+	out, err := gitconfig.Do("--show-origin", "--path", "--get-regexp", `^ghq\.(.+\.)?root$`)
+	if err == nil {
+		// pairs of the origin and "<key>\n<value>"
+		items := strings.Split(out, "\x00")
+		for i := 0; i+1 < len(items); i += 2 {
+			file, ok := strings.CutPrefix(items[i], "file:")
+			if _, value, _ := strings.Cut(items[i+1], "\n"); ok && resolveRootPath(value) == root {
+				return cmdutil.QuoteArgs([]string{"git", "config", "--file", file, "--edit"})
+			}
+		}
+	}
+	return "git config --global --edit"
+}
+
+// resolveRootPath resolves the root as localRepositoryRoots does, to be
+// compared with the ones it returns.
+func resolveRootPath(root string) string {
+	path := filepath.Clean(root)
+	if _, err := os.Stat(path); err == nil {
+		if resolved, err := evalSymlinks(path); err == nil {
+			path = resolved
+		}
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path
+}
+
+// diagnoseRootSettings checks that GHQ_ROOT does not silently override
+// ghq.root configured differently.
+func diagnoseRootSettings() []diagnosis {
+	envRoot := os.Getenv(envGhqRoot)
+	if envRoot == "" {
+		return nil
+	}
+	configured, err := gitconfig.PathAll("ghq.root")
+	if err != nil || len(configured) == 0 {
+		return nil
+	}
+	envRoots := filepath.SplitList(envRoot)
+	for i := range envRoots {
+		envRoots[i] = filepath.Clean(envRoots[i])
+	}
+	for _, root := range configured {
+		if !slices.Contains(envRoots, filepath.Clean(root)) {
+			return []diagnosis{{"root", fmt.Sprintf("%s=%s overrides ghq.root %s",
+				envGhqRoot, envRoot, strings.Join(configured, ", ")), "unset " + envGhqRoot}}
+		}
+	}
+	return nil
+}
+
+// diagnoseRepositories checks the paths, the remote URLs and the worktrees
+// of the local repositories, and the commands of their VCS backends.
+func diagnoseRepositories(repos []*LocalRepository) []diagnosis {
+	var (
+		ds       []diagnosis
+		ignore   = map[string]ignoreRules{}
+		byRemote = map[string][]*LocalRepository{}
+		remotes  []string
+		backends = map[*VCSBackend]int{}
+	)
+	for _, repo := range repos {
+		vcs, _ := repo.VCS()
+		backends[vcs]++
+
+		if r, err := relocationOf(repo); err != nil {
+			ds = append(ds, diagnosis{"path", fmt.Sprintf("%s: %s", repo.FullPath, err),
+				cmdutil.QuoteArgs([]string{"ghq", "relocate", "--dry-run", repo.RelPath})})
+		} else if r != nil {
+			ds = append(ds, diagnosis{"path", fmt.Sprintf("%s should be at %s for its remote URL %s", repo.FullPath, r.destPath, r.oldURL),
+				cmdutil.QuoteArgs([]string{"ghq", "relocate", repo.RelPath})})
+		}
+
+		if key, ok := remoteKey(repo); ok {
+			if _, ok := byRemote[key]; !ok {
+				remotes = append(remotes, key)
+			}
+			byRemote[key] = append(byRemote[key], repo)
+		}
+
+		if vcs == GitBackend {
+			ds = append(ds, diagnoseWorktrees(repo)...)
+		}
+		if !strings.HasSuffix(repo.FullPath, ".git") {
+			rules, ok := ignore[repo.RootPath]
+			if !ok {
+				// the walk has failed on bad rules already
+				rules, _ = loadIgnoreRules(repo.RootPath)
+				ignore[repo.RootPath] = rules
+			}
+			for _, nested := range findNestedRepositories(repo.FullPath, repo.RootPath, rules) {
+				ds = append(ds, diagnosis{"nested", fmt.Sprintf("%s is inside the working tree of %s", nested, repo.FullPath),
+					cmdutil.QuoteArgs([]string{"ghq", "migrate", nested})})
+			}
+		}
+	}
+
+	sort.Strings(remotes)
+	for _, key := range remotes {
+		clones := byRemote[key]
+		if len(clones) < 2 {
+			continue
+		}
+		paths := make([]string, len(clones))
+		for i, repo := range clones {
+			paths[i] = repo.FullPath
+		}
+		// keep the one under the primary root
+		sort.SliceStable(clones, func(i, j int) bool {
+			return clones[i].IsUnderPrimaryRoot() && !clones[j].IsUnderPrimaryRoot()
+		})
+		for _, repo := range clones[1:] {
+			ds = append(ds, diagnosis{"duplicate", fmt.Sprintf("%s is cloned more than once: %s", key, strings.Join(paths, ", ")),
+				removeCommand(repo, clones)})
+		}
+	}
+
+	var commands []string
+	used := map[string]int{}
+	for vcs, n := range backends {
+		if command, ok := vcsCommands[vcs]; ok {
+			if _, ok := used[command]; !ok {
+				commands = append(commands, command)
+			}
+			used[command] += n
+		}
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		if _, err := exec.LookPath(command); err != nil {
+			ds = append(ds, diagnosis{"vcs", fmt.Sprintf("%s is not found in PATH, used by %d repositories", command, used[command]),
+				fmt.Sprintf("install %s and add it to PATH", command)})
+		}
+	}
+	return ds
+}
+
+// diagnoseStrays reports the directories which are not repositories but at
+// the depth of the repositories of their hosts, which the walk skips.
+func diagnoseStrays(roots, strays []string) []diagnosis {
+	sort.Strings(strays)
+	var ds []diagnosis
+	for _, fpath := range strays {
+		for _, root := range roots {
+			rel, err := filepath.Rel(root, fpath)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+			host, _, _ := strings.Cut(rel, "/")
+			ds = append(ds, diagnosis{"depth", fmt.Sprintf("%s is not a repository, but at the depth of the repositories of %s", fpath, host),
+				fmt.Sprintf("echo %s >> %s", cmdutil.QuoteArgs([]string{"/" + rel}), cmdutil.QuoteArgs([]string{filepath.Join(root, ghqIgnoreFile)}))})
+			break
+		}
+	}
+	return ds
+}
+
+// diagnoseLoops reports the symlinks under the roots which are loops, which
+// the walk skips.
+func diagnoseLoops(loops []string) []diagnosis {
+	sort.Strings(loops)
+	ds := make([]diagnosis, 0, len(loops))
+	for _, fpath := range loops {
+		ds = append(ds, diagnosis{"symlink", fmt.Sprintf("%s is a symlink loop", fpath),
+			cmdutil.QuoteArgs([]string{"rm", fpath})})
+	}
+	return ds
+}
+
+// removeCommand returns the command to remove the clone by 'ghq rm', which
+// is told the root of the clone when another one is at the same path under
+// another root, not to remove that one.
+func removeCommand(repo *LocalRepository, clones []*LocalRepository) string {
+	args := []string{"ghq", "rm", filepath.ToSlash(repo.RelPath)}
+	if strings.HasSuffix(repo.FullPath, ".git") {
+		args = slices.Insert(args, 2, "--bare")
+	}
+	cmd := cmdutil.QuoteArgs(args)
+	if slices.ContainsFunc(clones, func(c *LocalRepository) bool {
+		return c != repo && c.RelPath == repo.RelPath
+	}) {
+		cmd = envGhqRoot + "=" + cmdutil.QuoteArgs([]string{repo.RootPath}) + " " + cmd
+	}
+	return cmd
+}
+
+// remoteKey returns the host and the path of the remote URL of the
+// repository, which are the same for the clones of the same remote.
+func remoteKey(repo *LocalRepository) (string, bool) {
+	vcs, _ := repo.VCS()
+	if vcs == nil || vcs.RemoteURL == nil {
+		return "", false
+	}
+	if linked, _, _ := isLinkedGitDir(repo.FullPath); linked {
+		return "", false
+	}
+	remote, err := vcs.RemoteURL(repo.FullPath)
+	if err != nil {
+		return "", false
+	}
+	u, ok := forgeRemoteURL(remote)
+	if !ok {
+		return "", false
+	}
+	return strings.ToLower(u.Hostname() + "/" + strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")), true
+}
+
+// diagnoseWorktrees checks the links between the Git repository and its
+// linked worktrees, or the one of the linked worktree to its repository.
+func diagnoseWorktrees(repo *LocalRepository) []diagnosis {
+	var ds []diagnosis
+	linked, target, err := isLinkedGitDir(repo.FullPath)
+	if err != nil {
+		return nil
+	}
+	if linked {
+		if !isWorktreeGitDir(target) {
+			// submodules are managed by their parent repositories
+			return nil
+		}
+		if _, err := os.Stat(target); err != nil {
+			return []diagnosis{{"worktree", fmt.Sprintf("%s is a worktree of the missing %s", repo.FullPath, target),
+				cmdutil.QuoteArgs([]string{"ghq", "rm", repo.RelPath})}}
+		}
+		content, err := os.ReadFile(filepath.Join(target, "gitdir"))
+		back := filepath.Clean(filepath.FromSlash(strings.TrimSpace(string(content))))
+		if err != nil || back != filepath.Join(repo.FullPath, ".git") {
+			ds = append(ds, diagnosis{"worktree", fmt.Sprintf("%s is not linked back from %s", repo.FullPath, target),
+				cmdutil.QuoteArgs([]string{"git", "-C", repo.FullPath, "worktree", "repair"})})
+		}
+		return ds
+	}
+	wtPaths, err := listLinkedWorktreePaths(repo.FullPath)
+	if err != nil {
+		return nil
+	}
+	for _, wt := range wtPaths {
+		if _, err := os.Stat(filepath.Join(wt, ".git")); os.IsNotExist(err) {
+			ds = append(ds, diagnosis{"worktree", fmt.Sprintf("%s has a worktree at the missing %s", repo.FullPath, wt),
+				cmdutil.QuoteArgs([]string{"git", "-C", repo.FullPath, "worktree", "prune"})})
+		}
+	}
+	return ds
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Songmu/gitconfig"
+)
+
+func TestDiagnoseRoots(t *testing.T) {
+	tmp := newTempDir(t)
+	ok := filepath.Join(tmp, "ok")
+	inner := filepath.Join(ok, "inner")
+	file := filepath.Join(tmp, "file")
+	loop := filepath.Join(tmp, "loop")
+	missing := filepath.Join(tmp, "missing")
+	for _, dir := range []string{ok, inner} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(loop, loop); err != nil {
+		t.Fatal(err)
+	}
+
+	setEnv(t, envGhqRoot, "")
+	t.Cleanup(gitconfig.WithConfig(t, ""))
+	var got []string
+	for _, d := range diagnoseRoots([]string{ok, inner, file, loop, missing}) {
+		got = append(got, d.message+" / "+d.fix)
+	}
+	expect := []string{
+		file + " is not a directory / ls -ld " + file,
+		loop + " is a symlink loop / ls -l " + loop,
+		missing + " does not exist / mkdir -p " + missing,
+		inner + " is inside another root " + ok + " / git config --global --edit",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %q, expect: %q", got, expect)
+	}
+	if entries, _ := os.ReadDir(ok); len(entries) != 1 {
+		t.Errorf("the file to check the root is writable should be removed, but: %v", entries)
+	}
+
+	// the fix is where the inner root is set
+	config := filepath.Join(tmp, "gitconfig")
+	err := os.WriteFile(config, []byte("[ghq]\n\troot = "+ok+"\n[ghq \"https://example.com/\"]\n\troot = "+inner+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setEnv(t, "GIT_CONFIG", config)
+	ds := diagnoseRoots([]string{ok, inner})
+	if expect := "git config --file " + config + " --edit"; len(ds) != 1 || ds[0].fix != expect {
+		t.Errorf("the config file setting the root should be edited, but: %v", ds)
+	}
+	setEnv(t, envGhqRoot, ok+string(filepath.ListSeparator)+inner)
+	ds = diagnoseRoots([]string{ok, inner})
+	if expect := "export " + envGhqRoot + "=" + ok; len(ds) != 1 || ds[0].fix != expect {
+		t.Errorf("the root should be removed from %s, but: %v", envGhqRoot, ds)
+	}
+}
+
+func TestDiagnoseRootSettings(t *testing.T) {
+	t.Cleanup(gitconfig.WithConfig(t, "[ghq]\n\troot = /path/to/ghq\n"))
+	setEnv(t, envGhqRoot, "/path/to/ghq")
+	if ds := diagnoseRootSettings(); len(ds) != 0 {
+		t.Errorf("the same root should not be reported, but: %v", ds)
+	}
+	setEnv(t, envGhqRoot, "/path/to/other")
+	ds := diagnoseRootSettings()
+	if len(ds) != 1 || ds[0].fix != "unset GHQ_ROOT" {
+		t.Errorf("GHQ_ROOT overriding ghq.root should be reported, but: %v", ds)
+	}
+}
+
+func TestDoDoctor(t *testing.T) {
+	origHome, origRoots := _home, _localRepositoryRoots
+	t.Cleanup(func() { _home, _localRepositoryRoots = origHome, origRoots })
+	primary, secondary := newTempDir(t), newTempDir(t)
+	_home = ""
+	homeOnce = &sync.Once{}
+	setEnv(t, envGhqRoot, primary+string(filepath.ListSeparator)+secondary)
+	_localRepositoryRoots = nil
+	localRepoOnce = &sync.Once{}
+	t.Cleanup(gitconfig.WithConfig(t, `[ghq]
+	ignore = node_modules/
+[ghq "https://github.com/"]
+	depth = 3
+`))
+
+	moved := initGitRepo(t, filepath.Join(primary, "github.com", "alice", "old"), "https://github.com/bob/new.git")
+	initGitRepo(t, filepath.Join(primary, "github.com", "alice", "dup"), "https://github.com/alice/dup.git")
+	dup := initGitRepo(t, filepath.Join(secondary, "github.com", "alice", "dup"), "git@github.com:alice/dup.git")
+	app := initGitRepo(t, filepath.Join(primary, "github.com", "alice", "app"), "https://github.com/alice/app.git")
+	nested := initGitRepo(t, filepath.Join(app, "vendor", "lib"), "https://github.com/carol/lib.git")
+	initGitRepo(t, filepath.Join(app, "node_modules", "dep"), "https://github.com/carol/dep.git")
+	wt := filepath.Join(t.TempDir(), "wt")
+	addWorktree(t, app, wt, "feature")
+	if err := os.RemoveAll(wt); err != nil {
+		t.Fatal(err)
+	}
+	loop := filepath.Join(secondary, "github.com", "alice", "loop")
+	if err := os.Symlink(loop, loop); err != nil {
+		t.Fatal(err)
+	}
+	stray := filepath.Join(primary, "github.com", "alice", "notes")
+	if err := os.MkdirAll(filepath.Join(stray, "lib", ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var runErr error
+	out, _, err := capture(func() {
+		runErr = newApp().Run(context.Background(), []string{"", "doctor"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runErr == nil || !strings.Contains(runErr.Error(), "6 problems found in 2 roots and 4 repositories") {
+		t.Errorf("error should be returned, but: %v", runErr)
+	}
+	for _, s := range []string{
+		"path: " + moved + " should be at " + filepath.Join(primary, "github.com", "bob", "new") + " for its remote URL https://github.com/bob/new.git\n    fix: ghq relocate github.com/alice/old\n",
+		"duplicate: github.com/alice/dup is cloned more than once: ",
+		"    fix: GHQ_ROOT=" + secondary + " ghq rm github.com/alice/dup\n",
+		"nested: " + nested + " is inside the working tree of " + app + "\n    fix: ghq migrate " + nested + "\n",
+		"depth: " + stray + " is not a repository, but at the depth of the repositories of github.com\n    fix: echo /github.com/alice/notes >> " + filepath.Join(primary, ".ghqignore") + "\n",
+		"symlink: " + loop + " is a symlink loop\n    fix: rm " + loop + "\n",
+		"worktree: " + app + " has a worktree at the missing " + wt + "\n    fix: git -C " + app + " worktree prune\n",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("out should contain %q, but: %q", s, out)
+		}
+	}
+	if strings.Contains(out, "node_modules") {
+		t.Errorf("the ignored repository should not be reported, but: %q", out)
+	}
+
+	// the fix removes the duplicate, not the one kept
+	setEnv(t, envGhqRoot, secondary)
+	_localRepositoryRoots = nil
+	localRepoOnce = &sync.Once{}
+	if _, _, err := captureWithInput([]string{"y"}, func() {
+		if err := newApp().Run(context.Background(), []string{"", "rm", "github.com/alice/dup"}); err != nil {
+			t.Errorf("error should be nil, but: %s", err)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dup); !os.IsNotExist(err) {
+		t.Errorf("the duplicate should be removed, but: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(primary, "github.com", "alice", "dup")); err != nil {
+		t.Errorf("the one under the primary root should be kept, but: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+// Package filelock provides advisory file locks shared between processes.
+package filelock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked is returned when the lock is held by another process and the
+// caller does not wait for it.
+var ErrLocked = errors.New("locked by another process")
+
+// PollInterval is the interval to retry acquiring a lock held by another process.
+var PollInterval = 100 * time.Millisecond
+
+// A File is an acquired lock.
+type File struct {
+	f *os.File
+}
+
+// Lock acquires the exclusive lock of the file name, creating it if needed.
+// If the lock is held by another process, Lock returns ErrLocked unless wait
+// is true, in which case it retries until the lock is acquired or ctx is done.
+// Locks are advisory, and released when the process exits.
+func Lock(ctx context.Context, name string, wait bool) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, &os.PathError{Op: "lock", Path: name, Err: err}
+		}
+		if ok {
+			return &File{f: f}, nil
+		}
+		if !wait {
+			f.Close()
+			return nil, ErrLocked
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+// Unlock releases the lock.
+func (l *File) Unlock() error {
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package filelock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "locks", "repo.lock")
+	ctx := context.Background()
+
+	l, err := Lock(ctx, name, false)
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if _, err := Lock(ctx, name, false); !errors.Is(err, ErrLocked) {
+		t.Errorf("error should be ErrLocked but: %v", err)
+	}
+
+	ctx2, cancel := context.WithTimeout(ctx, 3*PollInterval)
+	defer cancel()
+	if _, err := Lock(ctx2, name, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error should be deadline exceeded but: %v", err)
+	}
+
+	time.AfterFunc(2*PollInterval, func() { l.Unlock() })
+	l, err = Lock(ctx, name, true)
+	if err != nil {
+		t.Fatalf("waiting lock should be acquired after unlock, but: %s", err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Errorf("error should be nil but: %s", err)
+	}
+}
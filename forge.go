@@ -0,0 +1,701 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Songmu/gitconfig"
+	"github.com/x-motemen/ghq/cmdutil"
+	"github.com/x-motemen/ghq/logger"
+)
+
+// forgeTimeout bounds each request to the API of a forge.
+const forgeTimeout = 30 * time.Second
+
+// The kinds of forges whose repositories can be listed by
+// 'ghq get --org' and 'ghq get --user'.
+const (
+	forgeGitHub    = "github"
+	forgeGitLab    = "gitlab"
+	forgeGitea     = "gitea"
+	forgeForgejo   = "forgejo"
+	forgeBitbucket = "bitbucket"
+)
+
+// knownForges are the forges of the well-known hosts. The others are
+// configured by ghq.<url>.forge.
+var knownForges = map[string]string{
+	"github.com":    forgeGitHub,
+	"gitlab.com":    forgeGitLab,
+	"gitea.com":     forgeGitea,
+	"codeberg.org":  forgeForgejo,
+	"bitbucket.org": forgeBitbucket,
+}
+
+// forgeVisibilities are the values of 'ghq get --visibility'.
+var forgeVisibilities = []string{"public", "private", "internal"}
+
+// A forgeRepository is a repository listed by the API of a forge.
+type forgeRepository struct {
+	Name       string // e.g. "my-org/repo", or "group/subgroup/repo" on GitLab
+	CloneURL   string
+	SSHURL     string
+	Archived   bool
+	Fork       bool
+	Visibility string // public, private or internal
+	Language   string
+	Topics     []string
+
+	id int // of the project on GitLab
+}
+
+// A forge lists the repositories of a user or an organization (a group on
+// GitLab, a workspace on Bitbucket) through its API.
+type forge interface {
+	repositories(ctx context.Context, owner string, isUser bool) ([]forgeRepository, error)
+	// repository returns the repository at the path, e.g. "my-org/repo",
+	// with its current name if it has been renamed or transferred.
+	repository(ctx context.Context, path string) (forgeRepository, error)
+	// authenticated reports whether the requests are made with a credential,
+	// without which the private repositories are neither listed nor found.
+	authenticated(ctx context.Context) bool
+}
+
+// A languageDetector looks up the language of a repository, for the forges
+// which do not list it with the repositories.
+type languageDetector interface {
+	language(ctx context.Context, repo forgeRepository) (string, error)
+}
+
+// A forgeFilter selects the repositories to get from the listed ones.
+type forgeFilter struct {
+	archived, forks bool
+	topics          []string
+	language        string
+	visibility      string
+}
+
+// forgeOwner splits the argument of --org and --user, such as
+// "github.com/my-org" or "https://gitlab.com/group/subgroup", into the host
+// and the owner.
+func forgeOwner(arg string) (host, owner string, err error) {
+	if u, err := url.Parse(arg); err == nil && u.Host != "" {
+		arg = u.Host + u.Path
+	}
+	host, owner, ok := strings.Cut(strings.Trim(arg, "/"), "/")
+	if !ok || host == "" || owner == "" {
+		return "", "", fmt.Errorf("%q should be in the form of <host>/<owner>", arg)
+	}
+	return host, owner, nil
+}
+
+// errUnknownForge is returned by newForge for the host of an unknown forge.
+var errUnknownForge = errors.New("unknown forge")
+
+// newForge returns the forge of the host. Its kind and the URL of its API
+// can be configured by gitconfig:
+//
+//	[ghq "https://git.example.com/"]
+//	forge = gitea
+//	forgeApi = https://git.example.com/api/v1
+func newForge(host string) (forge, error) {
+	u := "https://" + host + "/"
+	kind, err := gitconfig.Do("--get-urlmatch", "ghq.forge", u)
+	if err != nil && !gitconfig.IsNotFound(err) {
+		return nil, err
+	}
+	if kind == "" {
+		kind = knownForges[host]
+	}
+	if kind == "" && isGitLabHost(&url.URL{Scheme: "https", Host: host, Path: "/"}) {
+		kind = forgeGitLab
+	}
+	api, err := gitconfig.Do("--get-urlmatch", "ghq.forgeApi", u)
+	if err != nil && !gitconfig.IsNotFound(err) {
+		return nil, err
+	}
+	client := &forgeClient{host: host, kind: kind, api: strings.TrimSuffix(api, "/")}
+
+	switch kind {
+	case forgeGitHub:
+		if client.api == "" {
+			client.api = "https://" + host + "/api/v3"
+			if host == "github.com" {
+				client.api = "https://api.github.com"
+			}
+		}
+		return &githubForge{client}, nil
+	case forgeGitLab:
+		if client.api == "" {
+			client.api = "https://" + host + "/api/v4"
+		}
+		return &gitlabForge{client}, nil
+	case forgeGitea, forgeForgejo:
+		if client.api == "" {
+			client.api = "https://" + host + "/api/v1"
+		}
+		return &giteaForge{client}, nil
+	case forgeBitbucket:
+		if client.api == "" {
+			client.api = "https://api.bitbucket.org/2.0"
+		}
+		return &bitbucketForge{client}, nil
+	case "":
+		return nil, fmt.Errorf("%w of %s; set ghq.https://%s/.forge to github, gitlab, gitea, forgejo or bitbucket", errUnknownForge, host, host)
+	}
+	return nil, fmt.Errorf("unsupported forge %q of %s", kind, host)
+}
+
+// forgeTargets lists the repositories of the owner, such as
+// "github.com/my-org", which pass the filter, and returns the URLs to get.
+func forgeTargets(ctx context.Context, arg string, isUser, ssh bool, filter *forgeFilter) ([]string, error) {
+	var targets []string
+	_, err := listForge(ctx, arg, isUser, filter, func(repo forgeRepository, ok bool) {
+		if ok {
+			targets = append(targets, repo.target(ssh))
+		}
+	})
+	return targets, err
+}
+
+// listForge lists the repositories of the owner, such as "github.com/my-org",
+// and calls fn for each with whether it passes the filter. It returns the
+// forge listed.
+func listForge(ctx context.Context, arg string, isUser bool, filter *forgeFilter, fn func(repo forgeRepository, ok bool)) (forge, error) {
+	host, owner, err := forgeOwner(arg)
+	if err != nil {
+		return nil, err
+	}
+	f, err := newForge(host)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f.(*bitbucketForge); ok && len(filter.topics) > 0 {
+		return nil, fmt.Errorf("bitbucket has no topics of repositories")
+	}
+	repos, err := f.repositories(ctx, owner, isUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the repositories of %s: %w", arg, err)
+	}
+	var n int
+	for _, repo := range repos {
+		ok, err := filter.match(ctx, f, repo)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			n++
+		}
+		fn(repo, ok)
+	}
+	logger.Log("found", fmt.Sprintf("%d of %d repositories of %s", n, len(repos), arg))
+	return f, nil
+}
+
+// target returns the URL to get the repository.
+func (repo forgeRepository) target(ssh bool) string {
+	if ssh && repo.SSHURL != "" {
+		return repo.SSHURL
+	}
+	return repo.CloneURL
+}
+
+// match reports whether the repository passes the filter.
+func (ff *forgeFilter) match(ctx context.Context, f forge, repo forgeRepository) (bool, error) {
+	switch {
+	case repo.Archived && !ff.archived,
+		repo.Fork && !ff.forks,
+		ff.visibility != "" && repo.Visibility != ff.visibility:
+		return false, nil
+	}
+	for _, topic := range ff.topics {
+		if !slices.ContainsFunc(repo.Topics, func(t string) bool { return strings.EqualFold(t, topic) }) {
+			return false, nil
+		}
+	}
+	if ff.language == "" {
+		return true, nil
+	}
+	lang := repo.Language
+	if d, ok := f.(languageDetector); ok && lang == "" {
+		var err error
+		if lang, err = d.language(ctx, repo); err != nil {
+			return false, fmt.Errorf("failed to detect the language of %s: %w", repo.Name, err)
+		}
+	}
+	return strings.EqualFold(lang, ff.language), nil
+}
+
+// A forgeClient calls the API of a forge.
+type forgeClient struct {
+	host, kind, api string
+
+	credential *forgeCredential
+}
+
+// A forgeCredential authenticates the requests to a forge.
+type forgeCredential struct {
+	username, secret string
+}
+
+// forgeTokenEnvs are the environment variables of the tokens for the
+// forges, in the order of precedence.
+var forgeTokenEnvs = map[string][]string{
+	forgeGitHub:    {"GH_TOKEN", "GITHUB_TOKEN"},
+	forgeGitLab:    {"GITLAB_TOKEN"},
+	forgeGitea:     {"GITEA_TOKEN"},
+	forgeForgejo:   {"FORGEJO_TOKEN", "GITEA_TOKEN"},
+	forgeBitbucket: {"BITBUCKET_TOKEN"},
+}
+
+// lookupCredential returns the token from the environment, or the
+// credential for the host from 'git credential fill', or nil if neither is
+// available so that only the public repositories are listed.
+func (c *forgeClient) lookupCredential(ctx context.Context) *forgeCredential {
+	envs := forgeTokenEnvs[c.kind]
+	if c.kind == forgeGitHub && c.host != "github.com" {
+		envs = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, env := range envs {
+		if token := os.Getenv(env); token != "" {
+			return &forgeCredential{secret: token}
+		}
+	}
+
+	cmd := cmdutil.Command(ctx, "git", "credential", "fill")
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=" + c.host + "\n\n")
+	cmd.Stderr = io.Discard
+	// never wait for credentials to be typed in
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	out, err := cmd.Output()
+	if err != nil {
+		logger.Log("debug", fmt.Sprintf("no credential for %s: %s", c.host, err))
+		return nil
+	}
+	cred := &forgeCredential{}
+	for line := range strings.Lines(string(out)) {
+		k, v, _ := strings.Cut(strings.TrimRight(line, "\r\n"), "=")
+		switch k {
+		case "username":
+			cred.username = v
+		case "password":
+			cred.secret = v
+		}
+	}
+	if cred.secret == "" {
+		return nil
+	}
+	return cred
+}
+
+// loadCredential returns the credential looked up on the first call, which
+// is empty if none is available.
+func (c *forgeClient) loadCredential(ctx context.Context) *forgeCredential {
+	if c.credential == nil {
+		if c.credential = c.lookupCredential(ctx); c.credential == nil {
+			c.credential = &forgeCredential{}
+		}
+	}
+	return c.credential
+}
+
+func (c *forgeClient) authenticated(ctx context.Context) bool {
+	return c.loadCredential(ctx).secret != ""
+}
+
+// inAPI reports whether the URL is on the origin of the API, to which the
+// credential may be sent.
+func (c *forgeClient) inAPI(u *url.URL) bool {
+	api, err := url.Parse(c.api)
+	return err == nil && strings.EqualFold(u.Scheme, api.Scheme) && strings.EqualFold(u.Host, api.Host)
+}
+
+// nextPage checks the URL of the next page given by the API, refusing the
+// one outside of the API not to follow a response to another host.
+func (c *forgeClient) nextPage(next string) (string, error) {
+	if next == "" {
+		return "", nil
+	}
+	u, err := url.Parse(next)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL of the next page: %w", err)
+	}
+	if !c.inAPI(u) {
+		return "", fmt.Errorf("refusing to follow the next page %s outside of %s", next, c.api)
+	}
+	return next, nil
+}
+
+// get requests the URL of the API and decodes the response into v. It
+// returns the URL of the next page, if any, from the Link header.
+// The credential is sent only to the origin of the API.
+func (c *forgeClient) get(ctx context.Context, u string, v any) (string, error) {
+	cred := c.loadCredential(ctx)
+	ctx, cancel := context.WithTimeout(ctx, forgeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", fmt.Sprintf("ghq/%s (+https://github.com/motemen/ghq)", version))
+	switch {
+	case cred.secret == "" || !c.inAPI(req.URL):
+	case c.kind == forgeBitbucket && cred.username != "":
+		// app passwords of Bitbucket are used with the username
+		req.SetBasicAuth(cred.username, cred.secret)
+	default:
+		req.Header.Set("Authorization", "Bearer "+cred.secret)
+	}
+	logger.Log("debug", "GET "+u)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &forgeHTTPError{url: u, statusCode: resp.StatusCode, status: resp.Status}
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return "", fmt.Errorf("%s: %w", u, err)
+	}
+	return nextLink(resp.Header.Get("Link")), nil
+}
+
+// A forgeHTTPError is the error response from the API of a forge.
+type forgeHTTPError struct {
+	url        string
+	statusCode int
+	status     string
+}
+
+func (e *forgeHTTPError) Error() string {
+	msg := e.status
+	if e.statusCode == http.StatusNotFound || e.statusCode == http.StatusUnauthorized {
+		msg += " (it may not exist or may need a token)"
+	}
+	return fmt.Sprintf("%s: %s", e.url, msg)
+}
+
+// isForgeNotFound reports whether the error is 404 from the API of a forge.
+func isForgeNotFound(err error) bool {
+	var e *forgeHTTPError
+	return errors.As(err, &e) && e.statusCode == http.StatusNotFound
+}
+
+// nextLink returns the URL of rel="next" in the Link header.
+func nextLink(link string) string {
+	for l := range strings.SplitSeq(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(l), ";")
+		if !ok {
+			continue
+		}
+		for p := range strings.SplitSeq(params, ";") {
+			if k, v, _ := strings.Cut(strings.TrimSpace(p), "="); k == "rel" && strings.Trim(v, `"`) == "next" {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// listPages gets all the pages from the URL, calling add for each.
+func listPages[T any](ctx context.Context, c *forgeClient, u string, add func(T)) error {
+	for u != "" {
+		var page []T
+		next, err := c.get(ctx, u, &page)
+		if err != nil {
+			return err
+		}
+		for _, v := range page {
+			add(v)
+		}
+		if u, err = c.nextPage(next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type githubForge struct {
+	*forgeClient
+}
+
+type githubRepository struct {
+	FullName   string   `json:"full_name"`
+	CloneURL   string   `json:"clone_url"`
+	SSHURL     string   `json:"ssh_url"`
+	Archived   bool     `json:"archived"`
+	Fork       bool     `json:"fork"`
+	Private    bool     `json:"private"`
+	Visibility string   `json:"visibility"`
+	Language   string   `json:"language"`
+	Topics     []string `json:"topics"`
+}
+
+func (r githubRepository) forgeRepository() forgeRepository {
+	visibility := r.Visibility
+	if visibility == "" {
+		visibility = privateOrPublic(r.Private)
+	}
+	return forgeRepository{
+		Name:       r.FullName,
+		CloneURL:   r.CloneURL,
+		SSHURL:     r.SSHURL,
+		Archived:   r.Archived,
+		Fork:       r.Fork,
+		Visibility: visibility,
+		Language:   r.Language,
+		Topics:     r.Topics,
+	}
+}
+
+func (f *githubForge) repositories(ctx context.Context, owner string, isUser bool) ([]forgeRepository, error) {
+	u := fmt.Sprintf("%s/orgs/%s/repos?type=all&per_page=100", f.api, url.PathEscape(owner))
+	switch {
+	case isUser && strings.EqualFold(f.login(ctx), owner):
+		// the repositories of a user list only the public ones, even for
+		// the user themselves
+		u = fmt.Sprintf("%s/user/repos?affiliation=owner&per_page=100", f.api)
+	case isUser:
+		u = fmt.Sprintf("%s/users/%s/repos?type=owner&per_page=100", f.api, url.PathEscape(owner))
+	}
+	var repos []forgeRepository
+	err := listPages(ctx, f.forgeClient, u, func(r githubRepository) {
+		repos = append(repos, r.forgeRepository())
+	})
+	return repos, err
+}
+
+// login returns the login of the user authenticated by the credential, or
+// "" if not authenticated.
+func (f *githubForge) login(ctx context.Context) string {
+	if !f.authenticated(ctx) {
+		return ""
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if _, err := f.get(ctx, f.api+"/user", &user); err != nil {
+		logger.Log("debug", fmt.Sprintf("failed to get the authenticated user: %s", err))
+		return ""
+	}
+	return user.Login
+}
+
+// repository gets the repository, following the redirection of GitHub from
+// the old name of a renamed or transferred one.
+func (f *githubForge) repository(ctx context.Context, path string) (forgeRepository, error) {
+	var r githubRepository
+	_, err := f.get(ctx, fmt.Sprintf("%s/repos/%s", f.api, path), &r)
+	return r.forgeRepository(), err
+}
+
+type gitlabForge struct {
+	*forgeClient
+}
+
+type gitlabProject struct {
+	ID                int            `json:"id"`
+	PathWithNamespace string         `json:"path_with_namespace"`
+	HTTPURLToRepo     string         `json:"http_url_to_repo"`
+	SSHURLToRepo      string         `json:"ssh_url_to_repo"`
+	Archived          bool           `json:"archived"`
+	ForkedFromProject map[string]any `json:"forked_from_project"`
+	Visibility        string         `json:"visibility"`
+	Topics            []string       `json:"topics"`
+}
+
+func (p gitlabProject) forgeRepository() forgeRepository {
+	return forgeRepository{
+		Name:       p.PathWithNamespace,
+		CloneURL:   p.HTTPURLToRepo,
+		SSHURL:     p.SSHURLToRepo,
+		Archived:   p.Archived,
+		Fork:       p.ForkedFromProject != nil,
+		Visibility: p.Visibility,
+		Topics:     p.Topics,
+		id:         p.ID,
+	}
+}
+
+func (f *gitlabForge) repositories(ctx context.Context, owner string, isUser bool) ([]forgeRepository, error) {
+	u := fmt.Sprintf("%s/groups/%s/projects?include_subgroups=true&per_page=100", f.api, url.PathEscape(owner))
+	if isUser {
+		u = fmt.Sprintf("%s/users/%s/projects?per_page=100", f.api, url.PathEscape(owner))
+	}
+	var repos []forgeRepository
+	err := listPages(ctx, f.forgeClient, u, func(p gitlabProject) {
+		repos = append(repos, p.forgeRepository())
+	})
+	return repos, err
+}
+
+// repository gets the project, which GitLab finds also by its old path
+// after it is renamed or transferred.
+func (f *gitlabForge) repository(ctx context.Context, path string) (forgeRepository, error) {
+	var p gitlabProject
+	_, err := f.get(ctx, fmt.Sprintf("%s/projects/%s", f.api, url.PathEscape(path)), &p)
+	return p.forgeRepository(), err
+}
+
+// language returns the main language of the project, since GitLab does not
+// list the languages with the projects.
+func (f *gitlabForge) language(ctx context.Context, repo forgeRepository) (string, error) {
+	var languages map[string]float64
+	if _, err := f.get(ctx, fmt.Sprintf("%s/projects/%d/languages", f.api, repo.id), &languages); err != nil {
+		return "", err
+	}
+	var (
+		lang  string
+		ratio float64
+	)
+	for l, r := range languages {
+		if r > ratio || r == ratio && l < lang {
+			lang, ratio = l, r
+		}
+	}
+	return lang, nil
+}
+
+// giteaForge is for Gitea and Forgejo, which share the API.
+type giteaForge struct {
+	*forgeClient
+}
+
+type giteaRepository struct {
+	FullName string   `json:"full_name"`
+	CloneURL string   `json:"clone_url"`
+	SSHURL   string   `json:"ssh_url"`
+	Archived bool     `json:"archived"`
+	Fork     bool     `json:"fork"`
+	Private  bool     `json:"private"`
+	Internal bool     `json:"internal"`
+	Language string   `json:"language"`
+	Topics   []string `json:"topics"`
+}
+
+func (r giteaRepository) forgeRepository() forgeRepository {
+	visibility := privateOrPublic(r.Private)
+	if r.Internal {
+		visibility = "internal"
+	}
+	return forgeRepository{
+		Name:       r.FullName,
+		CloneURL:   r.CloneURL,
+		SSHURL:     r.SSHURL,
+		Archived:   r.Archived,
+		Fork:       r.Fork,
+		Visibility: visibility,
+		Language:   r.Language,
+		Topics:     r.Topics,
+	}
+}
+
+func (f *giteaForge) repositories(ctx context.Context, owner string, isUser bool) ([]forgeRepository, error) {
+	u := fmt.Sprintf("%s/orgs/%s/repos?limit=50", f.api, url.PathEscape(owner))
+	if isUser {
+		u = fmt.Sprintf("%s/users/%s/repos?limit=50", f.api, url.PathEscape(owner))
+	}
+	var repos []forgeRepository
+	err := listPages(ctx, f.forgeClient, u, func(r giteaRepository) {
+		repos = append(repos, r.forgeRepository())
+	})
+	return repos, err
+}
+
+func (f *giteaForge) repository(ctx context.Context, path string) (forgeRepository, error) {
+	var r giteaRepository
+	_, err := f.get(ctx, fmt.Sprintf("%s/repos/%s", f.api, path), &r)
+	return r.forgeRepository(), err
+}
+
+// bitbucketForge is for Bitbucket Cloud, on which both users and teams own
+// repositories as workspaces.
+type bitbucketForge struct {
+	*forgeClient
+}
+
+type bitbucketRepository struct {
+	FullName  string         `json:"full_name"`
+	IsPrivate bool           `json:"is_private"`
+	Language  string         `json:"language"`
+	Parent    map[string]any `json:"parent"`
+	Links     struct {
+		Clone []struct {
+			Name string `json:"name"`
+			Href string `json:"href"`
+		} `json:"clone"`
+	} `json:"links"`
+}
+
+func (r bitbucketRepository) forgeRepository() forgeRepository {
+	repo := forgeRepository{
+		Name:       r.FullName,
+		Fork:       r.Parent != nil,
+		Visibility: privateOrPublic(r.IsPrivate),
+		Language:   r.Language,
+	}
+	for _, l := range r.Links.Clone {
+		switch l.Name {
+		case "https":
+			// the URL has the username of the authenticated user
+			if cu, err := url.Parse(l.Href); err == nil {
+				cu.User = nil
+				repo.CloneURL = cu.String()
+			}
+		case "ssh":
+			repo.SSHURL = l.Href
+		}
+	}
+	return repo
+}
+
+func (f *bitbucketForge) repositories(ctx context.Context, owner string, _ bool) ([]forgeRepository, error) {
+	var repos []forgeRepository
+	u := fmt.Sprintf("%s/repositories/%s?pagelen=100", f.api, url.PathEscape(owner))
+	for u != "" {
+		var page struct {
+			Values []bitbucketRepository `json:"values"`
+			Next   string                `json:"next"`
+		}
+		if _, err := f.get(ctx, u, &page); err != nil {
+			return nil, err
+		}
+		for _, r := range page.Values {
+			repos = append(repos, r.forgeRepository())
+		}
+		var err error
+		if u, err = f.nextPage(page.Next); err != nil {
+			return nil, err
+		}
+	}
+	return repos, nil
+}
+
+func (f *bitbucketForge) repository(ctx context.Context, path string) (forgeRepository, error) {
+	var r bitbucketRepository
+	_, err := f.get(ctx, fmt.Sprintf("%s/repositories/%s", f.api, path), &r)
+	return r.forgeRepository(), err
+}
+
+func privateOrPublic(private bool) string {
+	if private {
+		return "private"
+	}
+	return "public"
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Songmu/gitconfig"
+)
+
+// newFakeForge serves the API of a forge at the host git.example.com by
+// the responses keyed by "<path>?<query>", checking the token. A response
+// may start with a line "next:<path or URL>" of the next page.
+func newFakeForge(t *testing.T, kind string, pages map[string]string) {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer s3cret" {
+			http.Error(w, "unauthorized: "+got, http.StatusUnauthorized)
+			return
+		}
+		body, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		body = strings.ReplaceAll(body, "{{api}}", srv.URL)
+		if next, rest, ok := strings.Cut(body, "\n"); ok && strings.HasPrefix(next, "next:") {
+			next = strings.TrimPrefix(next, "next:")
+			if !strings.Contains(next, "://") {
+				next = srv.URL + next
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next", <%s/last>; rel="last"`, next, srv.URL))
+			body = rest
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(gitconfig.WithConfig(t, fmt.Sprintf(`
+[ghq "https://git.example.com/"]
+	forge = %s
+	forgeApi = %s/
+`, kind, srv.URL)))
+	for _, env := range []string{"GH_ENTERPRISE_TOKEN", "GITLAB_TOKEN", "GITEA_TOKEN", "FORGEJO_TOKEN", "BITBUCKET_TOKEN"} {
+		setEnv(t, env, "s3cret")
+	}
+}
+
+func TestForgeTargets(t *testing.T) {
+	testCases := []struct {
+		name   string
+		kind   string
+		pages  map[string]string
+		arg    string
+		isUser bool
+		ssh    bool
+		filter forgeFilter
+		expect []string
+	}{{
+		name: "github org",
+		kind: "github",
+		pages: map[string]string{
+			"/orgs/my-org/repos?type=all&per_page=100": `next:/orgs/my-org/repos?type=all&per_page=100&page=2
+[{"full_name": "my-org/a", "clone_url": "https://git.example.com/my-org/a.git", "visibility": "public", "language": "Go"},
+ {"full_name": "my-org/old", "clone_url": "https://git.example.com/my-org/old.git", "archived": true}]`,
+			"/orgs/my-org/repos?type=all&per_page=100&page=2": `
+[{"full_name": "my-org/fork", "clone_url": "https://git.example.com/my-org/fork.git", "fork": true},
+ {"full_name": "my-org/b", "clone_url": "https://git.example.com/my-org/b.git", "private": true}]`,
+		},
+		arg:    "git.example.com/my-org",
+		expect: []string{"https://git.example.com/my-org/a.git", "https://git.example.com/my-org/b.git"},
+	}, {
+		name: "github user with ssh, archived and forks",
+		kind: "github",
+		pages: map[string]string{
+			"/user": `{"login": "someone"}`,
+			"/users/me/repos?type=owner&per_page=100": `
+[{"full_name": "me/a", "ssh_url": "git@git.example.com:me/a.git", "archived": true},
+ {"full_name": "me/b", "ssh_url": "git@git.example.com:me/b.git", "fork": true}]`,
+		},
+		arg:    "https://git.example.com/me",
+		isUser: true,
+		ssh:    true,
+		filter: forgeFilter{archived: true, forks: true},
+		expect: []string{"git@git.example.com:me/a.git", "git@git.example.com:me/b.git"},
+	}, {
+		name: "github authenticated user",
+		kind: "github",
+		pages: map[string]string{
+			"/user": `{"login": "Me"}`,
+			"/user/repos?affiliation=owner&per_page=100": `
+[{"full_name": "me/a", "clone_url": "https://git.example.com/me/a.git", "private": true},
+ {"full_name": "me/b", "clone_url": "https://git.example.com/me/b.git"}]`,
+		},
+		arg:    "git.example.com/me",
+		isUser: true,
+		expect: []string{"https://git.example.com/me/a.git", "https://git.example.com/me/b.git"},
+	}, {
+		name: "github topics and visibility",
+		kind: "github",
+		pages: map[string]string{
+			"/orgs/my-org/repos?type=all&per_page=100": `
+[{"full_name": "my-org/a", "clone_url": "https://git.example.com/my-org/a.git", "visibility": "private", "topics": ["backend", "go"]},
+ {"full_name": "my-org/b", "clone_url": "https://git.example.com/my-org/b.git", "visibility": "public", "topics": ["backend", "go"]},
+ {"full_name": "my-org/c", "clone_url": "https://git.example.com/my-org/c.git", "visibility": "private", "topics": ["backend"]}]`,
+		},
+		arg:    "git.example.com/my-org",
+		filter: forgeFilter{topics: []string{"Backend", "go"}, visibility: "private"},
+		expect: []string{"https://git.example.com/my-org/a.git"},
+	}, {
+		name: "gitlab group with language",
+		kind: "gitlab",
+		pages: map[string]string{
+			"/groups/group%2Fsub/projects?include_subgroups=true&per_page=100": `
+[{"id": 1, "path_with_namespace": "group/sub/a", "http_url_to_repo": "https://git.example.com/group/sub/a.git", "visibility": "internal"},
+ {"id": 2, "path_with_namespace": "group/sub/b", "http_url_to_repo": "https://git.example.com/group/sub/b.git"},
+ {"id": 3, "path_with_namespace": "group/sub/c", "http_url_to_repo": "https://git.example.com/group/sub/c.git", "forked_from_project": {"id": 9}}]`,
+			"/projects/1/languages": `{"Go": 80.5, "Shell": 19.5}`,
+			"/projects/2/languages": `{"Ruby": 100}`,
+		},
+		arg:    "git.example.com/group/sub",
+		filter: forgeFilter{language: "go"},
+		expect: []string{"https://git.example.com/group/sub/a.git"},
+	}, {
+		name: "gitea user",
+		kind: "gitea",
+		pages: map[string]string{
+			"/users/me/repos?limit=50": `next:/users/me/repos?limit=50&page=2
+[{"full_name": "me/a", "clone_url": "https://git.example.com/me/a.git", "internal": true}]`,
+			"/users/me/repos?limit=50&page=2": `
+[{"full_name": "me/b", "clone_url": "https://git.example.com/me/b.git", "private": true}]`,
+		},
+		arg:    "git.example.com/me",
+		isUser: true,
+		filter: forgeFilter{visibility: "internal"},
+		expect: []string{"https://git.example.com/me/a.git"},
+	}, {
+		name: "bitbucket workspace",
+		kind: "bitbucket",
+		pages: map[string]string{
+			"/repositories/team?pagelen=100": `{"values": [
+  {"full_name": "team/a", "language": "python", "links": {"clone": [
+    {"name": "https", "href": "https://me@git.example.com/team/a.git"},
+    {"name": "ssh", "href": "git@git.example.com:team/a.git"}]}}],
+  "next": "{{api}}/repositories/team?pagelen=100&page=2"}`,
+			"/repositories/team?pagelen=100&page=2": `{"values": [
+  {"full_name": "team/b", "language": "go", "links": {"clone": [
+    {"name": "https", "href": "https://me@git.example.com/team/b.git"}]}}]}`,
+		},
+		arg:    "git.example.com/team",
+		filter: forgeFilter{language: "Python"},
+		expect: []string{"https://git.example.com/team/a.git"},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			newFakeForge(t, tc.kind, tc.pages)
+			got, err := forgeTargets(context.Background(), tc.arg, tc.isUser, tc.ssh, &tc.filter)
+			if err != nil {
+				t.Fatalf("error should be nil, but: %s", err)
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %q, expect: %q", got, tc.expect)
+			}
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		newFakeForge(t, "github", nil)
+		_, err := forgeTargets(context.Background(), "git.example.com/nobody", false, false, &forgeFilter{})
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("error should be 404, but: %v", err)
+		}
+	})
+
+	t.Run("next page outside of the API", func(t *testing.T) {
+		for kind, pages := range map[string]map[string]string{
+			"github": {
+				"/orgs/my-org/repos?type=all&per_page=100": `next:https://evil.example.com/orgs/my-org/repos?page=2
+[{"full_name": "my-org/a", "clone_url": "https://git.example.com/my-org/a.git"}]`,
+			},
+			"bitbucket": {
+				"/repositories/my-org?pagelen=100": `{"values": [],
+  "next": "https://evil.example.com/repositories/my-org?page=2"}`,
+			},
+		} {
+			newFakeForge(t, kind, pages)
+			_, err := forgeTargets(context.Background(), "git.example.com/my-org", false, false, &forgeFilter{})
+			if err == nil || !strings.Contains(err.Error(), "refusing to follow") {
+				t.Errorf("%s: the next page outside of the API should be refused, but: %v", kind, err)
+			}
+		}
+	})
+
+	t.Run("unknown forge", func(t *testing.T) {
+		t.Cleanup(gitconfig.WithConfig(t, ""))
+		_, err := forgeTargets(context.Background(), "git.example.com/my-org", false, false, &forgeFilter{})
+		if err == nil || !strings.Contains(err.Error(), "unknown forge") {
+			t.Errorf("error should be unknown forge, but: %v", err)
+		}
+	})
+}
+
+func TestForgeOwner(t *testing.T) {
+	testCases := []struct {
+		arg, host, owner string
+	}{
+		{"github.com/my-org", "github.com", "my-org"},
+		{"https://gitlab.com/group/sub/", "gitlab.com", "group/sub"},
+		{"github.com", "", ""},
+		{"my-org", "", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.arg, func(t *testing.T) {
+			host, owner, err := forgeOwner(tc.arg)
+			if tc.host == "" {
+				if err == nil {
+					t.Errorf("error should be returned, but got: %q, %q", host, owner)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if host != tc.host || owner != tc.owner {
+				t.Errorf("got: %q, %q, expect: %q, %q", host, owner, tc.host, tc.owner)
+			}
+		})
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	testCases := []struct {
+		link, expect string
+	}{
+		{`<https://api.example.com/repos?page=2>; rel="next", <https://api.example.com/repos?page=5>; rel="last"`,
+			"https://api.example.com/repos?page=2"},
+		{`<https://api.example.com/repos?page=1>; rel="prev"`, ""},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		if got := nextLink(tc.link); got != tc.expect {
+			t.Errorf("nextLink(%q) = %q, expect: %q", tc.link, got, tc.expect)
+		}
+	}
+}
+
+func TestForgeClient_credential(t *testing.T) {
+	dir := t.TempDir()
+	global := filepath.Join(dir, "gitconfig")
+	err := os.WriteFile(global, []byte(`[credential "https://git.example.com"]
+	helper = "!f() { test \"$1\" = get && echo username=me && echo password=app-password; }; f"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setEnv(t, "GIT_CONFIG_GLOBAL", global)
+	setEnv(t, "GIT_CONFIG_NOSYSTEM", "1")
+	setEnv(t, "BITBUCKET_TOKEN", "")
+
+	c := &forgeClient{host: "git.example.com", kind: forgeBitbucket}
+	got := c.lookupCredential(context.Background())
+	expect := &forgeCredential{username: "me", secret: "app-password"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %+v, expect: %+v", got, expect)
+	}
+
+	setEnv(t, "BITBUCKET_TOKEN", "token")
+	got = c.lookupCredential(context.Background())
+	expect = &forgeCredential{secret: "token"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("the token in the environment should be preferred, but got: %+v", got)
+	}
+}
+
+func TestForgeClient_get_otherHost(t *testing.T) {
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		fmt.Fprint(w, "{}")
+	}))
+	t.Cleanup(srv.Close)
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		fmt.Fprint(w, "{}")
+	}))
+	t.Cleanup(other.Close)
+
+	c := &forgeClient{host: "git.example.com", kind: forgeGitHub, api: srv.URL,
+		credential: &forgeCredential{secret: "s3cret"}}
+	var v struct{}
+	for _, u := range []string{srv.URL + "/user", other.URL + "/user"} {
+		if _, err := c.get(context.Background(), u, &v); err != nil {
+			t.Fatalf("error should be nil, but: %s", err)
+		}
+	}
+	if expect := []string{"Bearer s3cret", ""}; !reflect.DeepEqual(auth, expect) {
+		t.Errorf("the credential should be sent only to the API, but got: %q", auth)
+	}
+}
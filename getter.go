@@ -9,12 +9,15 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/x-motemen/ghq/logger"
 )
 
 var seen sync.Map
 
+// getRepoLock reports whether the repository is got for the first time in
+// this process. Other processes are excluded by lockRepository.
 func getRepoLock(localRepoRoot string) bool {
 	_, loaded := seen.LoadOrStore(localRepoRoot, struct{}{})
 	return !loaded
@@ -22,34 +25,62 @@ func getRepoLock(localRepoRoot string) bool {
 
 type getInfo struct {
 	localRepository *LocalRepository
+	// subpath is the directory inside the repository referenced by a browser URL.
+	subpath string
 }
 
 type getter struct {
 	update, shallow, silent, ssh, recursive, bare bool
 	vcs, branch, partial                          string
+	// timeout bounds the time to get each repository, if positive.
+	timeout time.Duration
+	// progress shows the progress of each repository, if not nil.
+	progress *progressUI
+	// retry retries cloning and updating on transient failures, if not nil.
+	retry *retryPolicy
 }
 
 func (g *getter) get(ctx context.Context, argURL string) (getInfo, error) {
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
 	u, err := newURL(argURL, g.ssh, false)
 	if err != nil {
 		return getInfo{}, fmt.Errorf("could not parse URL %q: %w", argURL, err)
 	}
+	u, br := parseBrowserURL(u)
 	branch := g.branch
 	if pos := strings.LastIndexByte(u.Path, '@'); pos >= 0 {
 		u.Path, branch = u.Path[:pos], u.Path[pos+1:]
 	}
+	var revision string
+	if branch == "" && br.ref != "" {
+		if br.isCommit() {
+			revision = br.ref
+		} else {
+			branch = br.ref
+		}
+	}
 	remote, err := NewRemoteRepository(u)
 	if err != nil {
 		return getInfo{}, err
 	}
 
-	return g.getRemoteRepository(ctx, remote, branch)
+	row := g.progress.add(argURL)
+	defer row.finish()
+	info, err := g.getRemoteRepository(ctx, remote, branch, revision, row)
+	info.subpath = br.subpath
+	return info, err
 }
 
 // getRemoteRepository clones or updates a remote repository remote.
 // If doUpdate is true, updates the locally cloned repository. Otherwise does nothing.
 // If isShallow is true, does shallow cloning. (no effect if already cloned or the VCS is Mercurial and git-svn)
-func (g *getter) getRemoteRepository(ctx context.Context, remote RemoteRepository, branch string) (getInfo, error) {
+// If revision is not empty, it is checked out after cloning. (Git only)
+// The progress is reported to row, if not nil.
+func (g *getter) getRemoteRepository(ctx context.Context, remote RemoteRepository, branch, revision string, row *progressRow) (getInfo, error) {
 	remoteURL := remote.URL()
 	local, err := LocalRepositoryFromURL(remoteURL, g.bare)
 	if err != nil {
@@ -77,18 +108,20 @@ func (g *getter) getRemoteRepository(ctx context.Context, remote RemoteRepositor
 
 	switch {
 	case newPath:
+		src := remoteURL.String()
 		if remoteURL.Scheme == "codecommit" {
-			logger.Log("clone", fmt.Sprintf("%s -> %s", remoteURL.Opaque, fpath))
-		} else {
-			logger.Log("clone", fmt.Sprintf("%s -> %s", remoteURL, fpath))
+			src = remoteURL.Opaque
 		}
+		logger.Emit(logger.Event{Prefix: "clone", Message: fmt.Sprintf("%s -> %s", src, fpath),
+			Repository: src, Path: fpath})
 		var (
 			localRepoRoot = fpath
 			repoURL       = remoteURL
 		)
 		vcs, ok := vcsRegistry[g.vcs]
 		if !ok {
-			vcs, repoURL, err = remote.VCS()
+			row.report("detecting vcs", -1)
+			vcs, repoURL, err = remote.VCS(ctx)
 			if err != nil {
 				return getInfo{}, err
 			}
@@ -105,21 +138,54 @@ func (g *getter) getRemoteRepository(ctx context.Context, remote RemoteRepositor
 			repoURL, _ = url.Parse(remoteURL.Opaque)
 		}
 		if getRepoLock(localRepoRoot) {
-			return info,
-				vcs.Clone(&vcsGetOption{
-					url:       repoURL,
-					dir:       localRepoRoot,
-					shallow:   g.shallow,
-					silent:    g.silent,
-					branch:    branch,
-					recursive: g.recursive,
-					bare:      g.bare,
-					partial:   g.partial,
-				})
+			row.report("locking", -1)
+			unlock, err := lockRepository(ctx, localRepoRoot)
+			if err != nil {
+				return info, err
+			}
+			defer unlock()
+			// another process may have cloned it while we were waiting for the lock
+			if ok, _ := isNotExistOrEmpty(localRepoRoot); !ok {
+				logger.Emit(logger.Event{Prefix: "exists", Message: localRepoRoot,
+					Repository: remoteURL.String(), Path: localRepoRoot})
+				return info, nil
+			}
+			row.report("cloning", -1)
+			vg := &vcsGetOption{
+				url:       repoURL,
+				dir:       localRepoRoot,
+				shallow:   g.shallow,
+				silent:    g.silent,
+				branch:    branch,
+				revision:  revision,
+				recursive: g.recursive,
+				bare:      g.bare,
+				partial:   g.partial,
+			}
+			if row != nil {
+				vg.progress = row.report
+			}
+			dir := vg.dir
+			err = g.retry.do(ctx, remoteURL.String(), row, func() error {
+				// the backend may have changed vg.dir in the last attempt
+				vg.dir = dir
+				return g.clone(ctx, vcs, vg)
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return info, fmt.Errorf("%w: %w", ctx.Err(), err)
+				}
+				return info, err
+			}
+			applyIdentityOrWarn(ctx, vcs, local.RootPath, localRepoRoot)
+			row.report("running hooks", -1)
+			runPostHooks(ctx, hookEvent{hook: hookPostClone, action: "clone",
+				path: localRepoRoot, url: repoURL.String(), vcs: vcsName(vcs)}, g.silent)
+			return info, nil
 		}
 		return info, nil
 	case g.update:
-		logger.Log("update", fpath)
+		logger.Emit(logger.Event{Prefix: "update", Message: fpath, Repository: remoteURL.String(), Path: fpath})
 		vcs, localRepoRoot := local.VCS()
 		if vcs == nil {
 			return getInfo{}, fmt.Errorf("failed to detect VCS for %q", fpath)
@@ -129,21 +195,79 @@ func (g *getter) getRemoteRepository(ctx context.Context, remote RemoteRepositor
 			repoURL, _ = url.Parse(remoteURL.Opaque)
 		}
 		if getRepoLock(localRepoRoot) {
-			return info, vcs.Update(&vcsGetOption{
-				url:       repoURL,
-				dir:       localRepoRoot,
-				silent:    g.silent,
-				recursive: g.recursive,
-				bare:      g.bare,
+			row.report("locking", -1)
+			unlock, err := lockRepository(ctx, localRepoRoot)
+			if err != nil {
+				return info, err
+			}
+			defer unlock()
+			row.report("updating", -1)
+			err = g.retry.do(ctx, remoteURL.String(), row, func() error {
+				return vcs.Update(ctx, &vcsGetOption{
+					url:       repoURL,
+					dir:       localRepoRoot,
+					silent:    g.silent,
+					recursive: g.recursive,
+					bare:      g.bare,
+				})
 			})
+			if err != nil {
+				return info, err
+			}
+			row.report("running hooks", -1)
+			runPostHooks(ctx, hookEvent{hook: hookPostUpdate, action: "update",
+				path: localRepoRoot, url: repoURL.String(), vcs: vcsName(vcs)}, g.silent)
+			return info, nil
 		}
 		return info, nil
 	}
-	logger.Log("exists", fpath)
+	logger.Emit(logger.Event{Prefix: "exists", Message: fpath, Repository: remoteURL.String(), Path: fpath})
 	return info, nil
 }
 
+// clone clones the repository into a staging directory next to vg.dir, and
+// renames it into place on success. A failed or interrupted clone leaves
+// nothing at vg.dir.
+func (g *getter) clone(ctx context.Context, vcs *VCSBackend, vg *vcsGetOption) error {
+	if vcs == FossilBackend {
+		// a fossil checkout records the absolute path of its repository file,
+		// so it cannot be moved after cloning
+		_, statErr := os.Stat(vg.dir)
+		if err := vcs.Clone(ctx, vg); err != nil {
+			if os.IsNotExist(statErr) {
+				os.RemoveAll(vg.dir)
+			}
+			return err
+		}
+		invalidateRepositoryIndex()
+		return nil
+	}
+	dst := vg.dir
+	if vcs == SubversionBackend || vcs == GitsvnBackend {
+		// the backends check out into the base of the standard layout
+		dst = svnBase(vg.dir)
+	}
+	s, err := newStaging(dst)
+	if err != nil {
+		return err
+	}
+	// keep "/trunk" or "/branches/<branch>" which the backends inspect
+	vg.dir = s.dir + vg.dir[len(dst):]
+	if err := vcs.Clone(ctx, vg); err != nil {
+		s.cleanup()
+		return err
+	}
+	if err := s.commit(vg.dir); err != nil {
+		s.cleanup()
+		return err
+	}
+	return nil
+}
+
 func detectLocalRepoRoot(remotePath, repoPath string) string {
+	// GitLab routes like "/group/sub/repo/-/tree/main" are not part of the repository path
+	remotePath, _, _ = strings.Cut(remotePath, "/-/")
+	repoPath, _, _ = strings.Cut(repoPath, "/-/")
 	remotePath = strings.TrimSuffix(strings.TrimSuffix(remotePath, "/"), ".git")
 	repoPath = strings.TrimSuffix(strings.TrimSuffix(repoPath, "/"), ".git")
 	pathParts := strings.Split(repoPath, "/")
@@ -55,6 +55,11 @@ func TestDetectLocalRepoRoot(t *testing.T) {
 		remotePath: "/path/to/repo.git/",
 		repoPath:   "/path/to/repo.git/",
 		expect:     "/path/to/repo",
+	}, {
+		name:       "gitlab route separator",
+		remotePath: "/group/sub/repo/-/tree/main/pkg",
+		repoPath:   "/group/sub/repo.git",
+		expect:     "/group/sub/repo",
 	}}
 
 	for _, tc := range testCases {
@@ -23,7 +23,7 @@ require (
 	github.com/cli/safeexec v1.0.1 // indirect
 	github.com/daviddengcn/go-colortext v1.0.0 // indirect
 	github.com/goccy/go-yaml v1.19.2 // indirect
-	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/sys v0.47.0
 	golang.org/x/text v0.40.0
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,19 +17,23 @@ type metaImport struct {
 	Prefix, VCS, RepoRoot string
 }
 
-func detectGoImport(u *url.URL) (string, *url.URL, error) {
+func detectGoImport(ctx context.Context, u *url.URL) (string, *url.URL, error) {
 	goGetU := *u
 	q := goGetU.Query()
 	q.Add("go-get", "1")
 	goGetU.RawQuery = q.Encode()
 
 	cli := &http.Client{
+		Timeout: defaultProbeTimeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// never follow redirection
 			return http.ErrUseLastResponse
 		},
 	}
-	req, _ := http.NewRequest(http.MethodGet, goGetU.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, goGetU.String(), nil)
+	if err != nil {
+		return "", nil, err
+	}
 	req.Header.Add("User-Agent", fmt.Sprintf("ghq/%s (+https://github.com/motemen/ghq)", version))
 	resp, err := cli.Do(req)
 	if err != nil {
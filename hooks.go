@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Songmu/gitconfig"
+	"github.com/x-motemen/ghq/cmdutil"
+	"github.com/x-motemen/ghq/logger"
+)
+
+// Hooks run on the events of repositories. A pre hook can veto the action by
+// failing, while a failure of a post hook is only warned, since the action
+// is done already.
+const (
+	hookPostClone   = "postClone"
+	hookPostUpdate  = "postUpdate"
+	hookPostCreate  = "postCreate"
+	hookPreRm       = "preRm"
+	hookPostMigrate = "postMigrate"
+)
+
+// A hookEvent is what hooks are run for, which is passed to them by the
+// environment variables.
+type hookEvent struct {
+	hook   string // e.g. hookPostClone
+	action string // e.g. "clone"
+	path   string // GHQ_REPO_PATH, where hooks run
+	url    string // GHQ_REPO_URL
+	vcs    string // GHQ_VCS
+}
+
+func (ev hookEvent) environ() []string {
+	return append(os.Environ(),
+		"GHQ_HOOK="+ev.hook,
+		"GHQ_ACTION="+ev.action,
+		"GHQ_REPO_PATH="+ev.path,
+		"GHQ_REPO_URL="+ev.url,
+		"GHQ_VCS="+ev.vcs,
+	)
+}
+
+// runHooks runs the hooks for the event in order, and stops at the first
+// failure. The commands configured by 'ghq.hook.<hook>' run first, then the
+// executable named after the hook in the hooks directory, if any. The output
+// of the hooks is shown on the standard error, unless silent.
+func runHooks(ctx context.Context, ev hookEvent, silent bool) error {
+	commands, err := gitconfig.GetAll("ghq.hook." + ev.hook)
+	if err != nil && !gitconfig.IsNotFound(err) {
+		return err
+	}
+	for _, command := range commands {
+		if err := runHook(ctx, ev, silent, command, shellCommand(command)...); err != nil {
+			return err
+		}
+	}
+	file, err := hookFile(ev.hook)
+	if err != nil {
+		return err
+	}
+	if file != "" {
+		return runHook(ctx, ev, silent, file, file)
+	}
+	return nil
+}
+
+func runHook(ctx context.Context, ev hookEvent, silent bool, name string, args ...string) error {
+	if !silent {
+		logger.Log("hook", fmt.Sprintf("%s: %s", ev.hook, name))
+	}
+	cmd := cmdutil.Command(ctx, args[0], args[1:]...)
+	cmd.Dir = ev.path
+	cmd.Env = ev.environ()
+	if !silent {
+		// the standard output of ghq is for the paths of repositories
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+	}
+	if err := cmdutil.RunCommand(cmd, true); err != nil {
+		var runErr *cmdutil.RunError
+		if errors.As(err, &runErr) {
+			// the output has been shown unless silent, or hidden from the
+			// JSON log
+			runErr.Silent = silent || logger.JSON()
+		}
+		return fmt.Errorf("%s hook %q failed: %w", ev.hook, name, err)
+	}
+	return nil
+}
+
+// runPostHooks runs the post hooks, and warns their failure.
+func runPostHooks(ctx context.Context, ev hookEvent, silent bool) {
+	if err := runHooks(ctx, ev, silent); err != nil {
+		logger.Log("warning", err.Error())
+		logHiddenStderr(err)
+	}
+}
+
+func shellCommand(command string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", command}
+	}
+	return []string{"sh", "-c", command}
+}
+
+// hooksDir returns the directory of the hook executables, which can be
+// configured by 'ghq.hooksPath'.
+func hooksDir() (string, error) {
+	dir, err := gitconfig.Do("--path", "--get", "ghq.hooksPath")
+	if err != nil && !gitconfig.IsNotFound(err) {
+		return "", err
+	}
+	if dir != "" {
+		return dir, nil
+	}
+	return defaultHooksDir()
+}
+
+// defaultHooksDir returns "ghq/hooks" under $XDG_CONFIG_HOME or ~/.config.
+// It is a variable to be replaced in tests.
+var defaultHooksDir = func() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "ghq", "hooks"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "ghq", "hooks"), nil
+}
+
+// hookFile returns the executable for the hook in the hooks directory, named
+// in kebab case as git hooks are (e.g. "post-clone" for postClone), or an
+// empty string if none.
+func hookFile(hook string) (string, error) {
+	dir, err := hooksDir()
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, r := range hook {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('-')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	file := filepath.Join(dir, b.String())
+	fi, err := os.Stat(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	if fi.IsDir() || runtime.GOOS != "windows" && fi.Mode()&0111 == 0 {
+		logger.Log("warning", fmt.Sprintf("%s is not executable, skipped", file))
+		return "", nil
+	}
+	return file, nil
+}
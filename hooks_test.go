@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Songmu/gitconfig"
+)
+
+func TestHookFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executable bits are not available")
+	}
+	dir := t.TempDir()
+	t.Cleanup(gitconfig.WithConfig(t, fmt.Sprintf(`
+[ghq]
+  hooksPath = %s
+`, filepath.ToSlash(dir))))
+	os.WriteFile(filepath.Join(dir, "post-clone"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(dir, "pre-rm"), []byte("#!/bin/sh\n"), 0644)
+
+	testCases := []struct {
+		hook   string
+		expect string
+	}{
+		{hookPostClone, filepath.Join(dir, "post-clone")},
+		{hookPreRm, ""}, // not executable
+		{hookPostUpdate, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.hook, func(t *testing.T) {
+			got, err := hookFile(tc.hook)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expect {
+				t.Errorf("got: %q, expect: %q", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestCommandGet_hooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command is not available")
+	}
+	withFakeGitBackend(t, func(t *testing.T, tmproot string, _ *_cloneArgs, _ *_updateArgs) {
+		hooksPath := t.TempDir()
+		out := filepath.Join(t.TempDir(), "hooks.log")
+		os.WriteFile(filepath.Join(hooksPath, "post-clone"),
+			[]byte("#!/bin/sh\necho file >> "+out+"\n"), 0755)
+		t.Cleanup(gitconfig.WithConfig(t, fmt.Sprintf(`
+[ghq]
+  hooksPath = %s
+[ghq "hook"]
+  postClone = echo \"$GHQ_ACTION $GHQ_REPO_URL $GHQ_REPO_PATH $(pwd -P)\" >> %s
+  postClone = echo second >> %s
+  postUpdate = echo \"$GHQ_ACTION $GHQ_REPO_PATH\" >> %s
+`, hooksPath, out, out, out)))
+
+		localDir := filepath.Join(tmproot, "github.com", "motemen", "ghq-test-repo")
+		for _, args := range [][]string{
+			{"", "get", "motemen/ghq-test-repo"},
+			{"", "get", "-u", "motemen/ghq-test-repo"},
+		} {
+			seen.Delete(localDir)
+			if _, _, err := capture(func() {
+				if err := newApp().Run(context.Background(), args); err != nil {
+					t.Errorf("error should be nil but: %s", err)
+				}
+			}); err != nil {
+				t.Fatal(err)
+			}
+			// to be detected as a git repository on update
+			os.MkdirAll(filepath.Join(localDir, ".git"), 0755)
+		}
+
+		b, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		realDir, _ := filepath.EvalSymlinks(localDir)
+		expect := []string{
+			fmt.Sprintf("clone https://github.com/motemen/ghq-test-repo %s %s", localDir, realDir),
+			"second",
+			"file",
+			"update " + localDir,
+		}
+		if got := strings.Split(strings.TrimSpace(string(b)), "\n"); !slices.Equal(got, expect) {
+			t.Errorf("hooks should run in order\ngot:    %q\nexpect: %q", got, expect)
+		}
+	})
+}
+
+func TestCommandGet_postHookFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command is not available")
+	}
+	withFakeGitBackend(t, func(t *testing.T, tmproot string, _ *_cloneArgs, _ *_updateArgs) {
+		t.Cleanup(gitconfig.WithConfig(t, `
+[ghq "hook"]
+  postClone = exit 1
+`))
+		err := newApp().Run(context.Background(), []string{"", "get", "motemen/ghq-test-repo"})
+		if err != nil {
+			t.Errorf("failure of a post hook should not fail the command, but: %s", err)
+		}
+		localDir := filepath.Join(tmproot, "github.com", "motemen", "ghq-test-repo")
+		if _, err := os.Stat(localDir); err != nil {
+			t.Errorf("the repository should be cloned: %s", err)
+		}
+	})
+}
+
+func TestDoRm_preRmHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command is not available")
+	}
+	defer func(orig string) { _home = orig }(_home)
+	_home = ""
+	homeOnce = &sync.Once{}
+	tmpd := newTempDir(t)
+	defer func(orig []string) { _localRepositoryRoots = orig }(_localRepositoryRoots)
+	setEnv(t, envGhqRoot, tmpd)
+	_localRepositoryRoots = nil
+	localRepoOnce = &sync.Once{}
+
+	testCases := []struct {
+		name      string
+		hook      string
+		expectErr bool
+	}{{
+		name:      "vetoed",
+		hook:      "exit 1",
+		expectErr: true,
+	}, {
+		name: "allowed",
+		hook: `test \"$GHQ_ACTION $GHQ_VCS\" = \"rm git\"`,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Cleanup(gitconfig.WithConfig(t, fmt.Sprintf(`
+[ghq "hook"]
+  preRm = %s
+`, tc.hook)))
+			localDir := filepath.Join(tmpd, "github.com", "motemen", "ghq-rm")
+			if err := os.MkdirAll(filepath.Join(localDir, ".git"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			var runErr error
+			if _, _, err := captureWithInput([]string{"y"}, func() {
+				runErr = newApp().Run(context.Background(), []string{"", "rm", "motemen/ghq-rm"})
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if gotErr := runErr != nil; gotErr != tc.expectErr {
+				t.Fatalf("error = %v, expectErr = %v", runErr, tc.expectErr)
+			}
+			_, err := os.Stat(localDir)
+			if removed := os.IsNotExist(err); removed == tc.expectErr {
+				t.Errorf("the repository should be removed only if allowed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Songmu/gitconfig"
+	"github.com/x-motemen/ghq/cmdutil"
+	"github.com/x-motemen/ghq/logger"
+)
+
+// identityKeys are the ghq configuration keys of the identity, and the git
+// configuration keys which they are applied to.
+var identityKeys = []struct {
+	ghq, git string
+	isBool   bool
+}{
+	{ghq: "userName", git: "user.name"},
+	{ghq: "userEmail", git: "user.email"},
+	{ghq: "userSigningKey", git: "user.signingkey"},
+	{ghq: "sshCommand", git: "core.sshCommand"},
+	{ghq: "gpgSign", git: "commit.gpgsign", isBool: true},
+}
+
+// An identitySetting is a git configuration of the identity.
+type identitySetting struct {
+	key, value string
+	isBool     bool
+}
+
+// identityURL returns the URL which the identity of the repository at dir is
+// matched by, i.e. "https://<host>/<path>" of the repository under root,
+// regardless of the protocol which it is cloned with.
+func identityURL(root, dir string) (string, error) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", err
+	}
+	return "https://" + filepath.ToSlash(rel), nil
+}
+
+// identityFor returns the identity configured for the URL by
+// 'ghq.<url>.userEmail' and so on.
+func identityFor(u string) ([]identitySetting, error) {
+	var settings []identitySetting
+	for _, k := range identityKeys {
+		v, err := gitconfig.Do("--get-urlmatch", "ghq."+k.ghq, u)
+		if err != nil && !gitconfig.IsNotFound(err) {
+			return nil, err
+		}
+		if v != "" {
+			settings = append(settings, identitySetting{key: k.git, value: v, isBool: k.isBool})
+		}
+	}
+	return settings, nil
+}
+
+// applyIdentity sets the identity configured for the Git repository at dir,
+// which is under root, to its local configuration.
+func applyIdentity(ctx context.Context, root, dir string) error {
+	u, err := identityURL(root, dir)
+	if err != nil {
+		return err
+	}
+	settings, err := identityFor(u)
+	if err != nil {
+		return err
+	}
+	for _, s := range settings {
+		logger.Log("identity", fmt.Sprintf("%s = %s", s.key, s.value))
+		cmd := cmdutil.Command(ctx, "git", "config", "--local", s.key, s.value)
+		cmd.Dir = dir
+		cmd.Env = repositoryGitEnv()
+		if err := cmdutil.RunCommand(cmd, true); err != nil {
+			return fmt.Errorf("failed to set %s: %w", s.key, err)
+		}
+	}
+	return nil
+}
+
+// applyIdentityOrWarn applies the identity to the repository just cloned or
+// created, and warns the failure since the repository is there already.
+func applyIdentityOrWarn(ctx context.Context, vcs *VCSBackend, root, dir string) {
+	if vcs != GitBackend && vcs != GitsvnBackend {
+		return
+	}
+	if err := applyIdentity(ctx, root, dir); err != nil {
+		logger.Log("warning", fmt.Sprintf("failed to apply the identity to %s: %s", dir, err))
+		logHiddenStderr(err)
+	}
+}
+
+// effectiveGitConfig returns the value of the key in effect in the Git
+// repository at dir, or an empty string if not set.
+func effectiveGitConfig(ctx context.Context, dir, key string, isBool bool) (string, error) {
+	args := []string{"config", "--get"}
+	if isBool {
+		args = append(args, "--type=bool")
+	}
+	cmd := exec.CommandContext(ctx, "git", append(args, key)...)
+	cmd.Dir = dir
+	cmd.Env = repositoryGitEnv()
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		// exits with 1 if the key is not set
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get %s in %s: %w", key, dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// repositoryGitEnv returns the environment to run 'git config' on the
+// configuration of a repository. GIT_CONFIG is removed, which makes git read
+// and write the file only.
+func repositoryGitEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "GIT_CONFIG=") {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// normalizeGitBool returns "true" or "false" for the boolean value of git
+// configurations, or v as is if it is not a boolean.
+func normalizeGitBool(v string) string {
+	switch strings.ToLower(v) {
+	case "true", "yes", "on", "1":
+		return "true"
+	case "false", "no", "off", "0", "":
+		return "false"
+	}
+	return v
+}
+
+// An identityMismatch is a setting of the identity which is not in effect in
+// a repository.
+type identityMismatch struct {
+	repo      *LocalRepository
+	setting   identitySetting
+	effective string
+}
+
+// checkIdentity returns the settings of the identity configured for the Git
+// repository which are not in effect.
+func checkIdentity(ctx context.Context, repo *LocalRepository) ([]identityMismatch, error) {
+	u, err := identityURL(repo.RootPath, repo.FullPath)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := identityFor(u)
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []identityMismatch
+	for _, s := range settings {
+		v, err := effectiveGitConfig(ctx, repo.FullPath, s.key, s.isBool)
+		if err != nil {
+			return nil, err
+		}
+		expected := s.value
+		if s.isBool {
+			expected, v = normalizeGitBool(expected), normalizeGitBool(v)
+		}
+		if v != expected {
+			mismatches = append(mismatches, identityMismatch{repo: repo, setting: s, effective: v})
+		}
+	}
+	return mismatches, nil
+}
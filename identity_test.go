@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Songmu/gitconfig"
+)
+
+const identityConfig = `
+[ghq]
+  userName = Gopher
+[ghq "https://github.com/work"]
+  userEmail = gopher@work.example.com
+  gpgSign = yes
+`
+
+func TestIdentityFor(t *testing.T) {
+	t.Cleanup(gitconfig.WithConfig(t, identityConfig))
+
+	testCases := []struct {
+		url    string
+		expect []identitySetting
+	}{{
+		url: "https://github.com/work/repo",
+		expect: []identitySetting{
+			{key: "user.name", value: "Gopher"},
+			{key: "user.email", value: "gopher@work.example.com"},
+			{key: "commit.gpgsign", value: "yes", isBool: true},
+		},
+	}, {
+		url: "https://github.com/personal/repo",
+		expect: []identitySetting{
+			{key: "user.name", value: "Gopher"},
+		},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			got, err := identityFor(tc.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("got: %+v, expect: %+v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestDoIdentityCheck(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command is not available")
+	}
+	// isolate from the configuration of the user
+	setEnv(t, "GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	setEnv(t, "GIT_CONFIG_NOSYSTEM", "1")
+	t.Cleanup(gitconfig.WithConfig(t, identityConfig))
+
+	defer func(orig string) { _home = orig }(_home)
+	_home = ""
+	homeOnce = &sync.Once{}
+	tmpd := newTempDir(t)
+	defer func(orig []string) { _localRepositoryRoots = orig }(_localRepositoryRoots)
+	setEnv(t, envGhqRoot, tmpd)
+	_localRepositoryRoots = nil
+	localRepoOnce = &sync.Once{}
+
+	work := filepath.Join(tmpd, "github.com", "work", "repo")
+	personal := filepath.Join(tmpd, "github.com", "personal", "repo")
+	for _, dir := range []string{work, personal} {
+		if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+			t.Fatalf("git init failed: %s\n%s", err, out)
+		}
+	}
+	// the personal one is already configured
+	if out, err := exec.Command("git", "config", "--file", filepath.Join(personal, ".git", "config"), "user.name", "Gopher").CombinedOutput(); err != nil {
+		t.Fatalf("git config failed: %s\n%s", err, out)
+	}
+
+	check := func(args ...string) (string, error) {
+		var runErr error
+		out, _, err := capture(func() {
+			runErr = newApp().Run(context.Background(), append([]string{"", "identity", "check"}, args...))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out, runErr
+	}
+
+	out, err := check()
+	if err == nil {
+		t.Error("mismatches should be an error")
+	}
+	for _, expect := range []string{
+		`github.com/work/repo: user.name is "", expected "Gopher"`,
+		`github.com/work/repo: user.email is "", expected "gopher@work.example.com"`,
+		`github.com/work/repo: commit.gpgsign is "false", expected "yes"`,
+	} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("output should contain %q, but: %s", expect, out)
+		}
+	}
+	if strings.Contains(out, "personal") {
+		t.Errorf("matched repositories should not be reported, but: %s", out)
+	}
+
+	if _, err := check("--fix"); err != nil {
+		t.Errorf("error should be nil with --fix, but: %s", err)
+	}
+	out, err = check()
+	if err != nil || out != "" {
+		t.Errorf("identity should be fixed, but: %s, %v", out, err)
+	}
+	b, err := os.ReadFile(filepath.Join(work, ".git", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "gopher@work.example.com") {
+		t.Errorf("identity should be applied to the local configuration, but:\n%s", b)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Songmu/gitconfig"
+)
+
+// ghqIgnoreFile is the file at the top of a root listing the paths under the
+// root which are not walked, in addition to 'ghq.ignore'.
+const ghqIgnoreFile = ".ghqignore"
+
+// An ignoreRule is a gitignore-style pattern of the paths not walked, such
+// as "node_modules", "/example.com/" or "github.com/**/vendor".
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	// anchored patterns match the path from the root, and the others the
+	// base name at any depth
+	anchored bool
+	segments []string
+}
+
+// ignoreRules are the rules of a root, the last matching one of which
+// decides whether a path is ignored, as in .gitignore.
+type ignoreRules []ignoreRule
+
+// parseIgnoreRules parses the patterns, skipping empty lines and comments
+// starting with "#". The source is shown in the error of a bad pattern.
+func parseIgnoreRules(patterns []string, source string) (ignoreRules, error) {
+	var rules ignoreRules
+	for i, p := range patterns {
+		p = strings.TrimRight(p, " \t\r")
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		r := ignoreRule{pattern: p}
+		if strings.HasPrefix(p, "!") {
+			r.negate = true
+			p = p[1:]
+		} else if strings.HasPrefix(p, `\#`) || strings.HasPrefix(p, `\!`) {
+			p = p[1:]
+		}
+		// only directories are walked, so the trailing slash means nothing
+		p = strings.TrimRight(p, "/")
+		if strings.Contains(p, "/") {
+			r.anchored = true
+			p = strings.TrimPrefix(p, "/")
+		}
+		if p == "" {
+			continue
+		}
+		r.segments = strings.Split(p, "/")
+		for _, s := range r.segments {
+			if _, err := path.Match(s, ""); err != nil {
+				return nil, fmt.Errorf("bad pattern %q at line %d of %s: %w", r.pattern, i+1, source, err)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// loadIgnoreRules returns the rules of 'ghq.ignore' followed by the ones in
+// the .ghqignore file of the root, which take precedence.
+func loadIgnoreRules(root string) (ignoreRules, error) {
+	patterns, err := gitconfig.GetAll("ghq.ignore")
+	if err != nil && !gitconfig.IsNotFound(err) {
+		return nil, err
+	}
+	rules, err := parseIgnoreRules(patterns, "ghq.ignore")
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(root, ghqIgnoreFile)
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for sc := bufio.NewScanner(bytes.NewReader(b)); sc.Scan(); {
+		lines = append(lines, sc.Text())
+	}
+	fileRules, err := parseIgnoreRules(lines, file)
+	if err != nil {
+		return nil, err
+	}
+	return append(rules, fileRules...), nil
+}
+
+// ignorePatterns returns the patterns of the rules of each root, by which
+// the repository index knows that the rules have been changed.
+func ignorePatterns(roots []string) (map[string][]string, error) {
+	patterns := map[string][]string{}
+	for _, root := range roots {
+		rules, err := loadIgnoreRules(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rules {
+			patterns[root] = append(patterns[root], r.pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// matches reports whether the path, given as the slash separated parts
+// relative to the root, is ignored by the rules itself. The walk does not
+// descend into the ignored directories, so their contents are not checked.
+func (rules ignoreRules) matches(parts []string) bool {
+	ignored := false
+	for _, r := range rules {
+		if r.match(parts) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// excludes reports whether the slash separated path relative to the root,
+// or any of its parents, is ignored.
+func (rules ignoreRules) excludes(relPath string) bool {
+	if len(rules) == 0 {
+		return false
+	}
+	parts := strings.Split(relPath, "/")
+	for i := 1; i <= len(parts); i++ {
+		if rules.matches(parts[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r ignoreRule) match(parts []string) bool {
+	if !r.anchored {
+		ok, _ := path.Match(r.segments[0], parts[len(parts)-1])
+		return ok
+	}
+	return matchSegments(r.segments, parts)
+}
+
+// matchSegments matches the path parts against the pattern segments, where
+// "**" matches zero or more parts, or one or more at the end.
+func matchSegments(segments, parts []string) bool {
+	for len(segments) > 0 {
+		if segments[0] == "**" {
+			rest := segments[1:]
+			if len(rest) == 0 {
+				return len(parts) > 0
+			}
+			for i := range len(parts) + 1 {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(segments[0], parts[0]); !ok {
+			return false
+		}
+		segments, parts = segments[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Songmu/gitconfig"
+)
+
+func TestIgnoreRules_excludes(t *testing.T) {
+	rules, err := parseIgnoreRules([]string{
+		"# dependencies",
+		"node_modules/",
+		"/example.com",
+		"github.com/**/vendor",
+		"build-*",
+		"!build-keep",
+		"gitlab.com/big/**",
+		`\!bang`,
+	}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		path   string
+		expect bool
+	}{
+		{"github.com/me/app/node_modules", true},
+		{"node_modules", true},
+		{"github.com/me/app/node_modules/lib/.git", true},
+		{"example.com", true},
+		{"example.com/me/app", true},
+		{"github.com/example.com", false},
+		{"github.com/vendor", true},
+		{"github.com/me/app/vendor", true},
+		{"gitlab.com/me/app/vendor", false},
+		{"github.com/me/build-out", true},
+		{"github.com/me/build-keep", false},
+		{"gitlab.com/big", false},
+		{"gitlab.com/big/repo", true},
+		{"github.com/me/!bang", true},
+		{"github.com/me/app", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			if got := rules.excludes(tc.path); got != tc.expect {
+				t.Errorf("got: %t, expect: %t", got, tc.expect)
+			}
+		})
+	}
+
+	if _, err := parseIgnoreRules([]string{"vendor", "[z-a"}, ".ghqignore"); err == nil ||
+		!strings.Contains(err.Error(), `bad pattern "[z-a" at line 2 of .ghqignore`) {
+		t.Errorf("error should be returned for the bad pattern, but: %v", err)
+	}
+}
+
+func TestDoList_ignore(t *testing.T) {
+	setSyncRoot(t)
+	root, err := primaryLocalRepositoryRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(gitconfig.WithConfig(t, "[ghq]\n\tignore = node_modules\n"))
+	for _, p := range []string{
+		"github.com/me/app",
+		"github.com/me/app/node_modules/lib",
+		"github.com/me/generated/pkg",
+		"github.com/me/generated/keep",
+		"example.com/me/app",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(p), ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ghqIgnoreFile), []byte("/example.com\ngithub.com/me/generated/*\n!github.com/me/generated/keep\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _, err := capture(func() {
+		if err := newApp().Run(context.Background(), []string{"", "list", "--nested"}); err != nil {
+			t.Errorf("error should be nil, but: %s", err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "github.com/me/app\ngithub.com/me/generated/keep\n"; filepath.ToSlash(out) != expect {
+		t.Errorf("got: %q, expect: %q", out, expect)
+	}
+}
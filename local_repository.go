@@ -7,8 +7,10 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/Songmu/gitconfig"
 	"github.com/saracen/walker"
@@ -26,6 +28,11 @@ type LocalRepository struct {
 
 	repoPath   string
 	vcsBackend *VCSBackend
+	// parent is the repository in whose working tree this one is, found
+	// by walking with walkOptions
+	parent *LocalRepository
+	// submodule is whether this one is a submodule of the parent
+	submodule bool
 }
 
 // RepoPath returns local repository path
@@ -72,8 +79,9 @@ func LocalRepositoryFromFullPath(fullPath string, backend *VCSBackend) (*LocalRe
 	}, nil
 }
 
-// LocalRepositoryFromURL resolve LocalRepository from URL
-func LocalRepositoryFromURL(remoteURL *url.URL, bare bool) (*LocalRepository, error) {
+// relPathFromURL returns the relative path of the local repository of the
+// URL under a root, and its parts.
+func relPathFromURL(remoteURL *url.URL, bare bool) (string, []string) {
 	pathParts := append(
 		[]string{remoteURL.Hostname()}, strings.Split(remoteURL.Path, "/")...,
 	)
@@ -84,6 +92,12 @@ func LocalRepositoryFromURL(remoteURL *url.URL, bare bool) (*LocalRepository, er
 		relPath = relPath + ".git"
 		pathParts[len(pathParts)-1] = pathParts[len(pathParts)-1] + ".git"
 	}
+	return relPath, pathParts
+}
+
+// LocalRepositoryFromURL resolve LocalRepository from URL
+func LocalRepositoryFromURL(remoteURL *url.URL, bare bool) (*LocalRepository, error) {
+	relPath, pathParts := relPathFromURL(remoteURL, bare)
 
 	var (
 		localRepository *LocalRepository
@@ -103,11 +117,7 @@ func LocalRepositoryFromURL(remoteURL *url.URL, bare bool) (*LocalRepository, er
 	if localRepository != nil {
 		return localRepository, nil
 	}
-	var remoteURLStr = remoteURL.String()
-	if remoteURL.Scheme == "codecommit" {
-		remoteURLStr = remoteURL.Opaque
-	}
-	prim, err := getRoot(remoteURLStr)
+	prim, err := rootOf(remoteURL)
 	if err != nil {
 		return nil, err
 	}
@@ -121,12 +131,24 @@ func LocalRepositoryFromURL(remoteURL *url.URL, bare bool) (*LocalRepository, er
 	}, nil
 }
 
+// rootOf returns the root in which the repository of the URL is cloned.
+func rootOf(remoteURL *url.URL) (string, error) {
+	u := remoteURL.String()
+	if remoteURL.Scheme == "codecommit" {
+		u = remoteURL.Opaque
+	}
+	return getRoot(u)
+}
+
 func getRoot(u string) (string, error) {
-	prim := os.Getenv(envGhqRoot)
-	if prim != "" {
-		return prim, nil
+	if os.Getenv(envGhqRoot) != "" {
+		// the first of the roots in GHQ_ROOT
+		return primaryLocalRepositoryRoot()
 	}
-	var err error
+	var (
+		prim string
+		err  error
+	)
 	if !codecommitLikeURLPattern.MatchString(u) {
 		prim, err = gitconfig.Do("--path", "--get-urlmatch", "ghq.root", u)
 		if err != nil && !gitconfig.IsNotFound(err) {
@@ -260,17 +282,72 @@ func walkAllLocalRepositories(callback func(*LocalRepository)) error {
 }
 
 func walkLocalRepositories(vcs string, callback func(*LocalRepository)) error {
+	return walkLocalRepositoriesWith(vcs, walkOptions{}, callback)
+}
+
+// walkOptions are the options of walkLocalRepositoriesWith. By default, the
+// walk does not descend into the working trees of the repositories found.
+type walkOptions struct {
+	// nested reports the repositories in the working trees of others
+	nested bool
+	// submodules reports the submodules of the repositories
+	submodules bool
+	// strays is called with the directories at the depth of the
+	// repositories of their hosts which are not repositories
+	strays func(fpath string)
+	// loops is called with the symlinks which are loops, which are not
+	// walked
+	loops func(fpath string)
+}
+
+// walkLocalRepositoriesWith walks the local repositories with the options.
+// The repositories in the working trees of others have their parents. The
+// paths ignored by 'ghq.ignore' or the .ghqignore file of the root are not
+// walked, and neither are the directories deeper than 'ghq.<url>.depth' of
+// their hosts.
+func walkLocalRepositoriesWith(vcs string, opt walkOptions, callback func(*LocalRepository)) error {
 	roots, err := localRepositoryRoots(true)
 	if err != nil {
 		return err
 	}
 
-	walkFn := func(fpath string, fi os.FileInfo) error {
+	var (
+		descend = opt.nested || opt.submodules
+		mu      sync.Mutex
+		found   = map[string]*LocalRepository{}
+	)
+	// parentOf returns the nearest repository found above fpath, which has
+	// been walked before its descendants.
+	parentOf := func(fpath string) *LocalRepository {
+		mu.Lock()
+		defer mu.Unlock()
+		for dir := filepath.Dir(fpath); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if repo, ok := found[dir]; ok {
+				return repo
+			}
+		}
+		return nil
+	}
+
+	depths := newWalkDepths()
+
+	// parts are the slash separated parts of fpath relative to the root
+	walkFn := func(fpath string, fi os.FileInfo, parts []string) error {
+		if isStagingDir(fpath) {
+			// a clone in progress, or left by an interrupted one
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		isSymlink := false
 		if fi.Mode()&os.ModeSymlink == os.ModeSymlink {
 			isSymlink = true
 			realpath, err := filepath.EvalSymlinks(fpath)
 			if err != nil {
+				if _, err := os.Stat(fpath); opt.loops != nil && errors.Is(err, syscall.ELOOP) {
+					opt.loops(fpath)
+				}
 				return nil
 			}
 			fi, err = os.Stat(realpath)
@@ -281,8 +358,17 @@ func walkLocalRepositories(vcs string, callback func(*LocalRepository)) error {
 		if !fi.IsDir() {
 			return nil
 		}
+		if descend && slices.Contains(vcsMetadataDirs, fi.Name()) {
+			return filepath.SkipDir
+		}
 		vcsBackend := findVCSBackend(fpath, vcs)
 		if vcsBackend == nil {
+			if len(parts) > 0 && len(parts) == depths.of(parts[0]) {
+				if opt.strays != nil && findVCSBackend(fpath, "") == nil {
+					opt.strays(fpath)
+				}
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
@@ -290,11 +376,27 @@ func walkLocalRepositories(vcs string, callback func(*LocalRepository)) error {
 		if err != nil || repo == nil {
 			return nil
 		}
-		callback(repo)
+		report := true
+		if descend {
+			if repo.parent = parentOf(fpath); repo.parent != nil {
+				linked, target, _ := isLinkedGitDir(fpath)
+				repo.submodule = linked && !isWorktreeGitDir(target)
+				report = repo.submodule && opt.submodules || !repo.submodule && opt.nested
+			}
+			mu.Lock()
+			found[fpath] = repo
+			mu.Unlock()
+		}
+		if report {
+			callback(repo)
+		}
 
 		if isSymlink {
 			return nil
 		}
+		if descend && !strings.HasSuffix(fpath, ".git") {
+			return nil
+		}
 		return filepath.SkipDir
 	}
 
@@ -309,21 +411,152 @@ func walkLocalRepositories(vcs string, callback func(*LocalRepository)) error {
 	for _, root := range roots {
 		fi, err := os.Stat(root)
 		if err != nil {
-			if os.IsNotExist(err) {
-				continue
+			if !os.IsNotExist(err) {
+				logger.Log("warning", err.Error())
 			}
+			continue
 		}
 		if fi.Mode()&0444 == 0 {
 			logger.Log("warning", fmt.Sprintf("%s: Permission denied", root))
 			continue
 		}
-		if err := walker.Walk(root, walkFn, errCb); err != nil {
+		rules, err := loadIgnoreRules(root)
+		if err != nil {
+			return err
+		}
+		walkRootFn := func(fpath string, fi os.FileInfo) error {
+			rel, err := filepath.Rel(root, fpath)
+			if err != nil || rel == "." {
+				return walkFn(fpath, fi, nil)
+			}
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if rules.matches(parts) {
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			return walkFn(fpath, fi, parts)
+		}
+		if err := walker.Walk(root, walkRootFn, errCb); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// walkDepths caches the depths of the repositories of the hosts, at which
+// the walk stops descending, during a walk.
+type walkDepths struct {
+	mu     sync.Mutex
+	depths map[string]int
+}
+
+func newWalkDepths() *walkDepths {
+	return &walkDepths{depths: map[string]int{}}
+}
+
+// of returns the depth of the repositories of the host from the root, such
+// as 3 for "github.com/<owner>/<repo>", configured by 'ghq.<url>.depth'. It
+// returns 0 if they can be at any depth, e.g. in the subgroups of GitLab.
+func (d *walkDepths) of(host string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if depth, ok := d.depths[host]; ok {
+		return depth
+	}
+	depth := walkDepth(host)
+	d.depths[host] = depth
+	return depth
+}
+
+func walkDepth(host string) int {
+	v, err := gitconfig.Do("--get-urlmatch", "ghq.depth", "https://"+host+"/")
+	if err != nil && !gitconfig.IsNotFound(err) {
+		logger.Log("error", err.Error())
+	}
+	if v == "" {
+		return 0
+	}
+	depth, err := strconv.Atoi(v)
+	if err != nil || depth < 0 || depth == 1 {
+		logger.Log("warning", fmt.Sprintf("invalid ghq.depth %q of %s, walking at any depth", v, host))
+		return 0
+	}
+	return depth
+}
+
+// vcsMetadataDirs are the directories of VCS metadata, which are not
+// searched for nested repositories.
+var vcsMetadataDirs = []string{".git", ".hg", ".svn", "_darcs", ".pijul", ".bzr"}
+
+// findNestedRepositories returns the repositories in the working tree of the
+// repository at dir, except for submodules and linked worktrees. The
+// directories ignored by the rules of the root, if any, are not searched.
+func findNestedRepositories(dir, root string, rules ignoreRules) []string {
+	var (
+		mu     sync.Mutex
+		nested []string
+	)
+	walker.Walk(dir, func(fpath string, fi os.FileInfo) error {
+		if fpath == dir || !fi.IsDir() {
+			return nil
+		}
+		if slices.Contains(vcsMetadataDirs, fi.Name()) {
+			return filepath.SkipDir
+		}
+		if len(rules) > 0 {
+			if rel, err := filepath.Rel(root, fpath); err == nil && rules.matches(strings.Split(filepath.ToSlash(rel), "/")) {
+				return filepath.SkipDir
+			}
+		}
+		if !hasVCSContents(fpath) {
+			return nil
+		}
+		if linked, _, _ := isLinkedGitDir(fpath); !linked {
+			mu.Lock()
+			nested = append(nested, fpath)
+			mu.Unlock()
+		}
+		return filepath.SkipDir
+	}, walker.WithErrorCallback(func(string, error) error { return nil }))
+	slices.Sort(nested)
+	return nested
+}
+
+// hasVCSContents reports whether the directory has the metadata of a VCS.
+func hasVCSContents(dir string) bool {
+	for _, d := range vcsContents {
+		if _, err := os.Stat(filepath.Join(dir, d)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosingRepository returns the nearest directory above dir, but below
+// stop, which is a repository, or "" if none.
+func enclosingRepository(dir, stop string) string {
+	for d := filepath.Dir(dir); d != stop && d != filepath.Dir(d); d = filepath.Dir(d) {
+		if hasVCSContents(d) {
+			return d
+		}
+	}
+	return ""
+}
+
+// warnNestedRepositories warns that the repositories in the working tree of
+// dir will also be moved or removed, and that dir is itself in the working
+// tree of another repository below stop, which will see it disappear.
+func warnNestedRepositories(dir, stop, action string) {
+	for _, nested := range findNestedRepositories(dir, "", nil) {
+		logger.Log("warning", fmt.Sprintf("%s is nested in %s and will be %s along with it", nested, dir, action))
+	}
+	if parent := enclosingRepository(dir, stop); parent != "" {
+		logger.Log("warning", fmt.Sprintf("%s is nested in the working tree of %s", dir, parent))
+	}
+}
+
 var (
 	_home    string
 	_homeErr error
@@ -379,3 +379,69 @@ func TestLocalRepositoryRoots_URLMatchLocalRepositoryRoots(t *testing.T) {
 		t.Errorf("localRepositoryRoots(true) = %+v, want: %+v", got, want)
 	}
 }
+
+func TestWalkLocalRepositories_depth(t *testing.T) {
+	setSyncRoot(t)
+	root, err := primaryLocalRepositoryRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(gitconfig.WithConfig(t, `[ghq "https://git.example.org/"]
+	depth = 3
+[ghq "https://github.com/"]
+	depth = 0
+[ghq "https://gitlab.com/"]
+	depth = 4
+`))
+	for _, p := range []string{
+		"git.example.org/me/repo/.git",
+		"git.example.org/me/stray/deep/.git",
+		"git.example.org/me/.hidden/file",
+		"github.com/me/group/repo/.git",
+		"gitlab.com/group/sub/repo/.git",
+		"gitlab.com/group/sub/deeper/repo/.git",
+		"bitbucket.org/me/any/depth/repo/.git",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(p)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		mu     sync.Mutex
+		repos  []string
+		strays []string
+	)
+	opt := walkOptions{strays: func(fpath string) {
+		mu.Lock()
+		defer mu.Unlock()
+		rel, _ := filepath.Rel(root, fpath)
+		strays = append(strays, filepath.ToSlash(rel))
+	}}
+	if err := walkLocalRepositoriesWith("", opt, func(repo *LocalRepository) {
+		mu.Lock()
+		defer mu.Unlock()
+		repos = append(repos, filepath.ToSlash(repo.RelPath))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(repos)
+	sort.Strings(strays)
+	expectRepos := []string{
+		"bitbucket.org/me/any/depth/repo",
+		"git.example.org/me/repo",
+		"github.com/me/group/repo",
+		"gitlab.com/group/sub/repo",
+	}
+	if !reflect.DeepEqual(repos, expectRepos) {
+		t.Errorf("repos got: %q, expect: %q", repos, expectRepos)
+	}
+	expectStrays := []string{
+		"git.example.org/me/.hidden",
+		"git.example.org/me/stray",
+		"gitlab.com/group/sub/deeper",
+	}
+	if !reflect.DeepEqual(strays, expectStrays) {
+		t.Errorf("strays got: %q, expect: %q", strays, expectStrays)
+	}
+}
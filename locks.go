@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/x-motemen/ghq/filelock"
+	"github.com/x-motemen/ghq/logger"
+)
+
+// noWaitLock makes ghq fail immediately, instead of waiting, when a
+// repository is locked by another ghq process.
+var noWaitLock bool
+
+// lockFile returns the path of the lock file for the directory. Lock files are
+// kept in the cache directory, since the directory may not exist yet.
+func lockFile(kind, dir string) (string, error) {
+	cacheDir, err := ghqCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(dir)))
+	return filepath.Join(cacheDir, "locks", kind+"-"+hex.EncodeToString(sum[:8])+".lock"), nil
+}
+
+// lockRepository locks the repositories at the paths exclusively against
+// other ghq processes. They are locked in the sorted order, so that two
+// processes locking the same paths do not wait for each other. The returned
+// function releases the locks.
+func lockRepository(ctx context.Context, paths ...string) (func(), error) {
+	paths = slices.Clone(paths)
+	for i, p := range paths {
+		paths[i] = filepath.Clean(p)
+	}
+	slices.Sort(paths)
+	var locks []*filelock.File
+	unlock := func() {
+		for _, l := range slices.Backward(locks) {
+			l.Unlock()
+		}
+	}
+	for _, p := range slices.Compact(paths) {
+		l, err := acquireLock(ctx, "repo", p)
+		if err != nil {
+			unlock()
+			return nil, err
+		}
+		locks = append(locks, l)
+	}
+	return unlock, nil
+}
+
+func acquireLock(ctx context.Context, kind, dir string) (*filelock.File, error) {
+	name, err := lockFile(kind, dir)
+	if err != nil {
+		return nil, err
+	}
+	l, err := filelock.Lock(ctx, name, false)
+	if err == nil {
+		logger.Log("debug", fmt.Sprintf("locked %s for %s", name, dir))
+		return l, nil
+	}
+	if !errors.Is(err, filelock.ErrLocked) {
+		return nil, err
+	}
+	if noWaitLock {
+		return nil, fmt.Errorf("%s is in use by another ghq process: %w", dir, err)
+	}
+	logger.Log("wait", fmt.Sprintf("%s is in use by another ghq process", dir))
+	return filelock.Lock(ctx, name, true)
+}
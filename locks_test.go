@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/x-motemen/ghq/filelock"
+)
+
+func TestCommandGet_locked(t *testing.T) {
+	withFakeGitBackend(t, func(t *testing.T, tmproot string, _ *_cloneArgs, updateArgs *_updateArgs) {
+		localDir := filepath.Join(tmproot, "github.com", "motemen", "ghq-test-repo")
+		os.MkdirAll(filepath.Join(localDir, ".git"), 0755)
+
+		// held by another ghq process
+		unlock, err := lockRepository(context.Background(), localDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen.Delete(localDir)
+		err = newApp().Run(context.Background(),
+			[]string{"", "get", "-u", "--no-wait", "motemen/ghq-test-repo"})
+		if !errors.Is(err, filelock.ErrLocked) {
+			t.Errorf("error should be ErrLocked but: %v", err)
+		}
+		if updateArgs.local != "" {
+			t.Errorf("locked repository should not be updated")
+		}
+
+		time.AfterFunc(2*filelock.PollInterval, unlock)
+		seen.Delete(localDir)
+		err = newApp().Run(context.Background(),
+			[]string{"", "get", "-u", "motemen/ghq-test-repo"})
+		if err != nil {
+			t.Errorf("error should be nil but: %s", err)
+		}
+		if updateArgs.local != localDir {
+			t.Errorf("repository should be updated after the lock is released, got: %q", updateArgs.local)
+		}
+	})
+}
+
+func TestLockRepository_paths(t *testing.T) {
+	tmpd := newTempDir(t)
+	a, b := filepath.Join(tmpd, "a"), filepath.Join(tmpd, "b")
+
+	unlock, err := lockRepository(context.Background(), b, a, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noWaitLock = true
+	defer func() { noWaitLock = false }()
+	for _, p := range []string{a, b} {
+		if _, err := lockRepository(context.Background(), p); !errors.Is(err, filelock.ErrLocked) {
+			t.Errorf("%s should be locked, but: %v", p, err)
+		}
+	}
+
+	unlock()
+	unlock, err = lockRepository(context.Background(), a, b)
+	if err != nil {
+		t.Fatalf("the locks should be released, but: %s", err)
+	}
+	unlock()
+}
@@ -1,9 +1,12 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/motemen/go-colorine"
 )
@@ -31,6 +34,11 @@ var (
 			"skip":     VerboseColor,
 			"cd":       VerboseColor,
 			"resolved": VerboseColor,
+			"exec":     VerboseColor,
+			"stderr":   VerboseColor,
+			"debug":    VerboseColor,
+			"hook":     VerboseColor,
+			"identity": VerboseColor,
 			// notice
 			"authorized": NoticeColor,
 			// warn
@@ -38,7 +46,8 @@ var (
 			"exists":  WarnColor,
 			"warning": WarnColor,
 			// error
-			"error": ErrorColor,
+			"error":  ErrorColor,
+			"failed": ErrorColor,
 		},
 		InfoColor, // default is info
 	)
@@ -49,6 +58,43 @@ var (
 	)
 )
 
+// Level is the verbosity of the log.
+type Level int
+
+// Levels of the log. A log line is emitted if the level of its prefix is not
+// greater than the current level.
+const (
+	LevelQuiet   Level = iota - 1 // errors and warnings only
+	LevelNormal                   // the default
+	LevelVerbose                  // and the command lines run
+	LevelDebug                    // and the internal decisions of ghq
+)
+
+// prefixLevels are the levels of the prefixes, which are LevelNormal if not listed.
+var prefixLevels = map[string]Level{
+	"error":   LevelQuiet,
+	"failed":  LevelQuiet,
+	"warning": LevelQuiet,
+	"stderr":  LevelQuiet,
+	"exec":    LevelVerbose,
+	"got":     LevelVerbose,
+	"debug":   LevelDebug,
+}
+
+// Formats of the log.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var (
+	mu     sync.Mutex
+	level  = LevelNormal
+	format = FormatText
+	output io.Writer // set by selectLogger
+	file   io.Writer
+)
+
 func init() {
 	selectLogger()
 }
@@ -62,12 +108,121 @@ func selectLogger() {
 
 // SetOutput sets log output writer
 func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	output = w
 	logger.SetOutput(w)
 }
 
+// SetLevel sets the verbosity of the log.
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+}
+
+// Enabled reports whether the log of the level is emitted.
+func Enabled(l Level) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return l <= level
+}
+
+// SetFormat sets the format of the log, either FormatText or FormatJSON.
+// An empty format means FormatText.
+func SetFormat(f string) error {
+	switch f {
+	case "":
+		f = FormatText
+	case FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q, must be %q or %q", f, FormatText, FormatJSON)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	format = f
+	return nil
+}
+
+// JSON reports whether the log is emitted in JSON.
+func JSON() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return format == FormatJSON
+}
+
+// SetFile sets the writer to which the log is also written without color,
+// regardless of the output. nil stops writing.
+func SetFile(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	file = w
+}
+
+// An Event is a log line. The fields other than Prefix and Message are
+// optional, and shown only in JSON.
+type Event struct {
+	Prefix     string
+	Message    string
+	Repository string
+	Path       string
+	Duration   time.Duration
+	Err        error
+}
+
+// Emit outputs the event
+func Emit(e Event) {
+	mu.Lock()
+	l, f, out, fw := level, format, output, file
+	mu.Unlock()
+	if prefixLevels[e.Prefix] > l {
+		return
+	}
+	now := time.Now()
+	if f == FormatJSON {
+		line := e.json(now)
+		out.Write(line)
+		if fw != nil {
+			fw.Write(line)
+		}
+		return
+	}
+	logger.Log(e.Prefix, e.Message)
+	if fw != nil {
+		fmt.Fprintf(fw, "%s %10s %s\n", now.Format(time.RFC3339), e.Prefix, e.Message)
+	}
+}
+
+type jsonEvent struct {
+	Time       string  `json:"time"`
+	Prefix     string  `json:"prefix"`
+	Message    string  `json:"message,omitempty"`
+	Repository string  `json:"repository,omitempty"`
+	Path       string  `json:"path,omitempty"`
+	Duration   float64 `json:"duration,omitempty"` // in seconds
+	Error      string  `json:"error,omitempty"`
+}
+
+// json encodes the event in a line.
+func (e Event) json(now time.Time) []byte {
+	je := jsonEvent{
+		Time:       now.Format(time.RFC3339Nano),
+		Prefix:     e.Prefix,
+		Message:    e.Message,
+		Repository: e.Repository,
+		Path:       e.Path,
+		Duration:   e.Duration.Seconds(),
+	}
+	if e.Err != nil {
+		je.Error = e.Err.Error()
+	}
+	b, _ := json.Marshal(je)
+	return append(b, '\n')
+}
+
 // Log outputs log
 func Log(prefix, message string) {
-	logger.Log(prefix, message)
+	Emit(Event{Prefix: prefix, Message: message})
 }
 
 // Logf outputs log with format
@@ -1,8 +1,14 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLog(t *testing.T) {
@@ -39,3 +45,76 @@ func TestLog(t *testing.T) {
 		Log("error", "should be none")
 	})
 }
+
+func TestEmit(t *testing.T) {
+	defer SetOutput(os.Stderr)
+	defer SetLevel(LevelNormal)
+	defer SetFormat(FormatText)
+
+	t.Run("levels", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "true")
+		selectLogger()
+		testCases := []struct {
+			level  Level
+			expect []string
+		}{
+			{LevelQuiet, []string{"error"}},
+			{LevelNormal, []string{"error", "clone"}},
+			{LevelVerbose, []string{"error", "clone", "exec"}},
+			{LevelDebug, []string{"error", "clone", "exec", "debug"}},
+		}
+		for _, tc := range testCases {
+			buf := &bytes.Buffer{}
+			SetOutput(buf)
+			SetLevel(tc.level)
+			for _, prefix := range []string{"error", "clone", "exec", "debug"} {
+				Log(prefix, "message")
+			}
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				// the color may be reset even without color
+				prefix, _, _ := strings.Cut(strings.Fields(line)[0], "\x1b")
+				got = append(got, prefix)
+			}
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("level %d: got: %v, expect: %v", tc.level, got, tc.expect)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buf, file := &bytes.Buffer{}, &bytes.Buffer{}
+		SetOutput(buf)
+		SetFile(file)
+		defer SetFile(nil)
+		SetLevel(LevelNormal)
+		if err := SetFormat(FormatJSON); err != nil {
+			t.Fatal(err)
+		}
+		Emit(Event{Prefix: "failed", Message: "oops", Repository: "github.com/x-motemen/ghq",
+			Path: "/ghq/github.com/x-motemen/ghq", Duration: 1500 * time.Millisecond, Err: errors.New("exit status 128")})
+		var got map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("should be a JSON line, but: %q", buf.String())
+		}
+		delete(got, "time")
+		expect := map[string]any{
+			"prefix":     "failed",
+			"message":    "oops",
+			"repository": "github.com/x-motemen/ghq",
+			"path":       "/ghq/github.com/x-motemen/ghq",
+			"duration":   1.5,
+			"error":      "exit status 128",
+		}
+		if !reflect.DeepEqual(got, expect) {
+			t.Errorf("got: %v, expect: %v", got, expect)
+		}
+		if file.String() != buf.String() {
+			t.Errorf("the file should have the same line, but: %q", file.String())
+		}
+	})
+
+	if err := SetFormat("xml"); err == nil {
+		t.Error("unknown format should be an error")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/urfave/cli/v3"
 	"github.com/x-motemen/ghq/logger"
@@ -14,14 +16,46 @@ const version = "1.10.1"
 var revision = "HEAD"
 
 func main() {
-	if err := newApp().Run(context.Background(), os.Args); err != nil {
+	// Cancel the context on interruption so that child processes are
+	// stopped and partially cloned repositories are cleaned up.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		// restore the default behavior, so that the second interruption terminates ghq immediately
+		stop()
+	}()
+	if err := newApp().Run(ctx, versionAlias(os.Args)); err != nil {
+		stop()
 		exitCode := 1
 		if excoder, ok := err.(cli.ExitCoder); ok {
 			exitCode = excoder.ExitCode()
 		}
 		logger.Log("error", err.Error())
+		logHiddenStderr(err)
+		closeLogFile()
 		os.Exit(exitCode)
 	}
+	closeLogFile()
+	stop()
+}
+
+func init() {
+	// -v is for --verbose, except for "ghq -v" by itself (see versionAlias)
+	cli.VersionFlag = &cli.BoolFlag{
+		Name:        "version",
+		Usage:       "print the version",
+		HideDefault: true,
+		Local:       true,
+	}
+}
+
+// versionAlias replaces "ghq -v" by itself, which printed the version before
+// -v became --verbose, with "ghq --version", so that it still does.
+func versionAlias(args []string) []string {
+	if len(args) == 2 && args[1] == "-v" {
+		return []string{args[0], "--version"}
+	}
+	return args
 }
 
 func newApp() *cli.Command {
@@ -32,5 +66,55 @@ func newApp() *cli.Command {
 		Authors:  []any{"motemen <motemen@gmail.com>", "Songmu <y.songmu@gmail.com>"},
 		Suggest:  true,
 		Commands: commands,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "quiet", Aliases: []string{"q"}, Usage: "Log errors and warnings only"},
+			&cli.BoolFlag{Name: "verbose", Aliases: []string{"v"}, Config: cli.BoolConfig{Count: new(int)},
+				Usage: "Log the command lines run (-v), and the decisions made by ghq (-vv)"},
+			&cli.StringFlag{Name: "log-file", Sources: cli.EnvVars("GHQ_LOG_FILE"),
+				Usage: "Append the log to `file` as well"},
+		},
+		UseShortOptionHandling: true,
+		Before:                 setupLogger,
+		// exit codes are handled by main, not to exit in the middle of tests
+		ExitErrHandler: func(context.Context, *cli.Command, error) {},
+	}
+}
+
+var logFile *os.File
+
+// setupLogger sets the level, the format and the file of the log by the
+// global options and GHQ_LOG_FORMAT.
+func setupLogger(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	if err := logger.SetFormat(os.Getenv("GHQ_LOG_FORMAT")); err != nil {
+		return ctx, fmt.Errorf("invalid GHQ_LOG_FORMAT: %w", err)
+	}
+	verbosity := cmd.Count("verbose")
+	if cmd.Bool("quiet") && verbosity > 0 {
+		return ctx, fmt.Errorf("--quiet and --verbose cannot be specified together")
+	}
+	level := logger.LevelNormal
+	if cmd.Bool("quiet") {
+		level = logger.LevelQuiet
+	}
+	logger.SetLevel(min(level+logger.Level(verbosity), logger.LevelDebug))
+
+	closeLogFile()
+	if name := cmd.String("log-file"); name != "" {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return ctx, fmt.Errorf("failed to open the log file: %w", err)
+		}
+		logFile = f
+		logger.SetFile(f)
+	}
+	return ctx, nil
+}
+
+func closeLogFile() {
+	if logFile == nil {
+		return
 	}
+	logger.SetFile(nil)
+	logFile.Close()
+	logFile = nil
 }
@@ -1,15 +1,169 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/Songmu/gitconfig"
+	"github.com/x-motemen/ghq/logger"
 )
 
 func TestMain(m *testing.M) {
 	teardown := gitconfig.WithConfig(nil, "")
+	cacheDir, err := os.MkdirTemp("", "ghq-cache")
+	if err != nil {
+		panic(err)
+	}
+	ghqCacheDir = func() (string, error) { return cacheDir, nil }
+	// not to run the hooks of the user
+	defaultHooksDir = func() (string, error) { return filepath.Join(cacheDir, "hooks"), nil }
 	code := m.Run()
+	os.RemoveAll(cacheDir)
 	teardown()
 	os.Exit(code)
 }
+
+func TestSetupLogger(t *testing.T) {
+	defer logger.SetLevel(logger.LevelNormal)
+	testCases := []struct {
+		name   string
+		args   []string
+		expect logger.Level
+		hasErr bool
+	}{{
+		name:   "default",
+		args:   []string{"", "root"},
+		expect: logger.LevelNormal,
+	}, {
+		name:   "quiet",
+		args:   []string{"", "-q", "root"},
+		expect: logger.LevelQuiet,
+	}, {
+		name:   "verbose",
+		args:   []string{"", "--verbose", "root"},
+		expect: logger.LevelVerbose,
+	}, {
+		name:   "verbose after the command",
+		args:   []string{"", "root", "-v"},
+		expect: logger.LevelVerbose,
+	}, {
+		name:   "debug",
+		args:   []string{"", "-vv", "root"},
+		expect: logger.LevelDebug,
+	}, {
+		name:   "quiet and verbose",
+		args:   []string{"", "-q", "-v", "root"},
+		hasErr: true,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logger.SetLevel(logger.LevelNormal)
+			var err error
+			if _, _, cerr := capture(func() {
+				err = newApp().Run(context.Background(), tc.args)
+			}); cerr != nil {
+				t.Fatal(cerr)
+			}
+			if tc.hasErr {
+				if err == nil {
+					t.Error("error should occur")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error should be nil but: %s", err)
+			}
+			if !logger.Enabled(tc.expect) || logger.Enabled(tc.expect+1) {
+				t.Errorf("log level should be %d", tc.expect)
+			}
+		})
+	}
+}
+
+func TestVersionAlias(t *testing.T) {
+	testCases := []struct {
+		name   string
+		args   []string
+		expect []string
+	}{{
+		name:   "by itself",
+		args:   []string{"ghq", "-v"},
+		expect: []string{"ghq", "--version"},
+	}, {
+		name:   "with a command",
+		args:   []string{"ghq", "-v", "root"},
+		expect: []string{"ghq", "-v", "root"},
+	}, {
+		name:   "after a command",
+		args:   []string{"ghq", "root", "-v"},
+		expect: []string{"ghq", "root", "-v"},
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := versionAlias(tc.args); !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("versionAlias(%q) = %q, expect: %q", tc.args, got, tc.expect)
+			}
+		})
+	}
+
+	out, _, err := capture(func() {
+		if err := newApp().Run(context.Background(), versionAlias([]string{"ghq", "-v"})); err != nil {
+			t.Errorf("error should be nil but: %s", err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, version) {
+		t.Errorf("version should be printed, but: %q", out)
+	}
+}
+
+func TestSetupLogger_file(t *testing.T) {
+	t.Setenv("GHQ_LOG_FORMAT", "json")
+	defer logger.SetFormat(logger.FormatText)
+	name := filepath.Join(t.TempDir(), "ghq.log")
+
+	buf := &bytes.Buffer{}
+	logger.SetOutput(buf)
+	defer logger.SetOutput(os.Stderr)
+	withFakeGitBackend(t, func(t *testing.T, tmproot string, _ *_cloneArgs, _ *_updateArgs) {
+		_, _, err := capture(func() {
+			if err := newApp().Run(context.Background(),
+				[]string{"", "--log-file", name, "get", "motemen/ghq-test-repo"}); err != nil {
+				t.Errorf("error should be nil but: %s", err)
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	closeLogFile()
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, buf.Bytes()) {
+		t.Errorf("log file should have the same log, but:\n%s\n%s", b, buf.Bytes())
+	}
+	var e struct {
+		Prefix     string `json:"prefix"`
+		Repository string `json:"repository"`
+		Path       string `json:"path"`
+	}
+	line, _, _ := bytes.Cut(b, []byte("\n"))
+	if err := json.Unmarshal(line, &e); err != nil {
+		t.Fatalf("log should be JSON lines, but: %s", b)
+	}
+	if e.Prefix != "clone" || e.Repository != "https://github.com/motemen/ghq-test-repo" ||
+		!strings.HasSuffix(filepath.ToSlash(e.Path), "github.com/motemen/ghq-test-repo") {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
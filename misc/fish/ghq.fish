@@ -1,6 +1,8 @@
+# Code generated by 'ghq shell-init --completion fish'; DO NOT EDIT.
+
 function __fish_ghq_needs_subcommand
     set -l cmd (commandline -opc)
-    for subcmd in get clone list rm root create migrate h help
+    for subcmd in get clone list look visit rm sync root create migrate relocate audit doctor probe identity shell-init help h
         if contains -- $subcmd $cmd
             return 1
         end
@@ -14,61 +16,120 @@ complete -c ghq -e
 complete -c ghq -f
 
 # Global arguments
-complete -c ghq -s h -l help -d 'Show help'
-complete -c ghq -n __fish_ghq_needs_subcommand -s v -l version -d 'Print the version'
+complete -c ghq -l quiet -s q -d 'Log errors and warnings only'
+complete -c ghq -l verbose -s v -d 'Log the command lines run (-v), and the decisions made by ghq (-vv)'
+complete -c ghq -l log-file -r -F -d 'Append the log to file as well'
+complete -c ghq -l help -s h -d 'Show help'
+complete -c ghq -n __fish_ghq_needs_subcommand -l version -d 'Print the version'
 
 # Global subcommands
 complete -c ghq -n __fish_ghq_needs_subcommand -a 'get clone' -d 'Clone/sync with a remote repository'
-complete -c ghq -n __fish_ghq_needs_subcommand -a list -d 'List local repositories'
-complete -c ghq -n __fish_ghq_needs_subcommand -a rm -d 'Remove local repository'
-complete -c ghq -n __fish_ghq_needs_subcommand -a root -d 'Show repositories\' root'
-complete -c ghq -n __fish_ghq_needs_subcommand -a create -d 'Create a new repository'
-complete -c ghq -n __fish_ghq_needs_subcommand -a migrate -d 'Migrate existing repository to ghq-managed directory'
-complete -c ghq -n __fish_ghq_needs_subcommand -a 'h help' -d 'Shows a list of commands or help for one command'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'list' -d 'List local repositories'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'look' -d 'Look into a local repository'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'visit' -d 'Record a visit to a local repository'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'rm' -d 'Remove local repository'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'sync' -d 'Reconcile local repositories with a set of repositories'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'root' -d 'Show repositories\' root'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'create' -d 'Create a new repository'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'migrate' -d 'Migrate existing repository to ghq-managed directory'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'relocate' -d 'Update the remote URL of a repository and move it to the new path'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'audit' -d 'Report local repositories whose upstream is stale'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'doctor' -d 'Check the roots and the local repositories for problems'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'probe' -d 'Show how the VCS of a remote repository is detected'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'identity' -d 'Manage the identity configured per host or organization'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'shell-init' -d 'Print the shell integration script'
+complete -c ghq -n __fish_ghq_needs_subcommand -a 'help h' -d 'Shows a list of commands or help for one command'
 
 # Arguments for subcommands
-complete -c ghq -n '__fish_seen_subcommand_from get clone' -s u -l update -d 'Update local repository if cloned already'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l update -s u -d 'Update local repository if cloned already'
 complete -c ghq -n '__fish_seen_subcommand_from get clone' -s p -d 'Clone with SSH'
 complete -c ghq -n '__fish_seen_subcommand_from get clone' -l shallow -d 'Do a shallow clone'
-complete -c ghq -n '__fish_seen_subcommand_from get clone' -s l -l look -d 'Look after get'
-complete -c ghq -n '__fish_seen_subcommand_from get clone' -l vcs -d 'Specify vcs backend for cloning'
-complete -c ghq -n '__fish_seen_subcommand_from get clone' -s s -l silent -d 'Clone or update silently'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l look -s l -d 'Look after get'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l vcs -xa 'bazaar bzr codecommit darcs fossil git git-svn github gitlab hg mercurial pijul subversion svn' -d 'Specify vcs backend for cloning'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l silent -s s -d 'Clone or update silently'
 complete -c ghq -n '__fish_seen_subcommand_from get clone' -l no-recursive -d 'Prevent recursive fetching'
-complete -c ghq -n '__fish_seen_subcommand_from get clone' -s b -l branch -d 'Specify branch name. This flag implies --single-branch on Git'
-complete -c ghq -n '__fish_seen_subcommand_from get clone' -s P -l parallel -d 'Import parallelly'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l branch -s b -x -d 'Specify branch name'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l parallel -s P -d 'Import parallelly'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l keep-going -d 'Go on after failures, then summarize them (default with --parallel)'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l fail-fast -d 'Stop at the first failure (default without --parallel)'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l failed-file -r -F -d 'Write the targets failed to get to file, to retry by \'ghq get < file\''
 complete -c ghq -n '__fish_seen_subcommand_from get clone' -l bare -d 'Do a bare clone'
-function __complete_get_partial
-    printf '%s\t%s\n' 'blobless' 'Do a blobless clone'
-    printf '%s\t%s\n' 'treeless' 'Do a treeless clone'
-end
-complete -c ghq -n '__fish_seen_subcommand_from get clone' -l partial -d 'Do a partial clone' -xa '(__complete_get_partial)'
-# When updating an existing repository (-u/--update), complete with local repositories
-complete -c ghq -n '__fish_seen_subcommand_from get clone' -n '__fish_seen_argument -s u -l update' -xa '(ghq list)'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l offline -d 'Detect VCS without probing the network'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l timeout -x -d 'Abort getting each repository after duration (e.g. 10m)'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l retries -x -d 'Retry cloning or updating up to count times on transient network failures'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l no-wait -d 'Fail instead of waiting when the repository is in use by another ghq process'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l partial -xa 'blobless treeless' -d 'Do a partial clone'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l org -x -d 'Get the repositories of the organization owner (e.g. github.com/my-org)'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l user -x -d 'Get the repositories of the user owner (e.g. github.com/me)'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l archived -d 'Include archived repositories with --org and --user'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l forks -d 'Include forked repositories with --org and --user'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l topic -x -d 'Get only the repositories with the topic with --org and --user'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l language -x -d 'Get only the repositories in the language with --org and --user'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -l visibility -xa 'public private internal' -d 'Get only the repositories of the visibility: public, private or internal'
+complete -c ghq -n '__fish_seen_subcommand_from get clone' -n '__fish_seen_argument -l update -s u' -xa '(command ghq __complete repositories (commandline -ct))'
 
-complete -c ghq -n '__fish_seen_subcommand_from list' -s e -l exact -d 'Perform an exact match'
-complete -c ghq -n '__fish_seen_subcommand_from list' -l vcs -d 'Specify vcs backend for matching'
-complete -c ghq -n '__fish_seen_subcommand_from list' -s p -l full-path -d 'Print full paths'
+complete -c ghq -n '__fish_seen_subcommand_from list' -l exact -s e -d 'Perform an exact match'
+complete -c ghq -n '__fish_seen_subcommand_from list' -l vcs -xa 'bazaar bzr codecommit darcs fossil git git-svn github gitlab hg mercurial pijul subversion svn' -d 'Specify vcs backend for matching'
+complete -c ghq -n '__fish_seen_subcommand_from list' -l full-path -s p -d 'Print full paths'
 complete -c ghq -n '__fish_seen_subcommand_from list' -l unique -d 'Print unique subpaths'
 complete -c ghq -n '__fish_seen_subcommand_from list' -l bare -d 'Query bare repositories'
+complete -c ghq -n '__fish_seen_subcommand_from list' -l stale -d 'List only the repositories whose upstream is stale'
+complete -c ghq -n '__fish_seen_subcommand_from list' -l nested -d 'Also list the repositories in the working trees of others'
+complete -c ghq -n '__fish_seen_subcommand_from list' -l submodules -d 'Also list the submodules of the repositories'
+complete -c ghq -n '__fish_seen_subcommand_from list' -l sort -xa 'name frecency recent mtime' -d 'Sort the repositories by order: name, frecency, recent or mtime'
+
+complete -c ghq -n '__fish_seen_subcommand_from look' -l exact -s e -d 'Perform an exact match'
+complete -c ghq -n '__fish_seen_subcommand_from look' -l print -d 'Print the full path instead of starting a shell'
+complete -c ghq -n '__fish_seen_subcommand_from look' -l select -d 'Choose from the candidates even if one of them has been visited'
+complete -c ghq -n '__fish_seen_subcommand_from look' -l bare -d 'Query bare repositories'
+complete -c ghq -n '__fish_seen_subcommand_from look' -xa '(command ghq __complete repositories (commandline -ct))'
+
+complete -c ghq -n '__fish_seen_subcommand_from visit' -xa '(__fish_complete_directories)'
 
 complete -c ghq -n '__fish_seen_subcommand_from rm' -l dry-run -d 'Do not remove actually'
 complete -c ghq -n '__fish_seen_subcommand_from rm' -l bare -d 'Remove a bare repository'
-complete -c ghq -n '__fish_seen_subcommand_from rm' -xa '(ghq list)'
+complete -c ghq -n '__fish_seen_subcommand_from rm' -l no-wait -d 'Fail instead of waiting when the repository is in use by another ghq process'
+complete -c ghq -n '__fish_seen_subcommand_from rm' -xa '(command ghq __complete repositories (commandline -ct))'
+
+complete -c ghq -n '__fish_seen_subcommand_from sync' -l from -r -F -d 'Read the set from manifest file, or list it from <host>/<owner> on a forge'
+complete -c ghq -n '__fish_seen_subcommand_from sync' -l user -d 'The owner given to --from is a user'
+complete -c ghq -n '__fish_seen_subcommand_from sync' -l forks -d 'Include forked repositories of the owner'
+complete -c ghq -n '__fish_seen_subcommand_from sync' -s p -d 'Clone with SSH'
+complete -c ghq -n '__fish_seen_subcommand_from sync' -l parallel -s P -d 'Clone and update parallelly'
+complete -c ghq -n '__fish_seen_subcommand_from sync' -l prune -d 'Remove the local repositories out of the set'
+complete -c ghq -n '__fish_seen_subcommand_from sync' -s y -d 'Remove without confirmation with --prune'
+complete -c ghq -n '__fish_seen_subcommand_from sync' -l dry-run -d 'Show what would happen without cloning, updating or removing'
+complete -c ghq -n '__fish_seen_subcommand_from sync' -l no-wait -d 'Fail instead of waiting when the repository is in use by another ghq process'
 
 complete -c ghq -n '__fish_seen_subcommand_from root' -l all -d 'Show all roots'
 
-complete -c ghq -n '__fish_seen_subcommand_from create' -l vcs -d 'Specify vcs backend explicitly'
+complete -c ghq -n '__fish_seen_subcommand_from create' -l vcs -xa 'bazaar bzr codecommit darcs fossil git git-svn github gitlab hg mercurial pijul subversion svn' -d 'Specify vcs backend explicitly'
 complete -c ghq -n '__fish_seen_subcommand_from create' -l bare -d 'Create a bare repository'
+complete -c ghq -n '__fish_seen_subcommand_from create' -l offline -d 'Detect VCS without probing the network'
+complete -c ghq -n '__fish_seen_subcommand_from create' -l no-wait -d 'Fail instead of waiting when the repository is in use by another ghq process'
+complete -c ghq -n '__fish_seen_subcommand_from create' -xa '(command ghq __complete namespaces (commandline -ct))'
 
 complete -c ghq -n '__fish_seen_subcommand_from migrate' -s y -d 'Skip confirmation prompt'
 complete -c ghq -n '__fish_seen_subcommand_from migrate' -l dry-run -d 'Show what would happen without moving'
+complete -c ghq -n '__fish_seen_subcommand_from migrate' -l no-wait -d 'Fail instead of waiting when the repository is in use by another ghq process'
+complete -c ghq -n '__fish_seen_subcommand_from migrate' -xa '(__fish_complete_directories)'
+
+complete -c ghq -n '__fish_seen_subcommand_from relocate' -l all -d 'Relocate all the repositories whose paths disagree with their remote URLs'
+complete -c ghq -n '__fish_seen_subcommand_from relocate' -s y -d 'Skip confirmation prompt'
+complete -c ghq -n '__fish_seen_subcommand_from relocate' -l dry-run -d 'Show what would happen without relocating'
+complete -c ghq -n '__fish_seen_subcommand_from relocate' -l no-wait -d 'Fail instead of waiting when the repository is in use by another ghq process'
+complete -c ghq -n '__fish_seen_subcommand_from relocate' -xa '(command ghq __complete repositories (commandline -ct))'
+
+
+
+complete -c ghq -n '__fish_seen_subcommand_from probe' -l offline -d 'Detect VCS without probing the network'
+complete -c ghq -n '__fish_seen_subcommand_from probe' -l no-cache -d 'Probe the network even if the result is cached'
+
+complete -c ghq -n '__fish_seen_subcommand_from identity' -n 'not __fish_seen_subcommand_from check' -a 'check' -d 'Report repositories whose identity does not match the configuration'
+complete -c ghq -n '__fish_seen_subcommand_from check' -l fix -d 'Apply the configured identity to the mismatched repositories'
+
+complete -c ghq -n '__fish_seen_subcommand_from shell-init' -l completion -d 'Print the completion script only'
+complete -c ghq -n '__fish_seen_subcommand_from shell-init' -l no-bind -d 'Do not bind the key to jump to a repository'
+complete -c ghq -n '__fish_seen_subcommand_from shell-init' -xa 'bash zsh fish'
 
-# Complete VCS backend options for supported subcommands
-complete -c ghq -n '__fish_seen_subcommand_from get clone list create' -n '__fish_seen_argument --vcs' -l vcs -x -a 'git github codecommit' -d git
-complete -c ghq -n '__fish_seen_subcommand_from get clone list create' -n '__fish_seen_argument --vcs' -l vcs -x -a 'svn subversion' -d subversion
-complete -c ghq -n '__fish_seen_subcommand_from get clone list create' -n '__fish_seen_argument --vcs' -l vcs -x -a git-svn -d git-svn
-complete -c ghq -n '__fish_seen_subcommand_from get clone list create' -n '__fish_seen_argument --vcs' -l vcs -x -a 'hg mercurial' -d mercurial
-complete -c ghq -n '__fish_seen_subcommand_from get clone list create' -n '__fish_seen_argument --vcs' -l vcs -x -a darcs -d darcs
-complete -c ghq -n '__fish_seen_subcommand_from get clone list create' -n '__fish_seen_argument --vcs' -l vcs -x -a pijul -d pijul
-complete -c ghq -n '__fish_seen_subcommand_from get clone list create' -n '__fish_seen_argument --vcs' -l vcs -x -a fossil -d fossil
-complete -c ghq -n '__fish_seen_subcommand_from get clone list create' -n '__fish_seen_argument --vcs' -l vcs -x -a 'bzr bazaar' -d bazaar
+complete -c ghq -n '__fish_seen_subcommand_from help h' -xa 'get clone list look visit rm sync root create migrate relocate audit doctor probe identity shell-init help h'
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+const progressRefreshInterval = 200 * time.Millisecond
+
+// progressUI is the live display of `ghq get --parallel` on a terminal. It
+// draws a row for each repository in flight under an overall counter, and
+// redraws them below lines logged meanwhile. It implements io.Writer to be set
+// as the output of the logger.
+type progressUI struct {
+	w     io.Writer
+	width int
+
+	mu      sync.Mutex
+	rows    []*progressRow
+	total   int
+	done    int
+	drawn   int    // number of lines currently drawn
+	pending []byte // a logged line not terminated yet
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// A progressRow is the progress of a repository in flight.
+type progressRow struct {
+	ui      *progressUI
+	name    string
+	phase   string
+	percent int // negative if unknown
+	start   time.Time
+}
+
+// isTerminal reports whether f is a terminal on which the progress can be drawn.
+func isTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fd := f.Fd()
+	if isatty.IsCygwinTerminal(fd) {
+		return true
+	}
+	return isatty.IsTerminal(fd) && enableVirtualTerminal(fd)
+}
+
+func newProgressUI(w io.Writer, width int) *progressUI {
+	if width <= 0 {
+		width = 80
+	}
+	return &progressUI{w: w, width: width, stopCh: make(chan struct{})}
+}
+
+// start redraws the display periodically to update the elapsed times.
+func (p *progressUI) start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(progressRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.redraw()
+				p.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// stop erases the display.
+func (p *progressUI) stop() {
+	if p == nil {
+		return
+	}
+	p.stopOnce.Do(func() { close(p.stopCh) })
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	if len(p.pending) > 0 {
+		p.w.Write(append(p.pending, '\n'))
+		p.pending = nil
+	}
+}
+
+// enqueue counts a repository to get in the overall counter.
+func (p *progressUI) enqueue() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total++
+	p.redraw()
+}
+
+// add starts a row for the repository.
+func (p *progressUI) add(name string) *progressRow {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	row := &progressRow{ui: p, name: name, phase: "starting", percent: -1, start: time.Now()}
+	p.rows = append(p.rows, row)
+	p.redraw()
+	return row
+}
+
+// println prints the line above the display.
+func (p *progressUI) println(w io.Writer, line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	fmt.Fprintln(w, line)
+	p.redraw()
+}
+
+// Write writes complete lines above the display, and keeps the rest until
+// its line is terminated.
+func (p *progressUI) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = append(p.pending, b...)
+	i := bytes.LastIndexByte(p.pending, '\n')
+	if i < 0 {
+		return len(b), nil
+	}
+	p.clear()
+	_, err := p.w.Write(p.pending[:i+1])
+	p.pending = append(p.pending[:0], p.pending[i+1:]...)
+	p.redraw()
+	return len(b), err
+}
+
+// clear erases the drawn lines and moves the cursor to the first of them.
+func (p *progressUI) clear() {
+	if p.drawn == 0 {
+		return
+	}
+	fmt.Fprintf(p.w, "\x1b[%dA\x1b[J", p.drawn)
+	p.drawn = 0
+}
+
+func (p *progressUI) redraw() {
+	var buf bytes.Buffer
+	if p.drawn > 0 {
+		fmt.Fprintf(&buf, "\x1b[%dA", p.drawn)
+	}
+	lines := p.render(time.Now())
+	for _, line := range lines {
+		buf.WriteString("\x1b[2K")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("\x1b[J")
+	p.w.Write(buf.Bytes())
+	p.drawn = len(lines)
+}
+
+func (p *progressUI) render(now time.Time) []string {
+	lines := []string{fmt.Sprintf("[%d/%d] getting repositories", p.done, p.total)}
+	for _, row := range p.rows {
+		status := row.phase
+		if row.percent >= 0 {
+			status += fmt.Sprintf(" %3d%%", row.percent)
+		}
+		elapsed := now.Sub(row.start).Truncate(time.Second)
+		lines = append(lines, truncateLine(
+			fmt.Sprintf("  %s  %s  %s", row.name, status, elapsed), p.width-1))
+	}
+	return lines
+}
+
+// truncateLine truncates the line to width runes, so that the line is not
+// wrapped by the terminal.
+func truncateLine(line string, width int) string {
+	r := []rune(line)
+	if len(r) <= width {
+		return line
+	}
+	if width <= 3 {
+		return string(r[:width])
+	}
+	return string(r[:width-3]) + "..."
+}
+
+// report updates the phase of the repository. percent is negative if unknown.
+func (r *progressRow) report(phase string, percent int) {
+	if r == nil {
+		return
+	}
+	p := r.ui
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r.phase, r.percent = phase, percent
+}
+
+// finish removes the row from the display.
+func (r *progressRow) finish() {
+	if r == nil {
+		return
+	}
+	p := r.ui
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, row := range p.rows {
+		if row == r {
+			p.rows = append(p.rows[:i], p.rows[i+1:]...)
+			break
+		}
+	}
+	p.done++
+	p.redraw()
+}
+
+var gitProgressReg = regexp.MustCompile(`^(?:remote: )?([A-Z][a-z]+(?: [a-z]+)*):\s+(\d+)%`)
+
+// gitProgressWriter parses the output of `git clone --progress`, in which
+// lines such as "Receiving objects:  45% (450/1000)" are updated by "\r".
+type gitProgressWriter struct {
+	report func(phase string, percent int)
+	buf    []byte
+}
+
+func (w *gitProgressWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		w.parse(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+func (w *gitProgressWriter) parse(line string) {
+	m := gitProgressReg.FindStringSubmatch(strings.TrimSpace(line))
+	if len(m) < 3 {
+		return
+	}
+	percent, err := strconv.Atoi(m[2])
+	if err != nil {
+		return
+	}
+	w.report(strings.ToLower(m[1]), percent)
+}
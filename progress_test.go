@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitProgressWriter(t *testing.T) {
+	type report struct {
+		phase   string
+		percent int
+	}
+	var got []report
+	w := &gitProgressWriter{report: func(phase string, percent int) {
+		got = append(got, report{phase, percent})
+	}}
+	out := "Cloning into 'repo'...\n" +
+		"remote: Enumerating objects: 100, done.\n" +
+		"remote: Counting objects:  50% (50/100)\rremote: Counting objects: 100% (100/100), done.\n" +
+		"Receiving objects:  12% (12/100)\rReceiving objects:  99% (99/100), 1.20 MiB | 2.00 MiB/s\r" +
+		"Resolving deltas:   3% (1/30)\r"
+	// written in arbitrary chunks
+	for len(out) > 0 {
+		n := min(7, len(out))
+		w.Write([]byte(out[:n]))
+		out = out[n:]
+	}
+	expect := []report{
+		{"counting objects", 50},
+		{"counting objects", 100},
+		{"receiving objects", 12},
+		{"receiving objects", 99},
+		{"resolving deltas", 3},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("got: %v, expect: %v", got, expect)
+	}
+}
+
+func TestProgressUI(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgressUI(&buf, 60)
+	p.enqueue()
+	p.enqueue()
+	row := p.add("github.com/motemen/ghq")
+	row.report("receiving objects", 42)
+	p.add("github.com/x-motemen/a-very-long-repository-name-which-overflows")
+
+	lines := p.render(row.start.Add(3 * time.Second))
+	expect := []string{
+		"[0/2] getting repositories",
+		"  github.com/motemen/ghq  receiving objects  42%  3s",
+	}
+	if !reflect.DeepEqual(lines[:2], expect) {
+		t.Errorf("got: %q, expect: %q", lines[:2], expect)
+	}
+	if len([]rune(lines[2])) != 59 || !strings.HasSuffix(lines[2], "...") {
+		t.Errorf("long line should be truncated to the width: %q", lines[2])
+	}
+
+	buf.Reset()
+	// a log line written in pieces appears above the display at once
+	p.Write([]byte("     clone"))
+	if buf.Len() != 0 {
+		t.Errorf("incomplete line should not be written: %q", buf.String())
+	}
+	p.Write([]byte(" github.com/motemen/ghq\n"))
+	out := buf.String()
+	clear := "\x1b[3A\x1b[J"
+	if !strings.HasPrefix(out, clear+"     clone github.com/motemen/ghq\n") {
+		t.Errorf("display should be cleared before the log line: %q", out)
+	}
+	if !strings.Contains(out, "[0/2] getting repositories") {
+		t.Errorf("display should be redrawn after the log line: %q", out)
+	}
+
+	row.finish()
+	if len(p.rows) != 1 || p.done != 1 {
+		t.Errorf("finished row should be removed: rows=%d done=%d", len(p.rows), p.done)
+	}
+
+	p.start()
+	p.stop()
+	p.stop()
+	if p.drawn != 0 {
+		t.Errorf("display should be erased after stop")
+	}
+}
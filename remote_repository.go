@@ -1,13 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
 
 	"github.com/Songmu/gitconfig"
-	"github.com/x-motemen/ghq/cmdutil"
 	"github.com/x-motemen/ghq/logger"
 )
 
@@ -18,7 +18,7 @@ type RemoteRepository interface {
 	// IsValid checks if the URL is valid.
 	IsValid() bool
 	// VCS returns the VCS backend that hosts the repository.
-	VCS() (*VCSBackend, *url.URL, error)
+	VCS(context.Context) (*VCSBackend, *url.URL, error)
 }
 
 // A GitHubRepository represents a GitHub repository. Implements RemoteRepository.
@@ -42,7 +42,7 @@ func (repo *GitHubRepository) IsValid() bool {
 }
 
 // VCS returns VCSBackend of the repository
-func (repo *GitHubRepository) VCS() (*VCSBackend, *url.URL, error) {
+func (repo *GitHubRepository) VCS(ctx context.Context) (*VCSBackend, *url.URL, error) {
 	u := *repo.url
 	pathComponents := strings.Split(strings.Trim(strings.TrimSuffix(u.Path, ".git"), "/"), "/")
 	path := "/" + strings.Join(pathComponents[0:2], "/")
@@ -53,6 +53,40 @@ func (repo *GitHubRepository) VCS() (*VCSBackend, *url.URL, error) {
 	return GitBackend, &u, nil
 }
 
+// A GitLabRepository represents a GitLab repository. Implements RemoteRepository.
+// GitLab projects can be nested in arbitrarily deep subgroups like
+// "group/sub1/sub2/project", so the whole path is kept as the repository path.
+type GitLabRepository struct {
+	url *url.URL
+}
+
+// URL returns URL of the repository
+func (repo *GitLabRepository) URL() *url.URL {
+	return repo.url
+}
+
+func (repo *GitLabRepository) pathComponents() []string {
+	p, _, _ := strings.Cut(repo.url.Path, "/-/")
+	return strings.Split(strings.Trim(strings.TrimSuffix(strings.TrimSuffix(p, "/"), ".git"), "/"), "/")
+}
+
+// IsValid determine if the repository is valid or not
+func (repo *GitLabRepository) IsValid() bool {
+	return len(repo.pathComponents()) >= 2
+}
+
+// VCS returns VCSBackend of the repository
+func (repo *GitLabRepository) VCS(ctx context.Context) (*VCSBackend, *url.URL, error) {
+	u := *repo.url
+	path := "/" + strings.Join(repo.pathComponents(), "/")
+	if p, _, _ := strings.Cut(u.Path, "/-/"); strings.HasSuffix(strings.TrimSuffix(p, "/"), ".git") {
+		path += ".git"
+	}
+	u.Path = path
+	u.RawPath = ""
+	return GitBackend, &u, nil
+}
+
 // A GitHubGistRepository represents a GitHub Gist repository.
 type GitHubGistRepository struct {
 	url *url.URL
@@ -69,7 +103,7 @@ func (repo *GitHubGistRepository) IsValid() bool {
 }
 
 // VCS returns VCSBackend of the gist
-func (repo *GitHubGistRepository) VCS() (*VCSBackend, *url.URL, error) {
+func (repo *GitHubGistRepository) VCS(ctx context.Context) (*VCSBackend, *url.URL, error) {
 	return GitBackend, repo.URL(), nil
 }
 
@@ -89,7 +123,7 @@ func (repo *DarksHubRepository) IsValid() bool {
 }
 
 // VCS returns VCSBackend of the DarcsHub repository
-func (repo *DarksHubRepository) VCS() (*VCSBackend, *url.URL, error) {
+func (repo *DarksHubRepository) VCS(ctx context.Context) (*VCSBackend, *url.URL, error) {
 	return DarcsBackend, repo.URL(), nil
 }
 
@@ -109,7 +143,7 @@ func (repo *NestPijulRepository) IsValid() bool {
 }
 
 // VCS returns VCSBackend of the Nest repository
-func (repo *NestPijulRepository) VCS() (*VCSBackend, *url.URL, error) {
+func (repo *NestPijulRepository) VCS(ctx context.Context) (*VCSBackend, *url.URL, error) {
 	return PijulBackend, repo.URL(), nil
 }
 
@@ -129,7 +163,7 @@ func (repo *CodeCommitRepository) IsValid() bool {
 }
 
 // VCS returns VCSBackend of the repository
-func (repo *CodeCommitRepository) VCS() (*VCSBackend, *url.URL, error) {
+func (repo *CodeCommitRepository) VCS(ctx context.Context) (*VCSBackend, *url.URL, error) {
 	u := *repo.url
 	return GitBackend, &u, nil
 }
@@ -149,7 +183,7 @@ func (repo *ChiselRepository) IsValid() bool {
 }
 
 // VCS returns VCSBackend of the repository
-func (repo *ChiselRepository) VCS() (*VCSBackend, *url.URL, error) {
+func (repo *ChiselRepository) VCS(ctx context.Context) (*VCSBackend, *url.URL, error) {
 	return FossilBackend, repo.URL(), nil
 }
 
@@ -179,57 +213,25 @@ var (
 )
 
 // VCS detects VCSBackend of the OtherRepository
-func (repo *OtherRepository) VCS() (*VCSBackend, *url.URL, error) {
-	// Respect 'ghq.url.https://ghe.example.com/.vcs' config variable
-	// (in gitconfig:)
-	//     [ghq "https://ghe.example.com/"]
-	//     vcs = github
-	vcs, err := gitconfig.Do("--path", "--get-urlmatch", "ghq.vcs", repo.URL().String())
-	if err != nil && !gitconfig.IsNotFound(err) {
-		logger.Log("error", err.Error())
-	}
-	if backend, ok := vcsRegistry[vcs]; ok {
-		return backend, repo.URL(), nil
-	}
-
-	if m := vcsSchemeReg.FindStringSubmatch(repo.url.Scheme); len(m) > 1 {
-		return scheme2vcs[m[1]], repo.URL(), nil
-	}
-
-	mayBeSvn := strings.HasPrefix(repo.url.Host, "svn.")
-	if mayBeSvn && cmdutil.RunSilently("svn", "info", repo.url.String()) == nil {
-		return SubversionBackend, repo.URL(), nil
-	}
-
-	// Detect VCS backend
-	if repo.url.Scheme == "ssh" && repo.url.User.Username() == "git" {
-		return GitBackend, repo.URL(), nil
-	}
-
-	switch repo.url.Host {
-	case "fossil-scm.org", "sqlite.org":
-		return FossilBackend, repo.URL(), nil
-	}
-
-	if cmdutil.RunSilently("git", "ls-remote", repo.url.String()) == nil {
-		return GitBackend, repo.URL(), nil
-	}
-
-	vcs, repoURL, err := detectGoImport(repo.url)
-	if err == nil {
-		// vcs == "mod" (modproxy) not supported yet
-		return vcsRegistry[vcs], repoURL, nil
-	}
-
-	if cmdutil.RunSilently("hg", "identify", repo.url.String()) == nil {
-		return MercurialBackend, repo.URL(), nil
+func (repo *OtherRepository) VCS(ctx context.Context) (*VCSBackend, *url.URL, error) {
+	d, err := newVCSDetector().detect(ctx, repo.url)
+	if err != nil {
+		return nil, nil, err
 	}
+	return d.backend, d.url, nil
+}
 
-	if !mayBeSvn && cmdutil.RunSilently("svn", "info", repo.url.String()) == nil {
-		return SubversionBackend, repo.URL(), nil
+// isGitLabHost reports whether the URL is configured as a GitLab host
+// (in gitconfig:)
+//
+//	[ghq "https://gitlab.example.com/"]
+//	vcs = gitlab
+func isGitLabHost(u *url.URL) bool {
+	vcs, err := gitconfig.Do("--get-urlmatch", "ghq.vcs", u.String())
+	if err != nil && !gitconfig.IsNotFound(err) {
+		logger.Log("error", err.Error())
 	}
-
-	return nil, nil, fmt.Errorf("unsupported VCS, url=%s: %w", repo.URL(), err)
+	return vcs == "gitlab"
 }
 
 // NewRemoteRepository returns new RemoteRepository object from URL
@@ -241,6 +243,8 @@ func NewRemoteRepository(u *url.URL) (RemoteRepository, error) {
 		switch u.Host {
 		case "github.com":
 			return &GitHubRepository{u}
+		case "gitlab.com":
+			return &GitLabRepository{u}
 		case "gist.github.com":
 			return &GitHubGistRepository{u}
 		case "hub.darcs.net":
@@ -250,6 +254,9 @@ func NewRemoteRepository(u *url.URL) (RemoteRepository, error) {
 		case "chiselapp.com":
 			return &ChiselRepository{u}
 		default:
+			if isGitLabHost(u) {
+				return &GitLabRepository{u}
+			}
 			return &OtherRepository{u}
 		}
 	}()
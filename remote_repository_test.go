@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"testing"
+
+	"github.com/Songmu/gitconfig"
 )
 
 func TestNewRemoteRepository(t *testing.T) {
@@ -27,6 +30,26 @@ func TestNewRemoteRepository(t *testing.T) {
 		url:        "https://example.com/motemen/pusheen-explorer/",
 		valid:      true,
 		vcsBackend: nil,
+	}, {
+		url:        "https://gitlab.com/group/sub1/sub2/project",
+		valid:      true,
+		vcsBackend: GitBackend,
+		repoURL:    "https://gitlab.com/group/sub1/sub2/project",
+	}, {
+		url:        "https://gitlab.com/group/sub/project.git",
+		valid:      true,
+		vcsBackend: GitBackend,
+		repoURL:    "https://gitlab.com/group/sub/project.git",
+	}, {
+		url:        "https://gitlab.com/group/sub/project/-/tree/main",
+		valid:      true,
+		vcsBackend: GitBackend,
+		repoURL:    "https://gitlab.com/group/sub/project",
+	}, {
+		url:        "ssh://git@gitlab.com/group/sub/project.git",
+		valid:      true,
+		vcsBackend: GitBackend,
+		repoURL:    "ssh://git@gitlab.com/group/sub/project.git",
 	}, {
 		url:        "https://gist.github.com/motemen/9733745",
 		valid:      true,
@@ -54,7 +77,7 @@ func TestNewRemoteRepository(t *testing.T) {
 			if repo.IsValid() != tc.valid {
 				t.Errorf("repo.IsValid() should be %v, but %v", tc.valid, repo.IsValid())
 			}
-			vcs, u, _ := repo.VCS()
+			vcs, u, _ := repo.VCS(context.Background())
 			if vcs != tc.vcsBackend {
 				t.Errorf("got: %+v, expect: %+v", vcs, tc.vcsBackend)
 			}
@@ -67,6 +90,31 @@ func TestNewRemoteRepository(t *testing.T) {
 	}
 }
 
+func TestNewRemoteRepository_gitlab(t *testing.T) {
+	t.Cleanup(gitconfig.WithConfig(t, `
+[ghq "https://git.example.com/"]
+  vcs = gitlab
+`))
+	repo, err := NewRemoteRepository(mustParseURL("https://git.example.com/group/sub/project.git"))
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if _, ok := repo.(*GitLabRepository); !ok {
+		t.Fatalf("repo should be *GitLabRepository but: %T", repo)
+	}
+	vcs, u, err := repo.VCS(context.Background())
+	if err != nil {
+		t.Errorf("error should be nil but: %s", err)
+	}
+	if vcs != GitBackend {
+		t.Errorf("got: %+v, expect: %+v", vcs, GitBackend)
+	}
+	expect := "https://git.example.com/group/sub/project.git"
+	if u.String() != expect {
+		t.Errorf("repoURL: got: %s, expect: %s", u, expect)
+	}
+}
+
 func TestNewRemoteRepository_vcs_error(t *testing.T) {
 	testCases := []struct {
 		url        string
@@ -88,7 +136,7 @@ func TestNewRemoteRepository_vcs_error(t *testing.T) {
 			if repo.IsValid() != tc.valid {
 				t.Errorf("repo.IsValid() should be %v, but %v", tc.valid, repo.IsValid())
 			}
-			vcs, u, err := repo.VCS()
+			vcs, u, err := repo.VCS(context.Background())
 			if err == nil {
 				t.Fatalf("error should be nil but: %s", err)
 			}
@@ -107,6 +155,8 @@ func TestNewRemoteRepository_error(t *testing.T) {
 		url string
 	}{{
 		url: "https://github.com/blog/github",
+	}, {
+		url: "https://gitlab.com/project",
 	}}
 
 	for _, tc := range testCases {
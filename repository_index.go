@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const repositoryIndexFile = "index.json"
+
+// repositoryIndexTTL is how long the index is used without walking the roots
+// again, to catch up with the repositories cloned or removed without ghq.
+var repositoryIndexTTL = 10 * time.Minute
+
+// A repositoryIndex is the cached list of the local repositories, which the
+// completion is backed by, not to walk the roots on every key press.
+type repositoryIndex struct {
+	Roots        []string            `json:"roots"`
+	Ignore       map[string][]string `json:"ignore,omitempty"`
+	Updated      time.Time           `json:"updated"`
+	Repositories []indexedRepository `json:"repositories"`
+}
+
+// An indexedRepository is a local repository in the index.
+type indexedRepository struct {
+	Root string `json:"root"`
+	Path string `json:"path"` // slash separated path relative to the root
+}
+
+func repositoryIndexPath() (string, error) {
+	dir, err := ghqCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, repositoryIndexFile), nil
+}
+
+// loadRepositoryIndex returns the index, which is rebuilt if it is missing,
+// expired, or for other roots or ignore rules.
+func loadRepositoryIndex() (*repositoryIndex, error) {
+	roots, err := localRepositoryRoots(true)
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := ignorePatterns(roots)
+	if err != nil {
+		return nil, err
+	}
+	p, err := repositoryIndexPath()
+	if err != nil {
+		return nil, err
+	}
+	if b, err := os.ReadFile(p); err == nil {
+		var idx repositoryIndex
+		// broken index is rebuilt
+		if json.Unmarshal(b, &idx) == nil && slices.Equal(idx.Roots, roots) &&
+			maps.EqualFunc(idx.Ignore, ignore, slices.Equal) &&
+			time.Since(idx.Updated) < repositoryIndexTTL {
+			return &idx, nil
+		}
+	}
+
+	idx := &repositoryIndex{Roots: roots, Ignore: ignore, Updated: time.Now()}
+	var mu sync.Mutex
+	if err := walkAllLocalRepositories(func(repo *LocalRepository) {
+		mu.Lock()
+		defer mu.Unlock()
+		idx.Repositories = append(idx.Repositories, indexedRepository{
+			Root: repo.RootPath,
+			Path: filepath.ToSlash(repo.RelPath),
+		})
+	}); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(idx.Repositories, func(a, b indexedRepository) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Root, b.Root)
+	})
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return nil, err
+	}
+	// the index is used even if it cannot be saved
+	_ = writeFileAtomically(p, b)
+	return idx, nil
+}
+
+// invalidateRepositoryIndex removes the index, since the repositories under
+// the roots have been changed.
+func invalidateRepositoryIndex() {
+	if p, err := repositoryIndexPath(); err == nil {
+		os.Remove(p)
+	}
+}
+
+func (r indexedRepository) localRepository() *LocalRepository {
+	return &LocalRepository{
+		FullPath:  filepath.Join(r.Root, filepath.FromSlash(r.Path)),
+		RelPath:   filepath.FromSlash(r.Path),
+		RootPath:  r.Root,
+		PathParts: strings.Split(r.Path, "/"),
+	}
+}
+
+// subpaths returns the subpaths of all the repositories, by which they can
+// be specified to 'ghq look' and so on.
+func (idx *repositoryIndex) subpaths() []string {
+	var paths []string
+	for _, r := range idx.Repositories {
+		paths = append(paths, r.localRepository().Subpaths()...)
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// namespaces returns the directories in the subpaths of all the
+// repositories, such as "github.com/", "github.com/motemen/" and "motemen/",
+// under which new repositories are created.
+func (idx *repositoryIndex) namespaces() []string {
+	var paths []string
+	for _, s := range idx.subpaths() {
+		for i, c := range s {
+			if c == '/' {
+				paths = append(paths, s[:i+1])
+			}
+		}
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
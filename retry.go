@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Songmu/gitconfig"
+	"github.com/x-motemen/ghq/cmdutil"
+	"github.com/x-motemen/ghq/logger"
+)
+
+const (
+	defaultRetryBackoff = time.Second
+	maxRetryBackoff     = time.Minute
+)
+
+// retryPolicy retries cloning and updating repositories on transient
+// network failures.
+type retryPolicy struct {
+	// retries is the number of retries after the first attempt.
+	retries int
+	// backoff is the base interval between attempts, doubled on each retry.
+	backoff time.Duration
+}
+
+// newRetryPolicy returns the retry policy configured by 'ghq.retries' and
+// 'ghq.retryBackoff'. retries overrides 'ghq.retries' if not negative.
+func newRetryPolicy(retries int) *retryPolicy {
+	p := &retryPolicy{retries: retries, backoff: defaultRetryBackoff}
+	if p.retries < 0 {
+		p.retries = 0
+		v, err := gitconfig.Get("ghq.retries")
+		if err != nil && !gitconfig.IsNotFound(err) {
+			logger.Log("error", err.Error())
+		}
+		if v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				logger.Log("warning", fmt.Sprintf("invalid ghq.retries %q, not retrying", v))
+			} else {
+				p.retries = n
+			}
+		}
+	}
+	v, err := gitconfig.Get("ghq.retryBackoff")
+	if err != nil && !gitconfig.IsNotFound(err) {
+		logger.Log("error", err.Error())
+	}
+	if v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			logger.Log("warning", fmt.Sprintf("invalid ghq.retryBackoff %q, using %s", v, defaultRetryBackoff))
+		} else {
+			p.backoff = d
+		}
+	}
+	return p
+}
+
+// do calls f, and calls it again while it fails with a retryable error.
+func (p *retryPolicy) do(ctx context.Context, name string, row *progressRow, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || p == nil || attempt > p.retries || ctx.Err() != nil || !isRetryable(err) {
+			return err
+		}
+		wait := p.wait(attempt)
+		logger.Log("retry", fmt.Sprintf("%s in %s (%d/%d): %s", name, wait, attempt, p.retries, err))
+		row.report(fmt.Sprintf("retrying (%d/%d)", attempt, p.retries), -1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// wait returns the jittered exponential backoff before the retry.
+func (p *retryPolicy) wait(attempt int) time.Duration {
+	d := p.backoff
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, maxRetryBackoff)
+	if d <= 0 {
+		return 0
+	}
+	// between the half and the full of the backoff not to retry in lockstep
+	return d/2 + rand.N(d/2+1)
+}
+
+var (
+	// fatalErrorReg matches the error output of failures which would not be
+	// resolved by retrying. It is checked before retryableErrorReg since
+	// e.g. "fatal: repository not found" may follow "HTTP 404".
+	fatalErrorReg = regexp.MustCompile(`(?i)` +
+		`authentication failed|permission denied|could not read (?:username|password)|` +
+		`repository not found|does not exist|not a valid repository|` +
+		`(?:requested URL|HTTP) returned error: 4\d\d|` +
+		`remote branch .* not found|invalid reference|unknown revision|` +
+		`already exists and is not an empty directory`)
+	// retryableErrorReg matches the error output of transient network failures.
+	retryableErrorReg = regexp.MustCompile(`(?i)` +
+		`connection (?:reset|refused|timed out|closed)|operation timed out|timed out after|` +
+		`early eof|unexpected disconnect|the remote end hung up unexpectedly|` +
+		`broken pipe|transfer closed|rpc failed|curl \d+|` +
+		`could not resolve host|temporary failure in name resolution|` +
+		`gnutls_handshake|ssl_(?:read|connect)|tls handshake|` +
+		`(?:kex|ssh)_exchange_identification|` +
+		`(?:requested URL|HTTP) returned error: 5\d\d|HTTP (?:error )?5\d\d|` +
+		`internal server error|bad gateway|service unavailable|gateway time-?out`)
+)
+
+// isRetryable reports whether the error is a transient failure of a command,
+// classified by its error output. The failures whose output was written to a
+// terminal and not kept are not retried, since git and hg exit with the same
+// status on authentication failures or typos as on network errors.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var runErr *cmdutil.RunError
+	if !errors.As(err, &runErr) {
+		return false
+	}
+	// not found, or killed by a signal
+	if runErr.ExitStatus() < 0 {
+		return false
+	}
+	out := runErr.Stderr
+	if out == "" {
+		return false
+	}
+	if fatalErrorReg.MatchString(out) {
+		return false
+	}
+	return retryableErrorReg.MatchString(out)
+}
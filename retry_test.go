@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Songmu/gitconfig"
+	"github.com/x-motemen/ghq/cmdutil"
+)
+
+func exitError(t *testing.T, code int) error {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command is not available")
+	}
+	err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	if err == nil {
+		t.Fatal("command should fail")
+	}
+	return err
+}
+
+func TestIsRetryable(t *testing.T) {
+	exit128 := exitError(t, 128)
+	runErr := func(stderr string) error {
+		return &cmdutil.RunError{Command: exec.Command("git"), ExecError: exit128, Stderr: stderr}
+	}
+	testCases := []struct {
+		name   string
+		err    error
+		expect bool
+	}{{
+		name:   "connection reset",
+		err:    runErr("error: RPC failed; curl 56 Recv failure: Connection reset by peer\nfatal: early EOF\n"),
+		expect: true,
+	}, {
+		name:   "hung up",
+		err:    runErr("fatal: the remote end hung up unexpectedly\n"),
+		expect: true,
+	}, {
+		name:   "HTTP 502",
+		err:    runErr("fatal: unable to access 'https://example.com/repo/': The requested URL returned error: 502\n"),
+		expect: true,
+	}, {
+		name:   "HTTP 404",
+		err:    runErr("remote: Repository not found.\nfatal: repository 'https://github.com/motemen/none/' not found\n"),
+		expect: false,
+	}, {
+		name:   "authentication",
+		err:    runErr("fatal: Authentication failed for 'https://example.com/repo/'\n"),
+		expect: false,
+	}, {
+		name:   "unknown failure",
+		err:    runErr("fatal: something went wrong\n"),
+		expect: false,
+	}, {
+		name:   "git fatal on a terminal",
+		err:    runErr(""),
+		expect: false,
+	}, {
+		name:   "hg abort on a terminal",
+		err:    &cmdutil.RunError{Command: exec.Command("hg"), ExecError: exitError(t, 255)},
+		expect: false,
+	}, {
+		name:   "svn failure on a terminal",
+		err:    &cmdutil.RunError{Command: exec.Command("svn"), ExecError: exitError(t, 1)},
+		expect: false,
+	}, {
+		name: "killed",
+		err: &cmdutil.RunError{Command: exec.Command("git"), ExecError: errors.New("signal: killed"),
+			Stderr: "fatal: early EOF\n"},
+		expect: false,
+	}, {
+		name:   "not a command failure",
+		err:    errors.New("connection reset"),
+		expect: false,
+	}, {
+		name:   "canceled",
+		err:    context.Canceled,
+		expect: false,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isRetryable(tc.err); got != tc.expect {
+				t.Errorf("got: %v, expect: %v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	t.Cleanup(gitconfig.WithConfig(t, `
+[ghq]
+  retries = 3
+  retryBackoff = 2s
+`))
+	p := newRetryPolicy(-1)
+	if p.retries != 3 || p.backoff != 2*time.Second {
+		t.Errorf("got: %+v", p)
+	}
+	if p := newRetryPolicy(1); p.retries != 1 {
+		t.Errorf("--retries should override ghq.retries, but: %d", p.retries)
+	}
+
+	for attempt, max := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second} {
+		if d := p.wait(attempt + 1); d < max/2 || d > max {
+			t.Errorf("wait of attempt %d should be within [%s, %s], but: %s", attempt+1, max/2, max, d)
+		}
+	}
+	if d := p.wait(100); d > maxRetryBackoff {
+		t.Errorf("wait should be capped at %s, but: %s", maxRetryBackoff, d)
+	}
+}
+
+func TestCommandGet_retry(t *testing.T) {
+	t.Cleanup(gitconfig.WithConfig(t, `
+[ghq]
+  retryBackoff = 0s
+`))
+	exit128 := exitError(t, 128)
+	withFakeGitBackend(t, func(t *testing.T, tmproot string, _ *_cloneArgs, _ *_updateArgs) {
+		var attempts int
+		GitBackend.Clone = func(_ context.Context, vg *vcsGetOption) error {
+			attempts++
+			if attempts < 3 {
+				return &cmdutil.RunError{Command: exec.Command("git", "clone"), ExecError: exit128,
+					Stderr: "fatal: early EOF\n"}
+			}
+			return os.MkdirAll(vg.dir, 0755)
+		}
+		err := newApp().Run(context.Background(),
+			[]string{"", "get", "--retries", "2", "motemen/ghq-retry-test"})
+		if err != nil {
+			t.Errorf("error should be nil but: %s", err)
+		}
+		if attempts != 3 {
+			t.Errorf("clone should be attempted 3 times, but: %d", attempts)
+		}
+
+		attempts = 0
+		err = newApp().Run(context.Background(),
+			[]string{"", "get", "motemen/ghq-retry-test2"})
+		if err == nil {
+			t.Errorf("error should not be nil without retries")
+		}
+		if attempts != 1 {
+			t.Errorf("clone should be attempted once, but: %d", attempts)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/x-motemen/ghq/cmdutil"
+)
+
+// maxSelectorRows is the number of candidates shown at once by the built-in
+// selector.
+const maxSelectorRows = 20
+
+var errNotSelected = errors.New("no repository selected")
+
+// selectByCommand lets the user choose one of the candidates by the selector
+// command such as fzf or peco, which reads the candidates from its standard
+// input and writes the chosen one to its standard output.
+func selectByCommand(ctx context.Context, selector string, candidates []string) (string, error) {
+	args := shellCommand(selector)
+	cmd := cmdutil.Command(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(strings.Join(candidates, "\n") + "\n")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	// the selector draws on the terminal
+	cmd.Stderr = os.Stderr
+	if err := cmdutil.RunCommand(cmd, true); err != nil {
+		var runErr *cmdutil.RunError
+		// fzf and peco exit with non-zero statuses when canceled
+		if errors.As(err, &runErr) && runErr.ExitStatus() > 0 && out.Len() == 0 {
+			return "", errNotSelected
+		}
+		return "", fmt.Errorf("selector %q failed: %w", selector, err)
+	}
+	// the first line, if multiple ones are selected
+	line, _, _ := strings.Cut(out.String(), "\n")
+	if line = strings.TrimSpace(line); line == "" {
+		return "", errNotSelected
+	}
+	return line, nil
+}
+
+// selectByPrompt lets the user choose one of the candidates on the terminal.
+// The candidates are narrowed down by typing a fuzzy pattern, and chosen by
+// their numbers, or automatically when only one is left.
+func selectByPrompt(in io.Reader, out io.Writer, candidates []string) (string, error) {
+	scr := bufio.NewScanner(in)
+	filtered := candidates
+	for {
+		for i, c := range filtered {
+			if i == maxSelectorRows {
+				fmt.Fprintf(out, "  ... and %d more\n", len(filtered)-i)
+				break
+			}
+			fmt.Fprintf(out, "%3d) %s\n", i+1, c)
+		}
+		fmt.Fprint(out, "Choose a number, or type to narrow down (empty to cancel): ")
+		if !scr.Scan() {
+			fmt.Fprintln(out)
+			if err := scr.Err(); err != nil {
+				return "", err
+			}
+			return "", errNotSelected
+		}
+		input := strings.TrimSpace(scr.Text())
+		if input == "" {
+			return "", errNotSelected
+		}
+		if n, err := strconv.Atoi(input); err == nil {
+			if 1 <= n && n <= min(len(filtered), maxSelectorRows) {
+				return filtered[n-1], nil
+			}
+			fmt.Fprintf(out, "%d is out of range\n", n)
+			continue
+		}
+		var narrowed []string
+		for _, c := range filtered {
+			if fuzzyMatch(input, c) {
+				narrowed = append(narrowed, c)
+			}
+		}
+		switch len(narrowed) {
+		case 0:
+			fmt.Fprintf(out, "nothing matches %q\n", input)
+		case 1:
+			return narrowed[0], nil
+		default:
+			filtered = narrowed
+		}
+	}
+}
+
+// fuzzyMatch reports whether the characters of the pattern appear in s in
+// order. The match is case-insensitive unless the pattern has an upper case.
+func fuzzyMatch(pattern, s string) bool {
+	if strings.ToLower(pattern) == pattern {
+		s = strings.ToLower(s)
+	}
+	for _, r := range pattern {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
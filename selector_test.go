@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	testCases := []struct {
+		pattern, s string
+		expect     bool
+	}{
+		{"ghq", "github.com/x-motemen/ghq", true},
+		{"xghq", "github.com/x-motemen/ghq", true},
+		{"mo ghq", "github.com/x-motemen/ghq", true},
+		{"qhg", "github.com/x-motemen/ghq", false},
+		{"GHQ", "github.com/x-motemen/ghq", false},
+		{"Ghq", "github.com/motemen/Ghq", true},
+		{"ghq", "github.com/motemen/GHQ", true},
+		{"", "github.com/x-motemen/ghq", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.s, func(t *testing.T) {
+			if got := fuzzyMatch(tc.pattern, tc.s); got != tc.expect {
+				t.Errorf("got: %t, expect: %t", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestSelectByPrompt(t *testing.T) {
+	candidates := []string{
+		"github.com/motemen/ghq",
+		"github.com/motemen/gore",
+		"github.com/Songmu/ghq-handler",
+		"gitlab.com/example/ghq",
+	}
+	testCases := []struct {
+		name      string
+		input     string
+		expect    string
+		expectErr error
+	}{{
+		name:   "number",
+		input:  "2\n",
+		expect: "github.com/motemen/gore",
+	}, {
+		name:   "narrowed to one",
+		input:  "handler\n",
+		expect: "github.com/Songmu/ghq-handler",
+	}, {
+		name:   "narrowed then number",
+		input:  "mo\n2\n",
+		expect: "github.com/motemen/gore",
+	}, {
+		name:   "out of range and unmatched",
+		input:  "9\nzzz\nlab\n",
+		expect: "gitlab.com/example/ghq",
+	}, {
+		name:      "canceled",
+		input:     "\n",
+		expectErr: errNotSelected,
+	}, {
+		name:      "EOF",
+		input:     "gh\n",
+		expectErr: errNotSelected,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := selectByPrompt(strings.NewReader(tc.input), io.Discard, candidates)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("error should be %v, but: %v", tc.expectErr, err)
+			}
+			if got != tc.expect {
+				t.Errorf("got: %q, expect: %q", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestSelectByCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh command is not available")
+	}
+	candidates := []string{"github.com/motemen/ghq", "github.com/motemen/gore"}
+	testCases := []struct {
+		name      string
+		selector  string
+		expect    string
+		expectErr error
+	}{{
+		name:     "chosen",
+		selector: "grep gore",
+		expect:   "github.com/motemen/gore",
+	}, {
+		name:     "first line",
+		selector: "cat",
+		expect:   "github.com/motemen/ghq",
+	}, {
+		name:      "canceled",
+		selector:  "exit 130",
+		expectErr: errNotSelected,
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := selectByCommand(context.Background(), tc.selector, candidates)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("error should be %v, but: %v", tc.expectErr, err)
+			}
+			if got != tc.expect {
+				t.Errorf("got: %q, expect: %q", got, tc.expect)
+			}
+		})
+	}
+}
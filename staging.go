@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/x-motemen/ghq/logger"
+)
+
+// stagingPrefix is the name prefix of temporary directories in which
+// repositories are prepared before being renamed into place.
+const stagingPrefix = ".ghq-tmp-"
+
+// A staging is a temporary directory next to the destination of a
+// repository. The repository is written into dir, and moved to dst by
+// commit only when it is complete, so that a failed or interrupted clone
+// never leaves a broken repository at dst.
+type staging struct {
+	root string // .ghq-tmp-<base>-<rand>, removed by cleanup
+	dir  string // <root>/<base>, where the repository is written
+	dst  string
+}
+
+// newStaging creates a staging directory for dst. Stale staging directories
+// left for dst by an earlier run are removed.
+func newStaging(dst string) (*staging, error) {
+	parent, base := filepath.Split(dst)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, err
+	}
+	removeStaleStagings(dst)
+	root, err := os.MkdirTemp(parent, stagingPrefix+base+"-")
+	if err != nil {
+		return nil, err
+	}
+	return &staging{root: root, dir: filepath.Join(root, base), dst: dst}, nil
+}
+
+// commit moves the repository written at dir to the destination, and
+// removes the staging directory.
+func (s *staging) commit(dir string) error {
+	ok, err := isNotExistOrEmpty(s.dst)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("destination path %q already exists and is not an empty directory", s.dst)
+	}
+	// an empty directory cannot be replaced by rename on some platforms
+	if err := os.Remove(s.dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(dir, s.dst); err != nil {
+		return err
+	}
+	s.cleanup()
+	invalidateRepositoryIndex()
+	return nil
+}
+
+// cleanup removes the staging directory with everything written in it.
+func (s *staging) cleanup() {
+	if err := os.RemoveAll(s.root); err != nil {
+		logger.Log("warning", fmt.Sprintf("failed to clean up %s: %s", s.root, err))
+	}
+}
+
+func removeStaleStagings(dst string) {
+	parent, base := filepath.Split(dst)
+	matches, err := filepath.Glob(filepath.Join(parent, stagingPrefix+base+"-*"))
+	if err != nil {
+		return
+	}
+	for _, m := range matches {
+		// "<base>-*" also matches stagings of "<base>-suffix"
+		if _, err := os.Stat(filepath.Join(m, base)); err != nil && !isEmptyDir(m) {
+			continue
+		}
+		logger.Log("cleanup", m)
+		if err := os.RemoveAll(m); err != nil {
+			logger.Log("warning", fmt.Sprintf("failed to clean up %s: %s", m, err))
+		}
+	}
+}
+
+func isEmptyDir(name string) bool {
+	ok, err := isNotExistOrEmpty(name)
+	return ok && err == nil
+}
+
+func isStagingDir(name string) bool {
+	return strings.HasPrefix(filepath.Base(name), stagingPrefix)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/x-motemen/ghq/cmdutil"
+)
+
+func TestStaging(t *testing.T) {
+	tmpd := newTempDir(t)
+	dst := filepath.Join(tmpd, "github.com", "motemen", "ghq")
+
+	s, err := newStaging(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(s.root) != filepath.Dir(dst) || !isStagingDir(s.root) {
+		t.Errorf("staging root should be a sibling of %s, but: %s", dst, s.root)
+	}
+	if err := os.MkdirAll(filepath.Join(s.dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.commit(s.dir); err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, ".git")); err != nil {
+		t.Errorf("repository should be moved to %s: %s", dst, err)
+	}
+	if _, err := os.Stat(s.root); !os.IsNotExist(err) {
+		t.Errorf("staging root should be removed: %v", err)
+	}
+
+	s, err = newStaging(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.cleanup()
+	os.MkdirAll(s.dir, 0755)
+	if err := s.commit(s.dir); err == nil {
+		t.Errorf("commit onto non-empty directory should fail")
+	}
+}
+
+func TestStaging_removeStale(t *testing.T) {
+	tmpd := newTempDir(t)
+	parent := filepath.Join(tmpd, "github.com", "motemen")
+	stale := filepath.Join(parent, stagingPrefix+"ghq-123", "ghq")
+	other := filepath.Join(parent, stagingPrefix+"ghq-test-456", "ghq-test")
+	for _, d := range []string{stale, other} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := newStaging(filepath.Join(parent, "ghq"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.cleanup()
+	if _, err := os.Stat(filepath.Dir(stale)); !os.IsNotExist(err) {
+		t.Errorf("stale staging should be removed: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("staging of another repository should be kept: %s", err)
+	}
+}
+
+func TestGetterClone_svn(t *testing.T) {
+	tmpd := newTempDir(t)
+	defer func(orig func(cmd *exec.Cmd) error) {
+		cmdutil.CommandRunner = orig
+	}(cmdutil.CommandRunner)
+	var checkout string
+	cmdutil.CommandRunner = func(cmd *exec.Cmd) error {
+		if cmd.Args[1] == "checkout" {
+			checkout = cmd.Args[len(cmd.Args)-1]
+			return os.MkdirAll(filepath.Join(checkout, ".svn"), 0755)
+		}
+		return nil
+	}
+
+	dir := filepath.Join(tmpd, "svn.example.com", "proj", "repo", "trunk")
+	vg := &vcsGetOption{
+		url: &url.URL{Scheme: "https", Host: "svn.example.com", Path: "/proj/repo/trunk"},
+		dir: dir,
+	}
+	if err := (&getter{}).clone(context.Background(), SubversionBackend, vg); err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	expect := filepath.Join(tmpd, "svn.example.com", "proj", "repo")
+	if d := stagedDestination(checkout); d != expect {
+		t.Errorf("checked out into %s, expect: %s", d, expect)
+	}
+	if _, err := os.Stat(filepath.Join(expect, ".svn")); err != nil {
+		t.Errorf("working copy should be moved to %s: %s", expect, err)
+	}
+}
+
+func TestGetterClone_failure(t *testing.T) {
+	tmpd := newTempDir(t)
+	dir := filepath.Join(tmpd, "example.com", "motemen", "ghq")
+	errClone := errors.New("clone failed")
+	backend := &VCSBackend{
+		Clone: func(_ context.Context, vg *vcsGetOption) error {
+			os.MkdirAll(filepath.Join(vg.dir, ".git"), 0755)
+			return errClone
+		},
+	}
+	err := (&getter{}).clone(context.Background(), backend, &vcsGetOption{dir: dir})
+	if !errors.Is(err, errClone) {
+		t.Errorf("error should be %v but: %v", errClone, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(dir))
+	if len(entries) > 0 {
+		t.Errorf("nothing should be left, but: %v", entries)
+	}
+}
+
+func TestWalkLocalRepositories_skipStaging(t *testing.T) {
+	tmpd := newTempDir(t)
+	defer func(orig []string) { _localRepositoryRoots = orig }(_localRepositoryRoots)
+	_localRepositoryRoots = []string{tmpd}
+
+	os.MkdirAll(filepath.Join(tmpd, "github.com", "motemen", "ghq", ".git"), 0755)
+	os.MkdirAll(filepath.Join(tmpd, "github.com", "motemen", stagingPrefix+"gore-1", "gore", ".git"), 0755)
+
+	var got []string
+	walkAllLocalRepositories(func(repo *LocalRepository) {
+		got = append(got, repo.RelPath)
+	})
+	if len(got) != 1 || got[0] != filepath.Join("github.com", "motemen", "ghq") {
+		t.Errorf("staging directories should be skipped, but got: %v", got)
+	}
+}
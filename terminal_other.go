@@ -0,0 +1,14 @@
+//go:build !(darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd || windows)
+
+package main
+
+// terminalWidth returns the width of the terminal, or 0 if unknown.
+func terminalWidth(fd uintptr) int {
+	return 0
+}
+
+// enableVirtualTerminal reports whether the terminal interprets ANSI escape
+// sequences, enabling it if needed.
+func enableVirtualTerminal(fd uintptr) bool {
+	return false
+}
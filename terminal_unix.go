@@ -0,0 +1,20 @@
+//go:build darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// terminalWidth returns the width of the terminal, or 0 if unknown.
+func terminalWidth(fd uintptr) int {
+	ws, err := unix.IoctlGetWinsize(int(fd), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0
+	}
+	return int(ws.Col)
+}
+
+// enableVirtualTerminal reports whether the terminal interprets ANSI escape
+// sequences, enabling it if needed.
+func enableVirtualTerminal(fd uintptr) bool {
+	return true
+}
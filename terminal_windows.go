@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+import "golang.org/x/sys/windows"
+
+// terminalWidth returns the width of the terminal, or 0 if unknown.
+func terminalWidth(fd uintptr) int {
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(fd), &info); err != nil {
+		return 0
+	}
+	return int(info.Window.Right - info.Window.Left + 1)
+}
+
+// enableVirtualTerminal reports whether the terminal interprets ANSI escape
+// sequences, enabling it if needed.
+func enableVirtualTerminal(fd uintptr) bool {
+	var mode uint32
+	if err := windows.GetConsoleMode(windows.Handle(fd), &mode); err != nil {
+		return false
+	}
+	return windows.SetConsoleMode(windows.Handle(fd), mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
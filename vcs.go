@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -10,19 +11,20 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/x-motemen/ghq/cmdutil"
 )
 
-func run(silent bool) func(command string, args ...string) error {
+func run(silent bool) func(ctx context.Context, command string, args ...string) error {
 	if silent {
 		return cmdutil.RunSilently
 	}
 	return cmdutil.Run
 }
 
-func runInDir(silent bool) func(dir, command string, args ...string) error {
+func runInDir(silent bool) func(ctx context.Context, dir, command string, args ...string) error {
 	if silent {
 		return cmdutil.RunInDirSilently
 	}
@@ -32,22 +34,28 @@ func runInDir(silent bool) func(dir, command string, args ...string) error {
 // A VCSBackend represents a VCS backend.
 type VCSBackend struct {
 	// Clones a remote repository to local path.
-	Clone func(*vcsGetOption) error
+	Clone func(context.Context, *vcsGetOption) error
 	// Updates a cloned local repository.
-	Update func(*vcsGetOption) error
-	Init   func(dir string) error
+	Update func(context.Context, *vcsGetOption) error
+	Init   func(ctx context.Context, dir string) error
 	// Returns VCS specific files
 	Contents []string
 	// Returns the remote URL of the repository at the given directory.
 	// If nil, the VCS backend does not support retrieving remote URLs.
 	RemoteURL func(dir string) (string, error)
+	// Sets the remote URL returned by RemoteURL.
+	// If nil, the VCS backend does not support changing remote URLs.
+	SetRemoteURL func(dir, url string) error
 }
 
 type vcsGetOption struct {
 	url                              *url.URL
 	dir                              string
 	recursive, shallow, silent, bare bool
-	branch, partial                  string
+	branch, revision, partial        string
+	// progress is called with the phase and its percentage (-1 if unknown)
+	// while cloning, instead of showing the output of the command. (Git only)
+	progress func(phase string, percent int)
 }
 
 // getGitRemoteURL retrieves the remote URL from a git repository.
@@ -94,10 +102,62 @@ func getGitRemoteURL(dir string) (string, error) {
 	return finalURL, nil
 }
 
+// gitRemoteName returns the name of the remote whose URL is returned by
+// getGitRemoteURL: 'origin' if it exists, or the first remote.
+func gitRemoteName(dir string) (string, error) {
+	cmd := exec.Command("git", "remote")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list remotes: %w", err)
+	}
+	remotes := strings.Fields(string(out))
+	if len(remotes) == 0 {
+		return "", fmt.Errorf("no remotes found")
+	}
+	if slices.Contains(remotes, "origin") {
+		return "origin", nil
+	}
+	return remotes[0], nil
+}
+
+// setHgDefaultPath returns the content of hgrc with the default path in
+// its [paths] section set to the URL, keeping the rest as is.
+func setHgDefaultPath(hgrc, url string) string {
+	var (
+		entry   = "default = " + url
+		lines   []string
+		section string
+		header  = -1
+	)
+	if strings.TrimSpace(hgrc) != "" {
+		lines = strings.Split(strings.TrimRight(hgrc, "\n"), "\n")
+	}
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			if section = trimmed; section == "[paths]" {
+				header = i
+			}
+			continue
+		}
+		if key, _, ok := strings.Cut(trimmed, "="); ok && section == "[paths]" && strings.TrimSpace(key) == "default" {
+			lines[i] = entry
+			return strings.Join(lines, "\n") + "\n"
+		}
+	}
+	if header < 0 {
+		lines = append(lines, "[paths]", entry)
+	} else {
+		lines = slices.Insert(lines, header+1, entry)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
 // GitBackend is the VCSBackend of git
 var GitBackend = &VCSBackend{
 	// support submodules?
-	Clone: func(vg *vcsGetOption) error {
+	Clone: func(ctx context.Context, vg *vcsGetOption) error {
 		dir, _ := filepath.Split(vg.dir)
 		err := os.MkdirAll(dir, 0755)
 		if err != nil {
@@ -122,45 +182,90 @@ var GitBackend = &VCSBackend{
 		} else if vg.partial == "treeless" {
 			args = append(args, "--filter=tree:0")
 		}
+		if vg.progress != nil {
+			args = append(args, "--progress")
+		}
 		args = append(args, vg.url.String(), vg.dir)
 
-		return run(vg.silent)("git", args...)
+		if vg.progress != nil {
+			cmd := cmdutil.Command(ctx, "git", args...)
+			cmd.Stdout = io.Discard
+			cmd.Stderr = &gitProgressWriter{report: vg.progress}
+			if err := cmdutil.RunCommand(cmd, true); err != nil {
+				return err
+			}
+		} else if err := run(vg.silent)(ctx, "git", args...); err != nil {
+			return err
+		}
+		if vg.revision == "" || vg.bare {
+			return nil
+		}
+		silent := vg.silent
+		if vg.progress != nil {
+			vg.progress("checking out", -1)
+			silent = true
+		}
+		if vg.shallow {
+			// A shallow clone only has the tip of the branch, so the
+			// revision has to be fetched before it can be checked out.
+			// Servers refuse abbreviated hashes in a fetch; deepen the
+			// clone to the full history in that case.
+			err := runInDir(true)(ctx, vg.dir, "git", "fetch", "--depth", "1", "origin", vg.revision)
+			if err != nil {
+				if err := runInDir(silent)(ctx, vg.dir, "git", "fetch", "--unshallow", "origin"); err != nil {
+					return err
+				}
+			}
+		}
+		return runInDir(silent)(ctx, vg.dir, "git", "checkout", vg.revision)
 	},
-	Update: func(vg *vcsGetOption) error {
+	Update: func(ctx context.Context, vg *vcsGetOption) error {
 		if _, err := os.Stat(filepath.Join(vg.dir, ".git/svn")); err == nil {
-			return GitsvnBackend.Update(vg)
+			return GitsvnBackend.Update(ctx, vg)
 		}
 		if vg.bare {
-			return runInDir(true)(vg.dir, "git", "fetch", vg.url.String(), "*:*")
+			return runInDir(true)(ctx, vg.dir, "git", "fetch", vg.url.String(), "*:*")
 		}
-		err := runInDir(true)(vg.dir, "git", "rev-parse", "@{upstream}")
+		err := runInDir(true)(ctx, vg.dir, "git", "rev-parse", "@{upstream}")
 		if err != nil {
-			err := runInDir(vg.silent)(vg.dir, "git", "fetch")
+			err := runInDir(vg.silent)(ctx, vg.dir, "git", "fetch")
 			if err != nil {
 				return err
 			}
 			return nil
 		}
-		err = runInDir(vg.silent)(vg.dir, "git", "pull", "--ff-only")
+		err = runInDir(vg.silent)(ctx, vg.dir, "git", "pull", "--ff-only")
 		if err != nil {
 			return err
 		}
 		if vg.recursive {
-			return runInDir(vg.silent)(vg.dir, "git", "submodule", "update", "--init", "--recursive")
+			return runInDir(vg.silent)(ctx, vg.dir, "git", "submodule", "update", "--init", "--recursive")
 		}
 		return nil
 	},
-	Init: func(dir string) error {
+	Init: func(ctx context.Context, dir string) error {
 		args := []string{"init"}
 		if strings.HasSuffix(dir, ".git") {
 			args = append(args, "--bare")
 		}
-		return cmdutil.RunInDir(dir, "git", args...)
+		return cmdutil.RunInDir(ctx, dir, "git", args...)
 	},
 	Contents: []string{".git"},
 	RemoteURL: func(dir string) (string, error) {
 		return getGitRemoteURL(dir)
 	},
+	SetRemoteURL: func(dir, url string) error {
+		remote, err := gitRemoteName(dir)
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command("git", "remote", "set-url", remote, url)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to set the URL of %s: %w\n%s", remote, err, out)
+		}
+		return nil
+	},
 }
 
 /*
@@ -201,7 +306,7 @@ func svnBase(p string) string {
 
 // SubversionBackend is the VCSBackend for subversion
 var SubversionBackend = &VCSBackend{
-	Clone: func(vg *vcsGetOption) error {
+	Clone: func(ctx context.Context, vg *vcsGetOption) error {
 		vg.dir = svnBase(vg.dir)
 		dir, _ := filepath.Split(vg.dir)
 		err := os.MkdirAll(dir, 0755)
@@ -222,16 +327,16 @@ var SubversionBackend = &VCSBackend{
 		} else if !strings.HasSuffix(remote.Path, trunk) {
 			copied := *vg.url
 			copied.Path += trunk
-			if err := cmdutil.RunSilently("svn", "info", copied.String()); err == nil {
+			if err := cmdutil.RunSilently(ctx, "svn", "info", copied.String()); err == nil {
 				remote = &copied
 			}
 		}
 		args = append(args, remote.String(), vg.dir)
 
-		return run(vg.silent)("svn", args...)
+		return run(vg.silent)(ctx, "svn", args...)
 	},
-	Update: func(vg *vcsGetOption) error {
-		return runInDir(vg.silent)(vg.dir, "svn", "update")
+	Update: func(ctx context.Context, vg *vcsGetOption) error {
+		return runInDir(vg.silent)(ctx, vg.dir, "svn", "update")
 	},
 	Contents: []string{".svn"},
 	RemoteURL: func(dir string) (string, error) {
@@ -253,7 +358,7 @@ var svnLastRevReg = regexp.MustCompile(`(?m)^Last Changed Rev: (\d+)$`)
 
 // GitsvnBackend is the VCSBackend for git-svn
 var GitsvnBackend = &VCSBackend{
-	Clone: func(vg *vcsGetOption) error {
+	Clone: func(ctx context.Context, vg *vcsGetOption) error {
 		orig := vg.dir
 		vg.dir = svnBase(vg.dir)
 		standard := orig == vg.dir
@@ -266,7 +371,7 @@ var GitsvnBackend = &VCSBackend{
 
 		var getSvnInfo = func(u string) (string, error) {
 			buf := &bytes.Buffer{}
-			cmd := exec.Command("svn", "info", u)
+			cmd := cmdutil.Command(ctx, "svn", "info", u)
 			cmd.Stdout = buf
 			cmd.Stderr = io.Discard
 			err := cmdutil.RunCommand(cmd, true)
@@ -308,10 +413,10 @@ var GitsvnBackend = &VCSBackend{
 			args = append(args, fmt.Sprintf("-r%s:HEAD", m[1]))
 		}
 		args = append(args, remote.String(), vg.dir)
-		return run(vg.silent)("git", args...)
+		return run(vg.silent)(ctx, "git", args...)
 	},
-	Update: func(vg *vcsGetOption) error {
-		return runInDir(vg.silent)(vg.dir, "git", "svn", "rebase")
+	Update: func(ctx context.Context, vg *vcsGetOption) error {
+		return runInDir(vg.silent)(ctx, vg.dir, "git", "svn", "rebase")
 	},
 	Contents: []string{".git/svn"},
 	RemoteURL: func(dir string) (string, error) {
@@ -323,7 +428,7 @@ var GitsvnBackend = &VCSBackend{
 // MercurialBackend is the VCSBackend for mercurial
 var MercurialBackend = &VCSBackend{
 	// Mercurial seems not supporting shallow clone currently.
-	Clone: func(vg *vcsGetOption) error {
+	Clone: func(ctx context.Context, vg *vcsGetOption) error {
 		dir, _ := filepath.Split(vg.dir)
 		err := os.MkdirAll(dir, 0755)
 		if err != nil {
@@ -335,13 +440,13 @@ var MercurialBackend = &VCSBackend{
 		}
 		args = append(args, vg.url.String(), vg.dir)
 
-		return run(vg.silent)("hg", args...)
+		return run(vg.silent)(ctx, "hg", args...)
 	},
-	Update: func(vg *vcsGetOption) error {
-		return runInDir(vg.silent)(vg.dir, "hg", "pull", "--update")
+	Update: func(ctx context.Context, vg *vcsGetOption) error {
+		return runInDir(vg.silent)(ctx, vg.dir, "hg", "pull", "--update")
 	},
-	Init: func(dir string) error {
-		return cmdutil.RunInDir(dir, "hg", "init")
+	Init: func(ctx context.Context, dir string) error {
+		return cmdutil.RunInDir(ctx, dir, "hg", "init")
 	},
 	Contents: []string{".hg"},
 	RemoteURL: func(dir string) (string, error) {
@@ -357,11 +462,20 @@ var MercurialBackend = &VCSBackend{
 		}
 		return url, nil
 	},
+	SetRemoteURL: func(dir, url string) error {
+		// hg has no command to set paths
+		hgrc := filepath.Join(dir, ".hg", "hgrc")
+		content, err := os.ReadFile(hgrc)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return os.WriteFile(hgrc, []byte(setHgDefaultPath(string(content), url)), 0644)
+	},
 }
 
 // DarcsBackend is the VCSBackend for darcs
 var DarcsBackend = &VCSBackend{
-	Clone: func(vg *vcsGetOption) error {
+	Clone: func(ctx context.Context, vg *vcsGetOption) error {
 		if vg.branch != "" {
 			return errors.New("darcs does not support branch")
 		}
@@ -378,13 +492,13 @@ var DarcsBackend = &VCSBackend{
 		}
 		args = append(args, vg.url.String(), vg.dir)
 
-		return run(vg.silent)("darcs", args...)
+		return run(vg.silent)(ctx, "darcs", args...)
 	},
-	Update: func(vg *vcsGetOption) error {
-		return runInDir(vg.silent)(vg.dir, "darcs", "pull")
+	Update: func(ctx context.Context, vg *vcsGetOption) error {
+		return runInDir(vg.silent)(ctx, vg.dir, "darcs", "pull")
 	},
-	Init: func(dir string) error {
-		return cmdutil.RunInDir(dir, "darcs", "init")
+	Init: func(ctx context.Context, dir string) error {
+		return cmdutil.RunInDir(ctx, dir, "darcs", "init")
 	},
 	Contents: []string{"_darcs"},
 	RemoteURL: func(dir string) (string, error) {
@@ -412,7 +526,7 @@ var DarcsBackend = &VCSBackend{
 
 // PijulBackend is the VCSBackend for pijul
 var PijulBackend = &VCSBackend{
-	Clone: func(vg *vcsGetOption) error {
+	Clone: func(ctx context.Context, vg *vcsGetOption) error {
 		dir, _ := filepath.Split(vg.dir)
 		err := os.MkdirAll(dir, 0755)
 		if err != nil {
@@ -425,13 +539,13 @@ var PijulBackend = &VCSBackend{
 		}
 		args = append(args, vg.url.String(), vg.dir)
 
-		return run(vg.silent)("pijul", args...)
+		return run(vg.silent)(ctx, "pijul", args...)
 	},
-	Update: func(vg *vcsGetOption) error {
-		return runInDir(vg.silent)(vg.dir, "pijul", "pull")
+	Update: func(ctx context.Context, vg *vcsGetOption) error {
+		return runInDir(vg.silent)(ctx, vg.dir, "pijul", "pull")
 	},
-	Init: func(dir string) error {
-		return cmdutil.RunInDir(dir, "pijul", "init")
+	Init: func(ctx context.Context, dir string) error {
+		return cmdutil.RunInDir(ctx, dir, "pijul", "init")
 	},
 	Contents: []string{".pijul"},
 	RemoteURL: func(dir string) (string, error) {
@@ -454,10 +568,10 @@ var PijulBackend = &VCSBackend{
 }
 
 var cvsDummyBackend = &VCSBackend{
-	Clone: func(vg *vcsGetOption) error {
+	Clone: func(ctx context.Context, vg *vcsGetOption) error {
 		return errors.New("CVS clone is not supported")
 	},
-	Update: func(vg *vcsGetOption) error {
+	Update: func(ctx context.Context, vg *vcsGetOption) error {
 		return errors.New("CVS update is not supported")
 	},
 	Contents: []string{"CVS/Repository"},
@@ -467,7 +581,7 @@ const fossilRepoName = ".fossil" // same as Go
 
 // FossilBackend is the VCSBackend for fossil
 var FossilBackend = &VCSBackend{
-	Clone: func(vg *vcsGetOption) error {
+	Clone: func(ctx context.Context, vg *vcsGetOption) error {
 		if vg.branch != "" {
 			return errors.New("fossil does not support cloning specific branch")
 		}
@@ -475,19 +589,19 @@ var FossilBackend = &VCSBackend{
 			return err
 		}
 
-		if err := run(vg.silent)("fossil", "clone", vg.url.String(), filepath.Join(vg.dir, fossilRepoName)); err != nil {
+		if err := run(vg.silent)(ctx, "fossil", "clone", vg.url.String(), filepath.Join(vg.dir, fossilRepoName)); err != nil {
 			return err
 		}
-		return runInDir(vg.silent)(vg.dir, "fossil", "open", fossilRepoName)
+		return runInDir(vg.silent)(ctx, vg.dir, "fossil", "open", fossilRepoName)
 	},
-	Update: func(vg *vcsGetOption) error {
-		return runInDir(vg.silent)(vg.dir, "fossil", "update")
+	Update: func(ctx context.Context, vg *vcsGetOption) error {
+		return runInDir(vg.silent)(ctx, vg.dir, "fossil", "update")
 	},
-	Init: func(dir string) error {
-		if err := cmdutil.RunInDir(dir, "fossil", "init", fossilRepoName); err != nil {
+	Init: func(ctx context.Context, dir string) error {
+		if err := cmdutil.RunInDir(ctx, dir, "fossil", "init", fossilRepoName); err != nil {
 			return err
 		}
-		return cmdutil.RunInDir(dir, "fossil", "open", fossilRepoName)
+		return cmdutil.RunInDir(ctx, dir, "fossil", "open", fossilRepoName)
 	},
 	Contents: []string{".fslckout", "_FOSSIL_"},
 	RemoteURL: func(dir string) (string, error) {
@@ -503,12 +617,20 @@ var FossilBackend = &VCSBackend{
 		}
 		return url, nil
 	},
+	SetRemoteURL: func(dir, url string) error {
+		cmd := exec.Command("fossil", "remote-url", url)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to set remote URL: %w\n%s", err, out)
+		}
+		return nil
+	},
 }
 
 // BazaarBackend is the VCSBackend for bazaar
 var BazaarBackend = &VCSBackend{
 	// bazaar seems not supporting shallow clone currently.
-	Clone: func(vg *vcsGetOption) error {
+	Clone: func(ctx context.Context, vg *vcsGetOption) error {
 		if vg.branch != "" {
 			return errors.New("--branch option is unavailable for Bazaar since branch is included in remote URL")
 		}
@@ -517,14 +639,14 @@ var BazaarBackend = &VCSBackend{
 		if err != nil {
 			return err
 		}
-		return run(vg.silent)("bzr", "branch", vg.url.String(), vg.dir)
+		return run(vg.silent)(ctx, "bzr", "branch", vg.url.String(), vg.dir)
 	},
-	Update: func(vg *vcsGetOption) error {
+	Update: func(ctx context.Context, vg *vcsGetOption) error {
 		// Without --overwrite bzr will not pull tags that changed.
-		return runInDir(vg.silent)(vg.dir, "bzr", "pull", "--overwrite")
+		return runInDir(vg.silent)(ctx, vg.dir, "bzr", "pull", "--overwrite")
 	},
-	Init: func(dir string) error {
-		return cmdutil.RunInDir(dir, "bzr", "init")
+	Init: func(ctx context.Context, dir string) error {
+		return cmdutil.RunInDir(ctx, dir, "bzr", "init")
 	},
 	Contents: []string{".bzr"},
 	RemoteURL: func(dir string) (string, error) {
@@ -545,6 +667,7 @@ var BazaarBackend = &VCSBackend{
 var vcsRegistry = map[string]*VCSBackend{
 	"git":        GitBackend,
 	"github":     GitBackend,
+	"gitlab":     GitBackend,
 	"codecommit": GitBackend,
 	"svn":        SubversionBackend,
 	"subversion": SubversionBackend,
@@ -557,3 +680,20 @@ var vcsRegistry = map[string]*VCSBackend{
 	"bzr":        BazaarBackend,
 	"bazaar":     BazaarBackend,
 }
+
+// vcsNames are the canonical names of the backends in vcsRegistry.
+var vcsNames = []string{"git", "git-svn", "svn", "hg", "darcs", "pijul", "fossil", "bzr"}
+
+// vcsName returns the canonical name of the backend, or an empty string if
+// the backend is not registered.
+func vcsName(backend *VCSBackend) string {
+	for _, name := range vcsNames {
+		if vcsRegistry[name] == backend {
+			return name
+		}
+	}
+	if backend == cvsDummyBackend {
+		return "cvs"
+	}
+	return ""
+}
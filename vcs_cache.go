@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/x-motemen/ghq/filelock"
+)
+
+const vcsCacheFile = "vcs.json"
+
+// vcsCacheTTL is how long a detected VCS backend is used without probing
+// again, to catch up with the repositories moved to another VCS.
+var vcsCacheTTL = 7 * 24 * time.Hour
+
+// A vcsCacheEntry is a VCS backend detected for a URL prefix.
+type vcsCacheEntry struct {
+	VCS      string `json:"vcs"`
+	Detector string `json:"detector"`
+	// URL is the URL of the repository to clone, if it differs from the
+	// one detected, e.g. pointed to by a go-import meta tag.
+	URL     string    `json:"url,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// vcsCache is the persistent cache of VCS backends detected over the network,
+// keyed by URL prefix (e.g. "git.example.com" or "go.example.com/team/repo").
+type vcsCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]vcsCacheEntry
+}
+
+var (
+	_vcsCache     *vcsCache
+	vcsCacheOnce  = &sync.Once{}
+	vcsCacheError error
+)
+
+func loadVCSCache() (*vcsCache, error) {
+	vcsCacheOnce.Do(func() {
+		dir, err := ghqCacheDir()
+		if err != nil {
+			vcsCacheError = err
+			return
+		}
+		_vcsCache, vcsCacheError = readVCSCache(filepath.Join(dir, vcsCacheFile))
+	})
+	return _vcsCache, vcsCacheError
+}
+
+func readVCSCache(path string) (*vcsCache, error) {
+	entries, err := readVCSCacheEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	return &vcsCache{path: path, entries: entries}, nil
+}
+
+func readVCSCacheEntries(path string) (map[string]vcsCacheEntry, error) {
+	entries := map[string]vcsCacheEntry{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		// broken cache is not fatal, it will be rebuilt
+		return map[string]vcsCacheEntry{}, nil
+	}
+	return entries, nil
+}
+
+func vcsCacheKey(u *url.URL) string {
+	return strings.ToLower(u.Host) + strings.TrimSuffix(u.Path, "/")
+}
+
+// vcsCacheStoreKey returns the URL prefix to store the detection under.
+// A host serves repositories of one VCS in general, so the detections by
+// probing the repository are stored for the whole host. The ones by go-import
+// meta tags are stored per repository, since they redirect each repository to
+// its own URL.
+func vcsCacheStoreKey(u *url.URL, detector string) string {
+	if detector == "go-import" {
+		return vcsCacheKey(u)
+	}
+	return strings.ToLower(u.Host)
+}
+
+// lookup returns the unexpired entry of the longest URL prefix matching the
+// URL.
+func (c *vcsCache) lookup(u *url.URL) (string, vcsCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := vcsCacheKey(u)
+	var (
+		found string
+		entry vcsCacheEntry
+	)
+	for prefix, e := range c.entries {
+		if time.Since(e.Updated) >= vcsCacheTTL {
+			continue
+		}
+		if (key == prefix || strings.HasPrefix(key, prefix+"/")) && len(prefix) > len(found) {
+			found, entry = prefix, e
+		}
+	}
+	return found, entry, found != ""
+}
+
+// store saves the VCS detected for the URL, and the URL of the repository if
+// it differs, dropping the expired entries. The cache file is re-read under
+// a lock, so that the entries stored by other ghq processes are kept.
+func (c *vcsCache) store(ctx context.Context, u *url.URL, vcs, detector string, repoURL *url.URL) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, err := filelock.Lock(ctx, c.path+".lock", true)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	entries, err := readVCSCacheEntries(c.path)
+	if err != nil {
+		return err
+	}
+	for prefix, e := range entries {
+		if time.Since(e.Updated) >= vcsCacheTTL {
+			delete(entries, prefix)
+		}
+	}
+	e := vcsCacheEntry{
+		VCS:      vcs,
+		Detector: detector,
+		Updated:  time.Now(),
+	}
+	if repoURL != nil && repoURL.String() != u.String() {
+		e.URL = repoURL.String()
+	}
+	entries[vcsCacheStoreKey(u, detector)] = e
+	c.entries = entries
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(c.path, b)
+}
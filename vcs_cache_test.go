@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVCSCache(t *testing.T) {
+	path := filepath.Join(newTempDir(t), "vcs.json")
+	c, err := readVCSCache(path)
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if _, _, ok := c.lookup(mustParseURL("https://git.example.com/foo/bar")); ok {
+		t.Errorf("empty cache should not have any entries")
+	}
+	if err := c.store(context.Background(), mustParseURL("https://git.example.com/foo/bar"), "git", "git ls-remote", nil); err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if err := c.store(context.Background(), mustParseURL("https://go.example.com/pkg"), "git", "go-import", mustParseURL("https://git.example.com/pkg")); err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+
+	c, err = readVCSCache(path)
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	c.entries["git.example.com/hg"] = vcsCacheEntry{VCS: "hg", Updated: time.Now()}
+	c.entries["git.example.com/moved"] = vcsCacheEntry{VCS: "hg", Updated: time.Now().Add(-vcsCacheTTL)}
+
+	testCases := []struct {
+		url, prefix, vcs, repoURL string
+	}{{
+		url:    "https://git.example.com/foo/bar",
+		prefix: "git.example.com",
+		vcs:    "git",
+	}, {
+		url:    "ssh://GIT.example.com/foo/bar/",
+		prefix: "git.example.com",
+		vcs:    "git",
+	}, {
+		url:    "https://git.example.com/foo/baz",
+		prefix: "git.example.com",
+		vcs:    "git",
+	}, {
+		url:    "https://git.example.com/hg/repo",
+		prefix: "git.example.com/hg",
+		vcs:    "hg",
+	}, {
+		url:    "https://git.example.com/hgrepo",
+		prefix: "git.example.com",
+		vcs:    "git",
+	}, {
+		url:    "https://git.example.com/moved",
+		prefix: "git.example.com",
+		vcs:    "git",
+	}, {
+		url:     "https://go.example.com/pkg/sub",
+		prefix:  "go.example.com/pkg",
+		vcs:     "git",
+		repoURL: "https://git.example.com/pkg",
+	}, {
+		url: "https://go.example.com/other",
+	}, {
+		url: "https://git.example.org/foo/bar",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			prefix, entry, ok := c.lookup(mustParseURL(tc.url))
+			if ok != (tc.prefix != "") {
+				t.Fatalf("ok should be %v", tc.prefix != "")
+			}
+			if prefix != tc.prefix {
+				t.Errorf("prefix: got: %q, expect: %q", prefix, tc.prefix)
+			}
+			if entry.VCS != tc.vcs {
+				t.Errorf("vcs: got: %q, expect: %q", entry.VCS, tc.vcs)
+			}
+			if entry.URL != tc.repoURL {
+				t.Errorf("url: got: %q, expect: %q", entry.URL, tc.repoURL)
+			}
+		})
+	}
+}
+
+func TestVCSCache_storeMerges(t *testing.T) {
+	path := filepath.Join(newTempDir(t), "vcs.json")
+	c1, err := readVCSCache(path)
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	c2, err := readVCSCache(path)
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if err := c1.store(context.Background(), mustParseURL("https://git.example.com/foo/bar"), "git", "git ls-remote", nil); err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	if err := c2.store(context.Background(), mustParseURL("https://hg.example.com/foo/bar"), "hg", "hg identify", nil); err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+
+	c, err := readVCSCache(path)
+	if err != nil {
+		t.Fatalf("error should be nil but: %s", err)
+	}
+	for _, key := range []string{"git.example.com", "hg.example.com"} {
+		if _, ok := c.entries[key]; !ok {
+			t.Errorf("%q should be kept in the cache, but: %v", key, c.entries)
+		}
+	}
+}
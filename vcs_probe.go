@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Songmu/gitconfig"
+	"github.com/x-motemen/ghq/cmdutil"
+	"github.com/x-motemen/ghq/logger"
+)
+
+const defaultProbeTimeout = 10 * time.Second
+
+// offlineMode disables network probing on VCS detection. Only gitconfig,
+// the URL itself and the detection cache are consulted.
+var offlineMode bool
+
+// A vcsDetection is the result of VCS detection.
+type vcsDetection struct {
+	backend  *VCSBackend
+	url      *url.URL
+	detector string
+}
+
+// A vcsDetector detects the VCS backend of a repository on a host which is
+// not known to ghq. The detectors are tried in order, and the network is
+// probed only when none of gitconfig, the URL and the cache tells the backend.
+type vcsDetector struct {
+	offline bool
+	noCache bool
+	timeout time.Duration
+	// trace is called with the name of each detector and what it found.
+	trace func(detector, message string)
+}
+
+func newVCSDetector() *vcsDetector {
+	d := &vcsDetector{
+		offline: offlineMode,
+		timeout: probeTimeout(),
+	}
+	if logger.Enabled(logger.LevelDebug) {
+		d.trace = func(detector, message string) {
+			logger.Log("debug", fmt.Sprintf("vcs %s: %s", detector, message))
+		}
+	}
+	return d
+}
+
+// probeTimeout returns the timeout of each network probe, which can be
+// configured by 'ghq.probeTimeout' (e.g. "5s").
+func probeTimeout() time.Duration {
+	v, err := gitconfig.Get("ghq.probeTimeout")
+	if err != nil {
+		if !gitconfig.IsNotFound(err) {
+			logger.Log("error", err.Error())
+		}
+		return defaultProbeTimeout
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		logger.Log("warning", fmt.Sprintf("invalid ghq.probeTimeout %q, using %s", v, defaultProbeTimeout))
+		return defaultProbeTimeout
+	}
+	return d
+}
+
+func (d *vcsDetector) tracef(detector, format string, args ...any) {
+	if d.trace != nil {
+		d.trace(detector, fmt.Sprintf(format, args...))
+	}
+}
+
+func (d *vcsDetector) detect(ctx context.Context, u *url.URL) (*vcsDetection, error) {
+	found := func(backend *VCSBackend, repoURL *url.URL, detector string) *vcsDetection {
+		return &vcsDetection{backend: backend, url: repoURL, detector: detector}
+	}
+
+	// Respect 'ghq.url.https://ghe.example.com/.vcs' config variable
+	// (in gitconfig:)
+	//     [ghq "https://ghe.example.com/"]
+	//     vcs = github
+	vcs, err := gitconfig.Do("--path", "--get-urlmatch", "ghq.vcs", u.String())
+	if err != nil && !gitconfig.IsNotFound(err) {
+		logger.Log("error", err.Error())
+	}
+	if backend, ok := vcsRegistry[vcs]; ok {
+		d.tracef("config", "ghq.vcs is %q", vcs)
+		return found(backend, u, "config"), nil
+	}
+	d.tracef("config", "ghq.vcs is not set")
+
+	if m := vcsSchemeReg.FindStringSubmatch(u.Scheme); len(m) > 1 {
+		d.tracef("scheme", "%q implies %s", u.Scheme, m[1])
+		return found(scheme2vcs[m[1]], u, "scheme"), nil
+	}
+	d.tracef("scheme", "%q does not imply any VCS", u.Scheme)
+
+	var cache *vcsCache
+	if d.noCache {
+		d.tracef("cache", "skipped")
+	} else if cache, err = loadVCSCache(); err != nil {
+		logger.Log("warning", fmt.Sprintf("failed to load VCS cache: %s", err))
+	} else if prefix, entry, ok := cache.lookup(u); ok {
+		repoURL := u
+		if entry.URL != "" {
+			if repoURL, err = url.Parse(entry.URL); err != nil {
+				repoURL = nil
+			}
+		}
+		if backend, ok := vcsRegistry[entry.VCS]; ok && repoURL != nil {
+			d.tracef("cache", "%q was detected as %s by %s at %s",
+				prefix, entry.VCS, entry.Detector, entry.Updated.Format(time.RFC3339))
+			return found(backend, repoURL, "cache"), nil
+		}
+	} else {
+		d.tracef("cache", "no entry for %q", vcsCacheKey(u))
+	}
+
+	if u.Scheme == "ssh" && u.User.Username() == "git" {
+		d.tracef("ssh user", "user \"git\" implies git")
+		return found(GitBackend, u, "ssh user"), nil
+	}
+
+	switch u.Host {
+	case "fossil-scm.org", "sqlite.org":
+		d.tracef("known host", "%s hosts fossil", u.Host)
+		return found(FossilBackend, u, "known host"), nil
+	}
+
+	if d.offline {
+		d.tracef("network", "skipped in offline mode")
+		return nil, fmt.Errorf("unsupported VCS, url=%s: cannot detect VCS in offline mode", u)
+	}
+
+	probed := func(backend *VCSBackend, repoURL *url.URL, detector string) (*vcsDetection, error) {
+		if cache != nil {
+			if err := cache.store(ctx, u, vcsName(backend), detector, repoURL); err != nil {
+				logger.Log("warning", fmt.Sprintf("failed to save VCS cache: %s", err))
+			}
+		}
+		return found(backend, repoURL, detector), nil
+	}
+
+	mayBeSvn := strings.HasPrefix(u.Host, "svn.")
+	if mayBeSvn {
+		if d.probe(ctx, "svn info", "svn", "info", "--non-interactive", u.String()) {
+			return probed(SubversionBackend, u, "svn info")
+		}
+	} else {
+		d.tracef("svn info", "deferred: host does not start with \"svn.\"")
+	}
+
+	if d.probe(ctx, "git ls-remote", "git", "ls-remote", u.String()) {
+		return probed(GitBackend, u, "git ls-remote")
+	}
+
+	vcs, repoURL, err := d.probeGoImport(ctx, u)
+	if err == nil {
+		// vcs == "mod" (modproxy) not supported yet
+		if backend, ok := vcsRegistry[vcs]; ok {
+			return probed(backend, repoURL, "go-import")
+		}
+		return found(nil, repoURL, "go-import"), nil
+	}
+
+	if d.probe(ctx, "hg identify", "hg", "identify", "--noninteractive", u.String()) {
+		return probed(MercurialBackend, u, "hg identify")
+	}
+
+	if !mayBeSvn && d.probe(ctx, "svn info", "svn", "info", "--non-interactive", u.String()) {
+		return probed(SubversionBackend, u, "svn info")
+	}
+
+	return nil, fmt.Errorf("unsupported VCS, url=%s: %w", u, err)
+}
+
+// probe runs the command with the probe timeout, and reports whether it succeeded.
+func (d *vcsDetector) probe(ctx context.Context, detector, command string, args ...string) bool {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = io.Discard
+	// never wait for credentials to be typed in
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	start := time.Now()
+	err := cmdutil.RunCommand(cmd, true)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	switch {
+	case err == nil:
+		d.tracef(detector, "succeeded (%s)", elapsed)
+		return true
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		d.tracef(detector, "timed out after %s", d.timeout)
+	default:
+		d.tracef(detector, "failed: %s (%s)", err, elapsed)
+	}
+	return false
+}
+
+func (d *vcsDetector) probeGoImport(ctx context.Context, u *url.URL) (string, *url.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	vcs, repoURL, err := detectGoImport(ctx, u)
+	switch {
+	case err == nil:
+		d.tracef("go-import", "meta tag points to %s %s", vcs, repoURL)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		d.tracef("go-import", "timed out after %s", d.timeout)
+	default:
+		d.tracef("go-import", "failed: %s", err)
+	}
+	return vcs, repoURL, err
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -50,7 +52,7 @@ func TestVCSBackend(t *testing.T) {
 	}{{
 		name: "[git] clone",
 		f: func() error {
-			return GitBackend.Clone(&vcsGetOption{
+			return GitBackend.Clone(context.Background(), &vcsGetOption{
 				url: remoteDummyURL,
 				dir: localDir,
 			})
@@ -59,7 +61,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[git] shallow clone",
 		f: func() error {
-			return GitBackend.Clone(&vcsGetOption{
+			return GitBackend.Clone(context.Background(), &vcsGetOption{
 				url:     remoteDummyURL,
 				dir:     localDir,
 				shallow: true,
@@ -70,17 +72,55 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[git] clone specific branch",
 		f: func() error {
-			return GitBackend.Clone(&vcsGetOption{
+			return GitBackend.Clone(context.Background(), &vcsGetOption{
 				url:    remoteDummyURL,
 				dir:    localDir,
 				branch: "hello",
 			})
 		},
 		expect: []string{"git", "clone", "--branch", "hello", "--single-branch", remoteDummyURL.String(), localDir},
+	}, {
+		name: "[git] clone with progress",
+		f: func() error {
+			return GitBackend.Clone(context.Background(), &vcsGetOption{
+				url:      remoteDummyURL,
+				dir:      localDir,
+				progress: func(string, int) {},
+			})
+		},
+		expect: []string{"git", "clone", "--progress", remoteDummyURL.String(), localDir},
+	}, {
+		name: "[git] clone and checkout revision",
+		f: func() error {
+			return GitBackend.Clone(context.Background(), &vcsGetOption{
+				url:      remoteDummyURL,
+				dir:      localDir,
+				revision: "0123abcd",
+			})
+		},
+		expect: []string{"git", "checkout", "0123abcd"},
+		dir:    localDir,
+	}, {
+		name: "[git] shallow clone and checkout revision",
+		f: func() error {
+			err := GitBackend.Clone(context.Background(), &vcsGetOption{
+				url:      remoteDummyURL,
+				dir:      localDir,
+				shallow:  true,
+				revision: "0123abcd",
+			})
+			fetch := _commands[len(_commands)-2].Args
+			if expect := []string{"git", "fetch", "--depth", "1", "origin", "0123abcd"}; !reflect.DeepEqual(fetch, expect) {
+				t.Errorf("\ngot:    %+v\nexpect: %+v", fetch, expect)
+			}
+			return err
+		},
+		expect: []string{"git", "checkout", "0123abcd"},
+		dir:    localDir,
 	}, {
 		name: "[git] update",
 		f: func() error {
-			return GitBackend.Update(&vcsGetOption{
+			return GitBackend.Update(context.Background(), &vcsGetOption{
 				dir: localDir,
 			})
 		},
@@ -99,7 +139,7 @@ func TestVCSBackend(t *testing.T) {
 				}
 				return nil
 			}
-			return GitBackend.Update(&vcsGetOption{
+			return GitBackend.Update(context.Background(), &vcsGetOption{
 				dir: localDir,
 			})
 		},
@@ -108,7 +148,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[git] recursive",
 		f: func() error {
-			return GitBackend.Clone(&vcsGetOption{
+			return GitBackend.Clone(context.Background(), &vcsGetOption{
 				url:       remoteDummyURL,
 				dir:       localDir,
 				recursive: true,
@@ -118,7 +158,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[git] update recursive",
 		f: func() error {
-			return GitBackend.Update(&vcsGetOption{
+			return GitBackend.Update(context.Background(), &vcsGetOption{
 				dir:       localDir,
 				recursive: true,
 			})
@@ -128,7 +168,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[git] bare clone",
 		f: func() error {
-			return GitBackend.Clone(&vcsGetOption{
+			return GitBackend.Clone(context.Background(), &vcsGetOption{
 				url:    remoteDummyURL,
 				dir:    localDir,
 				bare:   true,
@@ -139,7 +179,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[git] (partial) blobless clone",
 		f: func() error {
-			return GitBackend.Clone(&vcsGetOption{
+			return GitBackend.Clone(context.Background(), &vcsGetOption{
 				url:     remoteDummyURL,
 				dir:     localDir,
 				partial: "blobless",
@@ -149,7 +189,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[git] (partial) treeless clone",
 		f: func() error {
-			return GitBackend.Clone(&vcsGetOption{
+			return GitBackend.Clone(context.Background(), &vcsGetOption{
 				url:     remoteDummyURL,
 				dir:     localDir,
 				partial: "treeless",
@@ -164,7 +204,7 @@ func TestVCSBackend(t *testing.T) {
 				return err
 			}
 			defer os.RemoveAll(filepath.Join(localDir, ".git"))
-			return GitBackend.Update(&vcsGetOption{
+			return GitBackend.Update(context.Background(), &vcsGetOption{
 				dir: localDir,
 			})
 		},
@@ -173,7 +213,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[svn] checkout",
 		f: func() error {
-			return SubversionBackend.Clone(&vcsGetOption{
+			return SubversionBackend.Clone(context.Background(), &vcsGetOption{
 				url: remoteDummyURL,
 				dir: localDir,
 			})
@@ -182,7 +222,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[svn] checkout shallow",
 		f: func() error {
-			return SubversionBackend.Clone(&vcsGetOption{
+			return SubversionBackend.Clone(context.Background(), &vcsGetOption{
 				url:     remoteDummyURL,
 				dir:     localDir,
 				shallow: true,
@@ -192,7 +232,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[svn] checkout specific branch",
 		f: func() error {
-			return SubversionBackend.Clone(&vcsGetOption{
+			return SubversionBackend.Clone(context.Background(), &vcsGetOption{
 				url:    remoteDummyURL,
 				dir:    localDir,
 				branch: "hello",
@@ -212,7 +252,7 @@ func TestVCSBackend(t *testing.T) {
 				}
 				return nil
 			}
-			return SubversionBackend.Clone(&vcsGetOption{
+			return SubversionBackend.Clone(context.Background(), &vcsGetOption{
 				url: remoteDummyURL,
 				dir: localDir,
 			})
@@ -221,7 +261,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[svn] update",
 		f: func() error {
-			return SubversionBackend.Update(&vcsGetOption{
+			return SubversionBackend.Update(context.Background(), &vcsGetOption{
 				dir:    localDir,
 				silent: true,
 			})
@@ -231,7 +271,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[git-svn] clone",
 		f: func() error {
-			return GitsvnBackend.Clone(&vcsGetOption{
+			return GitsvnBackend.Clone(context.Background(), &vcsGetOption{
 				url: remoteDummyURL,
 				dir: localDir,
 			})
@@ -240,7 +280,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[git-svn] update",
 		f: func() error {
-			return GitsvnBackend.Update(&vcsGetOption{
+			return GitsvnBackend.Update(context.Background(), &vcsGetOption{
 				dir: localDir,
 			})
 		},
@@ -259,7 +299,7 @@ func TestVCSBackend(t *testing.T) {
 				}
 				return nil
 			}
-			return GitsvnBackend.Clone(&vcsGetOption{
+			return GitsvnBackend.Clone(context.Background(), &vcsGetOption{
 				url:     remoteDummyURL,
 				dir:     localDir,
 				shallow: true,
@@ -269,7 +309,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[git-svn] clone specific branch",
 		f: func() error {
-			return GitsvnBackend.Clone(&vcsGetOption{
+			return GitsvnBackend.Clone(context.Background(), &vcsGetOption{
 				url:    remoteDummyURL,
 				dir:    localDir,
 				branch: "hello",
@@ -293,7 +333,7 @@ func TestVCSBackend(t *testing.T) {
 			}
 			copied := *remoteDummyURL
 			copied.Path += "/tags/v9.9.9"
-			return GitsvnBackend.Clone(&vcsGetOption{
+			return GitsvnBackend.Clone(context.Background(), &vcsGetOption{
 				url:     &copied,
 				dir:     localDir,
 				branch:  "develop",
@@ -305,7 +345,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[hg] clone",
 		f: func() error {
-			return MercurialBackend.Clone(&vcsGetOption{
+			return MercurialBackend.Clone(context.Background(), &vcsGetOption{
 				url: remoteDummyURL,
 				dir: localDir,
 			})
@@ -314,7 +354,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[hg] update",
 		f: func() error {
-			return MercurialBackend.Update(&vcsGetOption{
+			return MercurialBackend.Update(context.Background(), &vcsGetOption{
 				dir: localDir,
 			})
 		},
@@ -323,7 +363,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[hg] clone shallow",
 		f: func() error {
-			return MercurialBackend.Clone(&vcsGetOption{
+			return MercurialBackend.Clone(context.Background(), &vcsGetOption{
 				url:     remoteDummyURL,
 				dir:     localDir,
 				shallow: true,
@@ -333,7 +373,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[hg] clone specific branch",
 		f: func() error {
-			return MercurialBackend.Clone(&vcsGetOption{
+			return MercurialBackend.Clone(context.Background(), &vcsGetOption{
 				url:    remoteDummyURL,
 				dir:    localDir,
 				branch: "hello",
@@ -343,7 +383,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[darcs] clone",
 		f: func() error {
-			return DarcsBackend.Clone(&vcsGetOption{
+			return DarcsBackend.Clone(context.Background(), &vcsGetOption{
 				url: remoteDummyURL,
 				dir: localDir,
 			})
@@ -352,7 +392,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[darcs] clone shallow",
 		f: func() error {
-			return DarcsBackend.Clone(&vcsGetOption{
+			return DarcsBackend.Clone(context.Background(), &vcsGetOption{
 				url:     remoteDummyURL,
 				dir:     localDir,
 				shallow: true,
@@ -362,7 +402,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[darcs] update",
 		f: func() error {
-			return DarcsBackend.Update(&vcsGetOption{
+			return DarcsBackend.Update(context.Background(), &vcsGetOption{
 				dir: localDir,
 			})
 		},
@@ -371,7 +411,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[pijul] clone",
 		f: func() error {
-			return PijulBackend.Clone(&vcsGetOption{
+			return PijulBackend.Clone(context.Background(), &vcsGetOption{
 				url: remoteDummyURL,
 				dir: localDir,
 			})
@@ -380,7 +420,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[pijul] update",
 		f: func() error {
-			return PijulBackend.Update(&vcsGetOption{
+			return PijulBackend.Update(context.Background(), &vcsGetOption{
 				dir: localDir,
 			})
 		},
@@ -389,7 +429,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[bzr] clone",
 		f: func() error {
-			return BazaarBackend.Clone(&vcsGetOption{
+			return BazaarBackend.Clone(context.Background(), &vcsGetOption{
 				url: remoteDummyURL,
 				dir: localDir,
 			})
@@ -398,7 +438,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[bzr] update",
 		f: func() error {
-			return BazaarBackend.Update(&vcsGetOption{
+			return BazaarBackend.Update(context.Background(), &vcsGetOption{
 				dir: localDir,
 			})
 		},
@@ -407,7 +447,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[bzr] clone shallow",
 		f: func() error {
-			return BazaarBackend.Clone(&vcsGetOption{
+			return BazaarBackend.Clone(context.Background(), &vcsGetOption{
 				url:     remoteDummyURL,
 				dir:     localDir,
 				shallow: true,
@@ -417,7 +457,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[fossil] clone",
 		f: func() error {
-			return FossilBackend.Clone(&vcsGetOption{
+			return FossilBackend.Clone(context.Background(), &vcsGetOption{
 				url: remoteDummyURL,
 				dir: localDir,
 			})
@@ -427,7 +467,7 @@ func TestVCSBackend(t *testing.T) {
 	}, {
 		name: "[fossil] update",
 		f: func() error {
-			return FossilBackend.Update(&vcsGetOption{
+			return FossilBackend.Update(context.Background(), &vcsGetOption{
 				dir: localDir,
 			})
 		},
@@ -455,14 +495,14 @@ func TestCvsDummyBackend(t *testing.T) {
 	tempDir := newTempDir(t)
 	localDir := filepath.Join(tempDir, "repo")
 
-	if err := cvsDummyBackend.Clone(&vcsGetOption{
+	if err := cvsDummyBackend.Clone(context.Background(), &vcsGetOption{
 		url: remoteDummyURL,
 		dir: localDir,
 	}); err == nil {
 		t.Error("error should be occurred, but nil")
 	}
 
-	if err := cvsDummyBackend.Clone(&vcsGetOption{
+	if err := cvsDummyBackend.Clone(context.Background(), &vcsGetOption{
 		url:     remoteDummyURL,
 		dir:     localDir,
 		shallow: true,
@@ -470,7 +510,7 @@ func TestCvsDummyBackend(t *testing.T) {
 		t.Error("error should be occurred, but nil")
 	}
 
-	if err := cvsDummyBackend.Update(&vcsGetOption{
+	if err := cvsDummyBackend.Update(context.Background(), &vcsGetOption{
 		dir: localDir,
 	}); err == nil {
 		t.Error("error should be occurred, but nil")
@@ -481,7 +521,7 @@ func TestBranchOptionIgnoredErrors(t *testing.T) {
 	tempDir := newTempDir(t)
 	localDir := filepath.Join(tempDir, "repo")
 
-	if err := DarcsBackend.Clone(&vcsGetOption{
+	if err := DarcsBackend.Clone(context.Background(), &vcsGetOption{
 		url:    remoteDummyURL,
 		dir:    localDir,
 		branch: "hello",
@@ -489,7 +529,7 @@ func TestBranchOptionIgnoredErrors(t *testing.T) {
 		t.Error("error should be occurred, but nil")
 	}
 
-	if err := FossilBackend.Clone(&vcsGetOption{
+	if err := FossilBackend.Clone(context.Background(), &vcsGetOption{
 		url:    remoteDummyURL,
 		dir:    localDir,
 		branch: "hello",
@@ -497,7 +537,7 @@ func TestBranchOptionIgnoredErrors(t *testing.T) {
 		t.Error("error should be occurred, but nil")
 	}
 
-	if err := BazaarBackend.Clone(&vcsGetOption{
+	if err := BazaarBackend.Clone(context.Background(), &vcsGetOption{
 		url:    remoteDummyURL,
 		dir:    localDir,
 		branch: "hello",
@@ -505,3 +545,62 @@ func TestBranchOptionIgnoredErrors(t *testing.T) {
 		t.Error("error should be occurred, but nil")
 	}
 }
+
+func TestSetHgDefaultPath(t *testing.T) {
+	testCases := []struct {
+		name, hgrc, expect string
+	}{{
+		name:   "empty",
+		hgrc:   "",
+		expect: "[paths]\ndefault = https://example.com/new\n",
+	}, {
+		name:   "replace",
+		hgrc:   "[ui]\nusername = me\n\n[paths]\ndefault-push = ssh://example.com/old\ndefault = https://example.com/old\n",
+		expect: "[ui]\nusername = me\n\n[paths]\ndefault-push = ssh://example.com/old\ndefault = https://example.com/new\n",
+	}, {
+		name:   "insert into paths",
+		hgrc:   "[paths]\nupstream = https://example.com/up\n[ui]\ndefault = not a path\n",
+		expect: "[paths]\ndefault = https://example.com/new\nupstream = https://example.com/up\n[ui]\ndefault = not a path\n",
+	}, {
+		name:   "append paths",
+		hgrc:   "[ui]\nusername = me",
+		expect: "[ui]\nusername = me\n[paths]\ndefault = https://example.com/new\n",
+	}}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := setHgDefaultPath(tc.hgrc, "https://example.com/new"); got != tc.expect {
+				t.Errorf("got: %q, expect: %q", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestGitBackend_shallowRevision(t *testing.T) {
+	bare, work := newUpstream(t, "repo")
+	git(t, work, "commit", "--allow-empty", "-m", "second")
+	git(t, work, "push", "origin", "main")
+	first := git(t, work, "rev-parse", "HEAD~1")
+
+	remote, err := url.Parse("file://" + filepath.ToSlash(bare))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, revision := range []string{first, first[:8]} {
+		t.Run(revision, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "repo")
+			err := GitBackend.Clone(context.Background(), &vcsGetOption{
+				url:      remote,
+				dir:      dir,
+				shallow:  true,
+				silent:   true,
+				revision: revision,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := git(t, dir, "rev-parse", "HEAD"); got != first {
+				t.Errorf("got: %s, expect: %s", got, first)
+			}
+		})
+	}
+}
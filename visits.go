@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/x-motemen/ghq/filelock"
+	"github.com/x-motemen/ghq/logger"
+)
+
+const visitsFile = "visits.json"
+
+// maxVisitCount is the total count of the visits above which the counts are
+// aged, so that the repositories visited in the past fade out.
+const maxVisitCount = 10000
+
+// A visit is the record of the accesses to a repository by 'ghq look',
+// 'ghq get --look' and 'ghq visit'.
+type visit struct {
+	Count int       `json:"count"`
+	Last  time.Time `json:"last"`
+}
+
+// visitNow is the current time for the visits, replaced in tests.
+var visitNow = time.Now
+
+// frecency returns the score of the visit, which is the count weighted by
+// how recent the last visit is.
+func (v visit) frecency(now time.Time) float64 {
+	age := now.Sub(v.Last)
+	switch {
+	case age < time.Hour:
+		return float64(v.Count) * 4
+	case age < 24*time.Hour:
+		return float64(v.Count) * 2
+	case age < 7*24*time.Hour:
+		return float64(v.Count) / 2
+	}
+	return float64(v.Count) / 4
+}
+
+func visitsPath() (string, error) {
+	dir, err := ghqCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, visitsFile), nil
+}
+
+// readVisits returns the visits keyed by the full paths of the repositories.
+func readVisits() (map[string]visit, error) {
+	p, err := visitsPath()
+	if err != nil {
+		return nil, err
+	}
+	visits := map[string]visit{}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return visits, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &visits); err != nil {
+		// broken records are not fatal, they will be rebuilt
+		return map[string]visit{}, nil
+	}
+	return visits, nil
+}
+
+// updateVisits updates the visits by fn, excluding other ghq processes.
+func updateVisits(ctx context.Context, fn func(map[string]visit)) error {
+	p, err := visitsPath()
+	if err != nil {
+		return err
+	}
+	l, err := filelock.Lock(ctx, p+".lock", true)
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	visits, err := readVisits()
+	if err != nil {
+		return err
+	}
+	fn(visits)
+	b, err := json.MarshalIndent(visits, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(p, b)
+}
+
+// recordVisit records an access to the repository at fullPath.
+func recordVisit(ctx context.Context, fullPath string) error {
+	return updateVisits(ctx, func(visits map[string]visit) {
+		v := visits[fullPath]
+		v.Count++
+		v.Last = visitNow()
+		visits[fullPath] = v
+
+		var total int
+		for _, v := range visits {
+			total += v.Count
+		}
+		if total <= maxVisitCount {
+			return
+		}
+		for p, v := range visits {
+			if v.Count /= 2; v.Count == 0 {
+				delete(visits, p)
+			} else {
+				visits[p] = v
+			}
+		}
+	})
+}
+
+// recordVisitOrWarn records an access to the repository, and warns the
+// failure since it is not the point of the command.
+func recordVisitOrWarn(ctx context.Context, fullPath string) {
+	if err := recordVisit(ctx, fullPath); err != nil {
+		logger.Log("warning", fmt.Sprintf("failed to record the visit to %s: %s", fullPath, err))
+	}
+}
+
+// forgetVisits removes the records of the repository at fullPath.
+func forgetVisits(ctx context.Context, fullPath string) error {
+	return updateVisits(ctx, func(visits map[string]visit) {
+		delete(visits, fullPath)
+	})
+}
+
+// mostFrecent returns the most frecently visited one of the repositories, or
+// nil if none of them has been visited.
+func mostFrecent(repos []*LocalRepository) (*LocalRepository, error) {
+	visits, err := readVisits()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		found *LocalRepository
+		score float64
+		now   = visitNow()
+	)
+	for _, repo := range repos {
+		if s := visits[repo.FullPath].frecency(now); s > score ||
+			s == score && s > 0 && repo.RelPath < found.RelPath {
+			found, score = repo, s
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// resetVisits removes the records of the visits before and after the test.
+func resetVisits(t *testing.T) {
+	t.Helper()
+	p, err := visitsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(p)
+	t.Cleanup(func() { os.Remove(p) })
+}
+
+// withVisitNow fixes the current time of the visits during the test.
+func withVisitNow(t *testing.T, now time.Time) {
+	t.Helper()
+	orig := visitNow
+	visitNow = func() time.Time { return now }
+	t.Cleanup(func() { visitNow = orig })
+}
+
+func TestVisitFrecency(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name   string
+		v      visit
+		expect float64
+	}{
+		{"within an hour", visit{Count: 3, Last: now.Add(-time.Minute)}, 12},
+		{"within a day", visit{Count: 3, Last: now.Add(-2 * time.Hour)}, 6},
+		{"within a week", visit{Count: 3, Last: now.Add(-48 * time.Hour)}, 1.5},
+		{"older", visit{Count: 3, Last: now.Add(-30 * 24 * time.Hour)}, 0.75},
+		{"never", visit{}, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.v.frecency(now); got != tc.expect {
+				t.Errorf("got: %v, expect: %v", got, tc.expect)
+			}
+		})
+	}
+}
+
+func TestRecordVisit(t *testing.T) {
+	resetVisits(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	withVisitNow(t, now)
+	ctx := context.Background()
+
+	for range 3 {
+		if err := recordVisit(ctx, "/ghq/a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recordVisit(ctx, "/ghq/b"); err != nil {
+		t.Fatal(err)
+	}
+	visits, err := readVisits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := visits["/ghq/a"]; v.Count != 3 || !v.Last.Equal(now) {
+		t.Errorf("unexpected visit: %+v", v)
+	}
+
+	// counts are aged when exceeding the max
+	if err := updateVisits(ctx, func(visits map[string]visit) {
+		visits["/ghq/a"] = visit{Count: maxVisitCount, Last: now}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordVisit(ctx, "/ghq/c"); err != nil {
+		t.Fatal(err)
+	}
+	visits, err = readVisits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := visits["/ghq/a"].Count; got != maxVisitCount/2 {
+		t.Errorf("count should be halved, but: %d", got)
+	}
+	if _, ok := visits["/ghq/b"]; ok {
+		t.Errorf("visits counted down to zero should be removed")
+	}
+
+	if err := forgetVisits(ctx, "/ghq/a"); err != nil {
+		t.Fatal(err)
+	}
+	visits, err = readVisits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := visits["/ghq/a"]; ok {
+		t.Errorf("visits should be forgotten")
+	}
+}
+
+func TestSortRepositories(t *testing.T) {
+	resetVisits(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	withVisitNow(t, now)
+
+	tmpd := newTempDir(t)
+	var repos []*LocalRepository
+	for i, name := range []string{"a", "b", "c", "d"} {
+		p := filepath.Join(tmpd, name)
+		if err := os.MkdirAll(filepath.Join(p, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(time.Duration(i) * time.Hour)
+		os.Chtimes(p, mtime, mtime)
+		os.Chtimes(filepath.Join(p, ".git"), mtime, mtime)
+		repos = append(repos, &LocalRepository{FullPath: p, RelPath: name})
+	}
+	if err := updateVisits(context.Background(), func(visits map[string]visit) {
+		// frecency: a = 2, b = 4, c = 2
+		visits[repos[0].FullPath] = visit{Count: 8, Last: now.Add(-30 * 24 * time.Hour)}
+		visits[repos[1].FullPath] = visit{Count: 1, Last: now.Add(-time.Minute)}
+		visits[repos[2].FullPath] = visit{Count: 1, Last: now.Add(-2 * time.Hour)}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		order  string
+		expect []string
+	}{
+		{"name", []string{"a", "b", "c", "d"}},
+		{"frecency", []string{"b", "a", "c", "d"}},
+		{"recent", []string{"b", "c", "a", "d"}},
+		{"mtime", []string{"d", "c", "b", "a"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.order, func(t *testing.T) {
+			sorted := slices.Clone(repos)
+			slices.Reverse(sorted)
+			if err := sortRepositories(sorted, tc.order); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, repo := range sorted {
+				got = append(got, repo.RelPath)
+			}
+			if !slices.Equal(got, tc.expect) {
+				t.Errorf("got: %v, expect: %v", got, tc.expect)
+			}
+		})
+	}
+
+	if err := sortRepositories(repos, "unknown"); err == nil {
+		t.Error("error should be returned for an unknown order")
+	}
+}
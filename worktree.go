@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"syscall"
+
+	"github.com/x-motemen/ghq/logger"
 )
 
 // isNotADirectory returns true if err indicates a "not a directory" condition
@@ -200,3 +203,32 @@ func repairWorktreeBackPointers(oldDir, destDir string) ([]string, error) {
 	}
 	return paths, nil
 }
+
+// repairWorktrees repairs the linked worktrees of the repository moved from
+// oldDir to destDir so that their .git files reference the new location.
+//
+// For each worktree, two pointers exist:
+//
+//	back-pointer:    .git/worktrees/<name>/gitdir  → worktree working dir
+//	forward ref:     <worktree>/.git               → main repo's .git/worktrees/<name>
+//
+// External worktrees (outside the repo) didn't move, so only the forward
+// ref is stale. Internal worktrees (inside the repo) moved along with
+// the repo, so BOTH pointers are stale. We fix the back-pointers first
+// so that "git worktree repair" can match entries to update the forward refs.
+func repairWorktrees(oldDir, destDir string) {
+	wtPaths, err := repairWorktreeBackPointers(oldDir, destDir)
+	if err != nil {
+		logger.Log("warning", fmt.Sprintf("failed to discover linked worktree paths: %v", err))
+		return
+	}
+	if len(wtPaths) == 0 {
+		return
+	}
+	args := append([]string{"worktree", "repair"}, wtPaths...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = destDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		logger.Log("warning", fmt.Sprintf("git worktree repair failed: %v\n%s", err, out))
+	}
+}